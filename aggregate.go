@@ -0,0 +1,163 @@
+package tat
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Dimensions usable on aggregate of messages
+const (
+	AggregateByLabel  = "label"
+	AggregateByTag    = "tag"
+	AggregateByAuthor = "author"
+	AggregateByTopic  = "topic"
+)
+
+// Time buckets usable on aggregate of messages
+const (
+	AggregateBucketMinute = "minute"
+	AggregateBucketHour   = "hour"
+	AggregateBucketDay    = "day"
+	AggregateBucketWeek   = "week"
+)
+
+// aggregateBuckets contains size in seconds of each time bucket
+var aggregateBuckets = map[string]int64{
+	AggregateBucketMinute: 60,
+	AggregateBucketHour:   3600,
+	AggregateBucketDay:    86400,
+	AggregateBucketWeek:   7 * 86400,
+}
+
+// MessageAggregateCriteria describes how messages are grouped
+// by GET /messages/aggregate/*topic
+type MessageAggregateCriteria struct {
+	// GroupBy contains dimensions: label, tag, author, topic
+	GroupBy []string
+	// LabelPrefix keeps only labels starting with it, ex: "status:". Only with groupBy label
+	LabelPrefix string
+	// Bucket is a time bucket on dateCreation: minute, hour, day or week
+	Bucket string
+}
+
+// MessageAggregateJSON is returned by GET /messages/aggregate/*topic
+type MessageAggregateJSON struct {
+	GroupBy []string                 `json:"groupBy,omitempty"`
+	Bucket  string                   `json:"bucket,omitempty"`
+	Total   int                      `json:"total"`
+	Results []MessageAggregateResult `json:"results"`
+}
+
+// MessageAggregateResult is a count of messages for one bucket and one value of each dimension
+type MessageAggregateResult struct {
+	// Date is the beginning of time bucket, timestamp in seconds (UTC)
+	Date   int64  `json:"date,omitempty"`
+	Label  string `json:"label,omitempty"`
+	Tag    string `json:"tag,omitempty"`
+	Author string `json:"author,omitempty"`
+	Topic  string `json:"topic,omitempty"`
+	Count  int    `json:"count"`
+}
+
+// AggregateBucket returns size in seconds of a time bucket and its offset from
+// Epoch: weeks begin on monday, 1970-01-05 00:00 UTC
+func AggregateBucket(bucket string) (int64, int64, error) {
+	size, ok := aggregateBuckets[bucket]
+	if !ok {
+		return 0, 0, fmt.Errorf("Invalid bucket %s, possible values: minute, hour, day, week", bucket)
+	}
+	if bucket == AggregateBucketWeek {
+		return size, 4 * 86400, nil
+	}
+	return size, 0, nil
+}
+
+// AggregateBucketStart returns the beginning of bucket containing timestamp ts
+func AggregateBucketStart(ts float64, bucket string) (int64, error) {
+	size, offset, err := AggregateBucket(bucket)
+	if err != nil {
+		return 0, err
+	}
+	d := int64(ts) - offset
+	r := d % size
+	if r < 0 {
+		r += size
+	}
+	return d - r + offset, nil
+}
+
+// Check returns an error if a dimension or bucket is invalid
+func (a *MessageAggregateCriteria) Check() error {
+	for _, g := range a.GroupBy {
+		switch g {
+		case AggregateByLabel, AggregateByTag, AggregateByAuthor, AggregateByTopic:
+		default:
+			return fmt.Errorf("Invalid groupBy %s, possible values: label, tag, author, topic", g)
+		}
+	}
+	if a.LabelPrefix != "" && !ArrayContains(a.GroupBy, AggregateByLabel) {
+		return fmt.Errorf("labelPrefix can only be used with groupBy label")
+	}
+	if a.Bucket != "" {
+		if _, _, err := AggregateBucket(a.Bucket); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetURL returns URL values for aggregate, to add to URL values of MessageCriteria
+func (a *MessageAggregateCriteria) GetURL() string {
+	v := url.Values{}
+	if len(a.GroupBy) > 0 {
+		v.Set("groupBy", strings.Join(a.GroupBy, ","))
+	}
+	if a.LabelPrefix != "" {
+		v.Set("labelPrefix", a.LabelPrefix)
+	}
+	if a.Bucket != "" {
+		v.Set("bucket", a.Bucket)
+	}
+	return v.Encode()
+}
+
+// GetMessageAggregateCriteriaFromURLValues returns a checked MessageAggregateCriteria from URL values
+func GetMessageAggregateCriteriaFromURLValues(values url.Values) (*MessageAggregateCriteria, error) {
+	a := &MessageAggregateCriteria{
+		LabelPrefix: values.Get("labelPrefix"),
+		Bucket:      values.Get("bucket"),
+	}
+	if groupBy := values.Get("groupBy"); groupBy != "" {
+		for _, g := range strings.Split(groupBy, ",") {
+			if g = strings.TrimSpace(g); g != "" && !ArrayContains(a.GroupBy, g) {
+				a.GroupBy = append(a.GroupBy, g)
+			}
+		}
+	}
+	return a, a.Check()
+}
+
+// MessageAggregate counts messages matching criteria, grouped by dimensions and time bucket
+func (c *Client) MessageAggregate(topic string, criteria *MessageCriteria, aggregate MessageAggregateCriteria) (*MessageAggregateJSON, error) {
+	if c == nil {
+		return nil, ErrClientNotInitiliazed
+	}
+	if criteria == nil {
+		criteria = &MessageCriteria{}
+	}
+
+	path := fmt.Sprintf("/messages/aggregate%s?%s&%s", topic, criteria.GetURL(), aggregate.GetURL())
+	body, err := c.simpleGetAndGetBytes(path)
+	if err != nil {
+		ErrorLogFunc("Error getting aggregate of messages: %s", err)
+		return nil, err
+	}
+
+	out := &MessageAggregateJSON{}
+	if err := json.Unmarshal(body, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
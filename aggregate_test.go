@@ -0,0 +1,56 @@
+package tat
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAggregateBucketStart(t *testing.T) {
+	// 2017-03-13 15:23:04 UTC, a monday
+	ts := 1489418584.123456
+
+	start, err := AggregateBucketStart(ts, AggregateBucketMinute)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1489418580), start)
+
+	start, err = AggregateBucketStart(ts, AggregateBucketHour)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1489417200), start)
+
+	start, err = AggregateBucketStart(ts, AggregateBucketDay)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1489363200), start)
+
+	start, err = AggregateBucketStart(ts, AggregateBucketWeek)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1489363200), start)
+
+	// 2017-03-16 15:00:00 UTC, thursday of same week
+	start, err = AggregateBucketStart(1489676400, AggregateBucketWeek)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1489363200), start)
+
+	_, err = AggregateBucketStart(ts, "month")
+	assert.NotNil(t, err)
+}
+
+func TestGetMessageAggregateCriteriaFromURLValues(t *testing.T) {
+	a := MessageAggregateCriteria{GroupBy: []string{"label", "author"}, LabelPrefix: "status:", Bucket: "day"}
+	values, err := url.ParseQuery(a.GetURL())
+	assert.Nil(t, err)
+
+	b, err := GetMessageAggregateCriteriaFromURLValues(values)
+	assert.Nil(t, err)
+	assert.Equal(t, a, *b)
+
+	_, err = GetMessageAggregateCriteriaFromURLValues(url.Values{"groupBy": {"label,color"}})
+	assert.NotNil(t, err, "color is not a dimension")
+
+	_, err = GetMessageAggregateCriteriaFromURLValues(url.Values{"groupBy": {"tag"}, "labelPrefix": {"status:"}})
+	assert.NotNil(t, err, "labelPrefix needs groupBy label")
+
+	_, err = GetMessageAggregateCriteriaFromURLValues(url.Values{"bucket": {"year"}})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ovh/tat"
+	auditDB "github.com/ovh/tat/api/audit"
+)
+
+// AuditController contains all methods about audit events
+type AuditController struct{}
+
+func (*AuditController) buildCriteria(ctx *gin.Context) (*tat.AuditCriteria, error) {
+	c := tat.AuditCriteria{}
+	c.Skip, _ = strconv.Atoi(ctx.DefaultQuery("skip", "0"))
+	c.Limit, _ = strconv.Atoi(ctx.DefaultQuery("limit", "100"))
+	if c.Limit <= 0 || c.Limit > 1000 {
+		return nil, tat.NewError(http.StatusBadRequest, "Please put a limit between 1 and 1000")
+	}
+	c.Actor = ctx.Query("actor")
+	c.Action = ctx.Query("action")
+	c.TargetType = ctx.Query("targetType")
+	c.Target = ctx.Query("target")
+	c.DateMin = ctx.Query("dateMin")
+	c.DateMax = ctx.Query("dateMax")
+	return &c, nil
+}
+
+// List returns audit events on topics, groups and users, last first
+// only for Tat admin
+func (a *AuditController) List(ctx *gin.Context) {
+	criteria, err := a.buildCriteria(ctx)
+	if err != nil {
+		ctx.JSON(tat.Error(err))
+		return
+	}
+	a.list(ctx, criteria)
+}
+
+func (*AuditController) list(ctx *gin.Context, criteria *tat.AuditCriteria) {
+	count, events, err := auditDB.List(criteria)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, &tat.AuditEventsJSON{Count: count, Events: events})
+}
+
+// recordAudit records a change made by user of request on a topic, a group or a user
+func recordAudit(ctx *gin.Context, action, targetType, target string, recursive bool, old, new interface{}) {
+	referer, _ := ctx.Get(tat.TatHeaderXTatRefererLower)
+	event := tat.AuditEvent{
+		Actor:      getCtxUsername(ctx),
+		Action:     action,
+		TargetType: targetType,
+		Target:     target,
+		Recursive:  recursive,
+		Old:        old,
+		New:        new,
+	}
+	event.Referer, _ = referer.(string)
+	if event.Actor == "" && targetType == tat.AuditTargetUser {
+		// request without authentication of a user on its account: creation, verification
+		event.Actor = target
+	}
+	auditDB.Record(event)
+}
+
+func auditTopic(ctx *gin.Context, action, topic string, recursive bool, old, new interface{}) {
+	recordAudit(ctx, action, tat.AuditTargetTopic, topic, recursive, old, new)
+}
+
+func auditGroup(ctx *gin.Context, action, group string, old, new interface{}) {
+	recordAudit(ctx, action, tat.AuditTargetGroup, group, false, old, new)
+}
+
+func auditUser(ctx *gin.Context, action, username string, old, new interface{}) {
+	recordAudit(ctx, action, tat.AuditTargetUser, username, false, old, new)
+}
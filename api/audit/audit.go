@@ -0,0 +1,144 @@
+package audit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ovh/tat"
+	"github.com/ovh/tat/api/store"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Record inserts an audit event, dated now. Errors are only logged: a change
+// already done on a topic, a group or a user is not refused if it can't be audited
+func Record(event tat.AuditEvent) {
+	event.ID = bson.NewObjectId().Hex()
+	event.Date = tat.TSFromNow()
+	if err := store.Tat().CAudit.Insert(event); err != nil {
+		log.Errorf("Error while recording audit event %s by %s on %s %s: %s", event.Action, event.Actor, event.TargetType, event.Target, err)
+	}
+}
+
+func buildAuditCriteria(criteria *tat.AuditCriteria) (bson.M, error) {
+	query := bson.M{}
+	if criteria.Actor != "" {
+		query["actor"] = criteria.Actor
+	}
+	if criteria.Action != "" {
+		query["action"] = criteria.Action
+	}
+	if criteria.TargetType != "" {
+		query["targetType"] = criteria.TargetType
+	}
+	if criteria.Target != "" {
+		query["target"] = criteria.Target
+	}
+
+	bsonDate := bson.M{}
+	if criteria.DateMin != "" {
+		d, err := strconv.ParseFloat(criteria.DateMin, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Error while parsing dateMin %s", err)
+		}
+		bsonDate["$gte"] = d
+	}
+	if criteria.DateMax != "" {
+		d, err := strconv.ParseFloat(criteria.DateMax, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Error while parsing dateMax %s", err)
+		}
+		bsonDate["$lte"] = d
+	}
+	if len(bsonDate) > 0 {
+		query["date"] = bsonDate
+	}
+	return query, nil
+}
+
+// List returns audit events matching criteria, last first, with count of all matching events
+func List(criteria *tat.AuditCriteria) (int, []tat.AuditEvent, error) {
+	query, err := buildAuditCriteria(criteria)
+	if err != nil {
+		return -1, nil, err
+	}
+	cursor := store.Tat().CAudit.Find(query)
+	count, err := cursor.Count()
+	if err != nil {
+		log.Errorf("Error while counting audit events: %s", err)
+		return -1, nil, fmt.Errorf("Error while counting audit events")
+	}
+	events := []tat.AuditEvent{}
+	if err := cursor.Sort("-date").Skip(criteria.Skip).Limit(criteria.Limit).All(&events); err != nil {
+		log.Errorf("Error while listing audit events: %s", err)
+		return -1, nil, fmt.Errorf("Error while listing audit events")
+	}
+	return count, events, nil
+}
+
+// ChangeTopicOnEvents changes target topic of audit events, history of a topic follows its renaming
+func ChangeTopicOnEvents(oldName, newName string) {
+	if _, err := store.Tat().CAudit.UpdateAll(bson.M{"targetType": tat.AuditTargetTopic, "target": oldName}, bson.M{"$set": bson.M{"target": newName}}); err != nil {
+		log.Errorf("Error while changing topic %s to %s on audit events: %s", oldName, newName, err)
+	}
+}
+
+// MigrateHistory moves entries of history of topics and groups, older than the last
+// store.MaxHistory ones, to audit events with action history. It runs at start, before
+// history is capped by new changes
+func MigrateHistory() {
+	migrateHistory(store.Tat().CTopics, tat.AuditTargetTopic, "topic")
+	migrateHistory(store.Tat().CGroups, tat.AuditTargetGroup, "name")
+}
+
+func migrateHistory(col *mgo.Collection, targetType, nameField string) {
+	var doc struct {
+		ID      string   `bson:"_id"`
+		Name    string   `bson:"name"`
+		Topic   string   `bson:"topic"`
+		History []string `bson:"history"`
+	}
+	query := bson.M{"history." + strconv.Itoa(store.MaxHistory): bson.M{"$exists": true}}
+	iter := col.Find(query).Select(bson.M{"_id": 1, nameField: 1, "history": 1}).Iter()
+	for iter.Next(&doc) {
+		target := doc.Name
+		if targetType == tat.AuditTargetTopic {
+			target = doc.Topic
+		}
+		old := doc.History[:len(doc.History)-store.MaxHistory]
+		for i, entry := range old {
+			// same id for an entry if migration is run again after a failure
+			event := historyEvent(entry, targetType, target)
+			event.ID = fmt.Sprintf("history-%s-%d", doc.ID, i)
+			if _, err := store.Tat().CAudit.UpsertId(event.ID, event); err != nil {
+				log.Errorf("Error while moving history of %s %s to audit events: %s", targetType, target, err)
+				iter.Close()
+				return
+			}
+		}
+		if err := col.UpdateId(doc.ID, bson.M{"$push": bson.M{"history": bson.M{"$each": []string{}, "$slice": -store.MaxHistory}}}); err != nil {
+			log.Errorf("Error while capping history of %s %s: %s", targetType, target, err)
+			continue
+		}
+		log.Infof("%d entries of history of %s %s moved to audit events", len(old), targetType, target)
+	}
+	if err := iter.Close(); err != nil {
+		log.Errorf("Error while listing history of %ss: %s", targetType, err)
+	}
+}
+
+// historyEvent returns an audit event from an entry of history: "<timestamp> <username> <change>"
+func historyEvent(entry, targetType, target string) tat.AuditEvent {
+	event := tat.AuditEvent{Action: tat.AuditActionHistory, TargetType: targetType, Target: target, New: entry}
+	parts := strings.SplitN(entry, " ", 3)
+	if len(parts) == 3 {
+		if ts, err := strconv.ParseInt(parts[0], 10, 64); err == nil {
+			event.Date = float64(ts)
+			event.Actor = parts[1]
+			event.New = parts[2]
+		}
+	}
+	return event
+}
@@ -0,0 +1,23 @@
+package audit
+
+import (
+	"testing"
+
+	"github.com/ovh/tat"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistoryEvent(t *testing.T) {
+	e := historyEvent("1484555263 bob add to ro alice", tat.AuditTargetTopic, "/Internal/ProjectA")
+	assert.Equal(t, tat.AuditActionHistory, e.Action)
+	assert.Equal(t, tat.AuditTargetTopic, e.TargetType)
+	assert.Equal(t, "/Internal/ProjectA", e.Target)
+	assert.Equal(t, "bob", e.Actor)
+	assert.Equal(t, "add to ro alice", e.New)
+	assert.Equal(t, float64(1484555263), e.Date)
+
+	e = historyEvent("not a dated entry", tat.AuditTargetGroup, "groupA")
+	assert.Equal(t, "", e.Actor)
+	assert.Equal(t, "not a dated entry", e.New)
+	assert.Equal(t, float64(0), e.Date)
+}
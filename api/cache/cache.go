@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"fmt"
 	"strings"
 
 	"gopkg.in/redis.v4"
@@ -99,6 +100,22 @@ func TestInstanceAtStartup() {
 	Client()
 }
 
+// Subscribe subscribes to redis channels. Messages published on a node of a redis
+// cluster are received on all nodes, so the first node is used in cluster mode
+func Subscribe(channels ...string) (*redis.PubSub, error) {
+	switch c := Client().(type) {
+	case *redis.Client:
+		return c.Subscribe(channels...)
+	case *redis.ClusterClient:
+		opts := &redis.Options{
+			Addr:     strings.Split(viper.GetString("redis_hosts"), ",")[0],
+			Password: viper.GetString("redis_password"),
+		}
+		return redis.NewClient(opts).Subscribe(channels...)
+	}
+	return nil, fmt.Errorf("Subscribe is not available without redis")
+}
+
 //CriteriaKey returns the Redis Key
 func CriteriaKey(i tat.CacheableCriteria, s ...string) string {
 	k := i.CacheKey()
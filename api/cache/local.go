@@ -30,6 +30,9 @@ func (c *LocalCache) DecrBy(key string, decrement int64) *redis.IntCmd {
 func (c *LocalCache) Del(keys ...string) *redis.IntCmd {
 	return redis.NewIntResult(0, nil)
 }
+func (c *LocalCache) Eval(script string, keys []string, args ...interface{}) *redis.Cmd {
+	return redis.NewCmdResult(nil, nil)
+}
 func (c *LocalCache) Exists(key string) *redis.BoolCmd {
 	return redis.NewBoolResult(false, nil)
 }
@@ -15,6 +15,7 @@ type Cache interface {
 	Decr(key string) *redis.IntCmd
 	DecrBy(key string, decrement int64) *redis.IntCmd
 	Del(keys ...string) *redis.IntCmd
+	Eval(script string, keys []string, args ...interface{}) *redis.Cmd
 	Exists(key string) *redis.BoolCmd
 	Expire(key string, expiration time.Duration) *redis.BoolCmd
 	ExpireAt(key string, tm time.Time) *redis.BoolCmd
@@ -265,7 +265,7 @@ func addToHistory(group *tat.Group, user string, historyToAdd string) error {
 	toAdd := strconv.FormatInt(time.Now().Unix(), 10) + " " + user + " " + historyToAdd
 	return store.Tat().CGroups.Update(
 		bson.M{"_id": group.ID},
-		bson.M{"$addToSet": bson.M{"history": toAdd}},
+		bson.M{"$push": bson.M{"history": bson.M{"$each": []string{toAdd}, "$slice": -store.MaxHistory}}},
 	)
 }
 
@@ -77,6 +77,7 @@ func (*GroupsController) Create(ctx *gin.Context) {
 		ctx.AbortWithError(http.StatusInternalServerError, err)
 		return
 	}
+	auditGroup(ctx, tat.AuditActionCreate, groupIn.Name, nil, groupIn.Description)
 	ctx.JSON(http.StatusCreated, groupIn)
 }
 
@@ -163,6 +164,9 @@ func (g *GroupsController) Update(ctx *gin.Context) {
 			return
 		}
 	}
+	auditGroup(ctx, tat.AuditActionUpdate, paramJSON.Name,
+		tat.GroupJSON{Name: groupToUpdate.Name, Description: groupToUpdate.Description},
+		tat.GroupJSON{Name: paramJSON.Name, Description: paramJSON.Description})
 
 	ctx.JSON(http.StatusCreated, "")
 }
@@ -211,6 +215,7 @@ func (g *GroupsController) Delete(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Error while deleting Group: %s", err.Error())})
 		return
 	}
+	auditGroup(ctx, tat.AuditActionDelete, groupToDelete.Name, groupToDelete, nil)
 	ctx.JSON(http.StatusOK, "")
 }
 
@@ -227,6 +232,7 @@ func (g *GroupsController) AddUser(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Error while add user to group: %s", err)})
 		return
 	}
+	auditGroup(ctx, tat.AuditActionAddUser, group.Name, nil, paramJSON.Username)
 	ctx.JSON(http.StatusCreated, "")
 }
 
@@ -242,6 +248,7 @@ func (g *GroupsController) RemoveUser(ctx *gin.Context) {
 	if err := groupDB.RemoveUser(&group, getCtxUsername(ctx), paramJSON.Username); err != nil {
 		return
 	}
+	auditGroup(ctx, tat.AuditActionRemoveUser, group.Name, paramJSON.Username, nil)
 	ctx.JSON(http.StatusOK, "")
 }
 
@@ -257,6 +264,7 @@ func (g *GroupsController) AddAdminUser(ctx *gin.Context) {
 	if err := groupDB.AddAdminUser(&group, getCtxUsername(ctx), paramJSON.Username); err != nil {
 		return
 	}
+	auditGroup(ctx, tat.AuditActionAddAdminUser, group.Name, nil, paramJSON.Username)
 	ctx.JSON(http.StatusCreated, "")
 }
 
@@ -272,5 +280,6 @@ func (g *GroupsController) RemoveAdminUser(ctx *gin.Context) {
 	if err := groupDB.RemoveAdminUser(&group, getCtxUsername(ctx), paramJSON.Username); err != nil {
 		return
 	}
+	auditGroup(ctx, tat.AuditActionRemoveAdminUser, group.Name, paramJSON.Username, nil)
 	ctx.JSON(http.StatusOK, "")
 }
@@ -14,6 +14,7 @@ func InitHooks() {
 	initKafka()
 	initWebhook()
 	initXMPPHook()
+	initStreams()
 }
 
 // CloseHooks closes hooks
@@ -26,6 +27,11 @@ func SendHook(hook *tat.HookJSON, topic tat.Topic) {
 	go innerSendHook(hook, topic)
 }
 
+// SendStreamHook sends a hook only to streams, not to hooks of topic parameters and filters
+func SendStreamHook(hook *tat.HookJSON, topic tat.Topic) {
+	go innerSendHookStreams(hook, topic)
+}
+
 // GetCapabilities returns tat capabilities about hooks
 func GetCapabilities() []tat.CapabilitieHook {
 	hooks := []tat.CapabilitieHook{
@@ -37,6 +43,8 @@ func GetCapabilities() []tat.CapabilitieHook {
 }
 
 func innerSendHook(hook *tat.HookJSON, topic tat.Topic) {
+	// streams first, they don't have to wait for webhooks
+	innerSendHookStreams(hook, topic)
 	innerSendHookTopicParameters(hook, topic)
 	innerSendHookTopicFilters(hook, topic)
 }
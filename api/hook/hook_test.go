@@ -94,3 +94,54 @@ func TestMatchCriteria(t *testing.T) {
 		tat.FilterCriteria{AndTag: "tagA,tagB", AndLabel: "labelA,labelB"}),
 		"this message should match")
 }
+
+func TestMatchMessageCriteria(t *testing.T) {
+
+	assert.Equal(t, true, matchMessageCriteria(tat.Message{Text: "foo"}, nil),
+		"this message should match")
+
+	assert.Equal(t, true, matchMessageCriteria(
+		tat.Message{Text: "build #1234 ok", Tags: []string{"build"}},
+		&tat.MessageCriteria{Text: "#1234", StartTag: "bui"}),
+		"this message should match")
+
+	assert.Equal(t, true, matchMessageCriteria(
+		tat.Message{Text: "Build #1234 OK"},
+		&tat.MessageCriteria{Text: "build #1234 ok"}),
+		"this message should match, text is not case sensitive")
+
+	assert.Equal(t, false, matchMessageCriteria(
+		tat.Message{ID: "a", InReplyOfID: "b", InReplyOfIDRoot: "b"},
+		&tat.MessageCriteria{OnlyMsgRoot: tat.True}),
+		"this message should not match")
+
+	assert.Equal(t, true, matchMessageCriteria(
+		tat.Message{ID: "a", InReplyOfID: "b", InReplyOfIDRoot: "b"},
+		&tat.MessageCriteria{AllIDMessage: "b", OnlyMsgReply: tat.True}),
+		"this message should match")
+
+	assert.Equal(t, false, matchMessageCriteria(
+		tat.Message{Labels: []tat.Label{{Text: "doing", Color: "#eeeeee"}}},
+		&tat.MessageCriteria{StartLabel: "done"}),
+		"this message should not match")
+
+	assert.Equal(t, true, matchMessageCriteria(
+		tat.Message{Reactions: map[string][]string{"ack": {"foo"}}},
+		&tat.MessageCriteria{Reaction: "+1,ack"}),
+		"this message should match")
+
+	assert.Equal(t, false, matchMessageCriteria(
+		tat.Message{Reactions: map[string][]string{"ack": {}}},
+		&tat.MessageCriteria{Reaction: "ack"}),
+		"this message should not match")
+
+	assert.Equal(t, true, matchMessageCriteria(
+		tat.Message{Fields: map[string]interface{}{"build": float64(1250), "env": "prod"}},
+		&tat.MessageCriteria{Field: "build>=1200,build<1300,env=prod"}),
+		"this message should match")
+
+	assert.Equal(t, false, matchMessageCriteria(
+		tat.Message{Fields: map[string]interface{}{"build": float64(1250)}},
+		&tat.MessageCriteria{Field: "build>1250"}),
+		"this message should not match")
+}
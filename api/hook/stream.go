@@ -0,0 +1,255 @@
+package hook
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ovh/tat"
+	"github.com/ovh/tat/api/cache"
+	"github.com/ovh/tat/api/store"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Stream is a subscription of one user on some topics, events
+// matching criteria are sent on Events chan
+type Stream struct {
+	ID       string
+	Username string
+	Topics   []string
+	Criteria *tat.MessageCriteria
+	Events   chan *tat.HookMessageJSON
+}
+
+// socket is a stream, as saved in sockets collection
+type socket struct {
+	ID             string   `bson:"_id"`
+	Username       string   `bson:"username"`
+	Topics         []string `bson:"topics"`
+	Instance       string   `bson:"instance"`
+	DateConnection int64    `bson:"dateConnection"`
+}
+
+var (
+	streams      = make(map[string]*Stream)
+	streamsMutex = &sync.RWMutex{}
+)
+
+// Subscribe registers a new stream for user on topics. Caller have to check
+// read access on topics before and must call Unsubscribe when client is gone
+func Subscribe(username string, topics []string, criteria *tat.MessageCriteria) *Stream {
+	s := &Stream{
+		ID:       bson.NewObjectId().Hex(),
+		Username: username,
+		Topics:   topics,
+		Criteria: criteria,
+		Events:   make(chan *tat.HookMessageJSON, 100),
+	}
+
+	streamsMutex.Lock()
+	streams[s.ID] = s
+	streamsMutex.Unlock()
+
+	hostname, _ := os.Hostname()
+	if err := store.Tat().CSockets.Insert(&socket{
+		ID:             s.ID,
+		Username:       username,
+		Topics:         topics,
+		Instance:       hostname,
+		DateConnection: time.Now().Unix(),
+	}); err != nil {
+		log.Errorf("Error while saving stream %s for user %s: %s", s.ID, username, err)
+	}
+	return s
+}
+
+// Unsubscribe removes a stream
+func Unsubscribe(s *Stream) {
+	streamsMutex.Lock()
+	delete(streams, s.ID)
+	streamsMutex.Unlock()
+
+	if err := store.Tat().CSockets.RemoveId(s.ID); err != nil {
+		log.Errorf("Error while removing stream %s for user %s: %s", s.ID, s.Username, err)
+	}
+}
+
+// streamEvent is an event published to all instances, sent on their streams
+type streamEvent struct {
+	Topic       string               `json:"topic"`
+	HookMessage *tat.HookMessageJSON `json:"hookMessage"`
+}
+
+func streamsChannel() string {
+	return cache.Key("tat", "streams")
+}
+
+func redisEnabled() bool {
+	return viper.GetString("redis_hosts") != "" || viper.GetString("redis_sentinels") != ""
+}
+
+// initStreams removes sockets left by a previous run of this instance and, with
+// a redis, listens events published by all instances
+func initStreams() {
+	hostname, _ := os.Hostname()
+	if _, err := store.Tat().CSockets.RemoveAll(bson.M{"instance": hostname}); err != nil {
+		log.Errorf("Error while removing old streams of instance %s: %s", hostname, err)
+	}
+	if redisEnabled() {
+		go listenStreams()
+	}
+}
+
+func listenStreams() {
+	for {
+		pubsub, err := cache.Subscribe(streamsChannel())
+		if err != nil {
+			log.Errorf("Error while subscribing to streams events, retry in 5s: %s", err)
+			time.Sleep(5 * time.Second)
+			continue
+		}
+		for {
+			msg, err := pubsub.ReceiveMessage()
+			if err != nil {
+				log.Errorf("Error while receiving streams events: %s", err)
+				break
+			}
+			e := &streamEvent{}
+			if err := json.Unmarshal([]byte(msg.Payload), e); err != nil {
+				log.Errorf("Error while reading stream event: %s", err)
+				continue
+			}
+			sendOnStreams(e.HookMessage, e.Topic)
+		}
+		pubsub.Close()
+	}
+}
+
+// innerSendHookStreams publishes event to all instances if a stream is opened on topic,
+// event is sent only on streams of this instance if tat is not linked to a redis
+func innerSendHookStreams(h *tat.HookJSON, topic tat.Topic) {
+	if h.HookMessage == nil || h.HookMessage.MessageJSONOut == nil {
+		return
+	}
+	if !redisEnabled() {
+		sendOnStreams(h.HookMessage, topic.Topic)
+		return
+	}
+	if n, err := store.Tat().CSockets.Find(bson.M{"topics": topic.Topic}).Count(); err == nil && n == 0 {
+		return
+	}
+	b, err := json.Marshal(&streamEvent{Topic: topic.Topic, HookMessage: h.HookMessage})
+	if err != nil {
+		log.Errorf("Error while marshalling stream event on topic %s: %s", topic.Topic, err)
+		return
+	}
+	if err := cache.Client().Publish(streamsChannel(), string(b)).Err(); err != nil {
+		log.Errorf("Error while publishing stream event on topic %s: %s", topic.Topic, err)
+		sendOnStreams(h.HookMessage, topic.Topic)
+	}
+}
+
+func sendOnStreams(m *tat.HookMessageJSON, topic string) {
+	if m == nil || m.MessageJSONOut == nil {
+		return
+	}
+	streamsMutex.RLock()
+	defer streamsMutex.RUnlock()
+
+	for _, s := range streams {
+		if !tat.ArrayContains(s.Topics, topic) {
+			continue
+		}
+		if !matchMessageCriteria(m.MessageJSONOut.Message, s.Criteria) {
+			continue
+		}
+		select {
+		case s.Events <- m:
+		default:
+			log.Warnf("Stream %s of user %s is full, event %s on topic %s dropped", s.ID, s.Username, m.Action, topic)
+		}
+	}
+}
+
+// matchMessageCriteria checks criteria which can be computed on a message
+// without a request on database
+func matchMessageCriteria(m tat.Message, c *tat.MessageCriteria) bool {
+	if c == nil {
+		return true
+	}
+
+	if c.IDMessage != "" && c.IDMessage != m.ID {
+		return false
+	}
+	if c.InReplyOfID != "" && c.InReplyOfID != m.InReplyOfID {
+		return false
+	}
+	if c.InReplyOfIDRoot != "" && c.InReplyOfIDRoot != m.InReplyOfIDRoot {
+		return false
+	}
+	if c.AllIDMessage != "" && c.AllIDMessage != m.ID &&
+		c.AllIDMessage != m.InReplyOfID && c.AllIDMessage != m.InReplyOfIDRoot {
+		return false
+	}
+	if c.OnlyMsgReply == tat.True && m.InReplyOfID == "" {
+		return false
+	}
+	if c.Text != "" && !strings.Contains(strings.ToLower(m.Text), strings.ToLower(c.Text)) {
+		return false
+	}
+	if c.StartLabel != "" && !hasLabelPrefix(m, c.StartLabel) {
+		return false
+	}
+	if c.StartTag != "" && !hasTagPrefix(m, c.StartTag) {
+		return false
+	}
+	if c.Reaction != "" && !hasReaction(m, c.Reaction) {
+		return false
+	}
+	if c.Field != "" && !m.MatchFields(c.Field) {
+		return false
+	}
+
+	return matchCriteria(m, tat.FilterCriteria{
+		Label:       c.Label,
+		NotLabel:    c.NotLabel,
+		AndLabel:    c.AndLabel,
+		Tag:         c.Tag,
+		NotTag:      c.NotTag,
+		AndTag:      c.AndTag,
+		Username:    c.Username,
+		OnlyMsgRoot: c.OnlyMsgRoot == tat.True,
+	})
+}
+
+func hasLabelPrefix(m tat.Message, prefix string) bool {
+	for _, l := range m.Labels {
+		if strings.HasPrefix(l.Text, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasTagPrefix(m tat.Message, prefix string) bool {
+	for _, t := range m.Tags {
+		if strings.HasPrefix(t, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasReaction returns true if message has one of reactions, comma separated
+func hasReaction(m tat.Message, reactions string) bool {
+	for _, r := range strings.Split(reactions, ",") {
+		if len(m.Reactions[r]) > 0 {
+			return true
+		}
+	}
+	return false
+}
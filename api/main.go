@@ -7,10 +7,12 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/ovh/tat"
+	"github.com/ovh/tat/api/audit"
 	"github.com/ovh/tat/api/cache"
 	"github.com/ovh/tat/api/group"
 	"github.com/ovh/tat/api/hook"
 	"github.com/ovh/tat/api/message"
+	"github.com/ovh/tat/api/ratelimit"
 	"github.com/ovh/tat/api/store"
 	"github.com/ovh/tat/api/topic"
 	log "github.com/sirupsen/logrus"
@@ -58,9 +60,11 @@ var mainCmd = &cobra.Command{
 		topic.InitDB()
 		group.InitDB()
 		message.InitDB()
+		audit.MigrateHistory()
 
 		routerRoot := router.Group("")
 
+		initRoutesAudit(routerRoot, CheckPassword())
 		initRoutesGroups(routerRoot, CheckPassword())
 		initRoutesMessages(routerRoot, CheckPassword())
 		initRoutesPresences(routerRoot, CheckPassword())
@@ -70,6 +74,9 @@ var mainCmd = &cobra.Command{
 		initRoutesSystem(routerRoot, CheckPassword())
 		hook.InitHooks()
 		defer hook.CloseHooks()
+		go publishScheduledMessages()
+		go purgeExpiredMessages()
+		go notifyOverdueTasks()
 
 		s := &http.Server{
 			Addr:           ":" + viper.GetString("listen_port"),
@@ -81,6 +88,7 @@ var mainCmd = &cobra.Command{
 
 		log.Infof("TAT is running on %s", viper.GetString("listen_port"))
 		cache.TestInstanceAtStartup()
+		ratelimit.CheckAtStartup()
 
 		if err := s.ListenAndServe(); err != nil {
 			log.Infof("Error while running ListenAndServe: %s", err.Error())
@@ -93,7 +101,7 @@ func corsMiddleware() gin.HandlerFunc {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Max-Age", "0")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE, UPDATE")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, Tat_Password, Tat_Username, Tat-Password, Tat-Username")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, Tat_Password, Tat_Username, Tat-Password, Tat-Username, Idempotency-Key")
 		c.Writer.Header().Set("Access-Control-Expose-Headers", "Content-Length")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
 		c.Writer.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
@@ -208,6 +216,15 @@ func init() {
 	flags.Int("write-timeout", 50, "Write Timeout in seconds")
 	viper.BindPFlag("write_timeout", flags.Lookup("write-timeout"))
 
+	flags.Int("stream-heartbeat", 20, "Interval in seconds between two heartbeats sent on a stream")
+	viper.BindPFlag("stream_heartbeat", flags.Lookup("stream-heartbeat"))
+
+	flags.Int("stream-max-duration", 45, "Max duration in seconds of a stream, should be lower than write-timeout")
+	viper.BindPFlag("stream_max_duration", flags.Lookup("stream-max-duration"))
+
+	flags.String("db-text-search-language", "english", "Language used by full-text index on messages, for stemming and stop words. Use none to disable them")
+	viper.BindPFlag("db_text_search_language", flags.Lookup("db-text-search-language"))
+
 	flags.Int("db-socket-timeout", 40, "Session DB Socket Timeout in seconds")
 	viper.BindPFlag("db_socket_timeout", flags.Lookup("db-socket-timeout"))
 
@@ -276,6 +293,33 @@ func init() {
 
 	flags.Int("default-message-max-size", 1000, "Default max length of messages in a newly created topic")
 	viper.BindPFlag("default_message_max_size", flags.Lookup("default-message-max-size"))
+
+	flags.Int("scheduled-messages-interval", 10, "Interval in seconds between two checks of scheduled messages to post")
+	viper.BindPFlag("scheduled_messages_interval", flags.Lookup("scheduled-messages-interval"))
+
+	flags.Int("tasks-overdue-interval", 60, "Interval in seconds between two checks of tasks with an exceeded due date, sent to hooks. 0 to disable checks on this instance")
+	viper.BindPFlag("tasks_overdue_interval", flags.Lookup("tasks-overdue-interval"))
+
+	flags.Int("retention-purge-interval", 3600, "Interval in seconds between two purges of expired messages, on topics with a retention, and of trash. 0 to disable purge on this instance")
+	viper.BindPFlag("retention_purge_interval", flags.Lookup("retention-purge-interval"))
+
+	flags.Int("trash-retention", 604800, "Duration in seconds while deleted messages are kept in trash and can be undeleted. 0 to delete messages for good")
+	viper.BindPFlag("trash_retention", flags.Lookup("trash-retention"))
+
+	flags.Int64("default-attachments-quota", 104857600, "Default max size in bytes of all attachments on a topic, if topic has no attachmentsQuota")
+	viper.BindPFlag("default_attachments_quota", flags.Lookup("default-attachments-quota"))
+
+	flags.Int("rate-limit-user", 0, "Max messages created per minute by a user, on all topics. Shared by all instances through redis. 0 for no limit")
+	viper.BindPFlag("rate_limit_user", flags.Lookup("rate-limit-user"))
+
+	flags.Int("rate-limit-topic", 0, "Max messages created per minute on a topic. Shared by all instances through redis. 0 for no limit")
+	viper.BindPFlag("rate_limit_topic", flags.Lookup("rate-limit-topic"))
+
+	flags.Int("rate-limit-global", 0, "Max messages created per minute on all topics. Shared by all instances through redis. 0 for no limit")
+	viper.BindPFlag("rate_limit_global", flags.Lookup("rate-limit-global"))
+
+	flags.Int("idempotency-window", 86400, "Duration in seconds while an Idempotency-Key is remembered, per user and topic, on messages creation")
+	viper.BindPFlag("idempotency_window", flags.Lookup("idempotency-window"))
 }
 
 func main() {
@@ -4,7 +4,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"mime/multipart"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -25,6 +28,29 @@ import (
 
 const lengthLabel = 100
 
+const lengthReaction = 32
+
+// idempotencyPendingTimeout is the max duration of a creation with an Idempotency-Key.
+// After it, key is released even if creation did not finish
+const idempotencyPendingTimeout = time.Minute
+
+// ErrIdempotencyKeyInProgress is returned when a message with the same
+// Idempotency-Key is being created by another request
+var ErrIdempotencyKeyInProgress = errors.New("A request with the same Idempotency-Key is in progress")
+
+// ErrConflict is returned when a message was updated after the dateUpdate
+// given as precondition of an update
+var ErrConflict = errors.New("Message was updated since ifDateUpdate, get it again before updating it")
+
+// idempotencyEntry remembers result of a message creation, by user, topic and key
+type idempotencyEntry struct {
+	Username       string    `bson:"username"`
+	Topic          string    `bson:"topic"`
+	Key            string    `bson:"key"`
+	Out            string    `bson:"out"`
+	DateExpiration time.Time `bson:"dateExpiration"`
+}
+
 // InitDB gets all topics, for each topic with "collection" setted, add
 // collection to store
 func InitDB() {
@@ -89,6 +115,28 @@ func buildMessageCriteria(criteria *tat.MessageCriteria) (bson.M, error) {
 	if criteria.Text != "" {
 		query = append(query, bson.M{"text": bson.RegEx{Pattern: "^.*" + regexp.QuoteMeta(criteria.Text) + ".*$", Options: "im"}})
 	}
+	if criteria.TextSearch != "" {
+		query = append(query, bson.M{"$text": bson.M{"$search": criteria.TextSearch}})
+	}
+	if criteria.Field != "" {
+		queryFields, err := buildFieldsCriteria(criteria.Field)
+		if err != nil {
+			return bson.M{}, err
+		}
+		query = append(query, queryFields...)
+	}
+	if criteria.Reaction != "" {
+		queryReactions := bson.M{}
+		queryReactions["$or"] = []bson.M{}
+		for _, val := range strings.Split(criteria.Reaction, ",") {
+			r, err := checkReaction(val)
+			if err != nil {
+				return bson.M{}, err
+			}
+			queryReactions["$or"] = append(queryReactions["$or"].([]bson.M), bson.M{"reactions." + r + ".0": bson.M{"$exists": true}})
+		}
+		query = append(query, queryReactions)
+	}
 
 	if criteria.Topic != "" {
 		queryTopics := bson.M{}
@@ -355,6 +403,18 @@ func buildMessageCriteria(criteria *tat.MessageCriteria) (bson.M, error) {
 		criteria.SortBy = "-dateCreation"
 	}
 
+	if criteria.SortBy == tat.SortByRelevance && criteria.TextSearch == "" {
+		return bson.M{}, fmt.Errorf("textSearch is required with sortBy relevance")
+	}
+
+	if criteria.Cursor != "" {
+		queryCursor, err := store.CursorQuery(criteria.Cursor, criteria.SortBy, tat.MessageCursorSortFields)
+		if err != nil {
+			return bson.M{}, err
+		}
+		query = append(query, queryCursor)
+	}
+
 	if (criteria.TreeView == tat.TreeViewFullTree || criteria.TreeView == tat.TreeViewOneTree) && criteria.SortBy != "-dateCreation" {
 		return bson.M{}, fmt.Errorf("Sort must be -dateCreation or treeView will not work")
 	}
@@ -484,12 +544,54 @@ func cacheMessageList(criteria *tat.MessageCriteria, topic *tat.Topic, messages
 	return nil
 }
 
+// buildFieldsCriteria returns one query per condition on fields, ex: build>=1200.
+// Equality matches a number or a bool written as text too
+func buildFieldsCriteria(conditions string) ([]bson.M, error) {
+	cs, err := tat.ParseFieldConditions(conditions)
+	if err != nil {
+		return nil, err
+	}
+	query := []bson.M{}
+	for _, c := range cs {
+		key := "fields." + c.Name
+		switch c.Operator {
+		case tat.FieldOperatorEqual:
+			query = append(query, bson.M{key: bson.M{"$in": c.Values()}})
+		case tat.FieldOperatorNotEqual:
+			query = append(query, bson.M{key: bson.M{"$nin": c.Values()}})
+		case tat.FieldOperatorGreater:
+			query = append(query, bson.M{key: bson.M{"$gt": c.Value()}})
+		case tat.FieldOperatorGreaterOrEqual:
+			query = append(query, bson.M{key: bson.M{"$gte": c.Value()}})
+		case tat.FieldOperatorLower:
+			query = append(query, bson.M{key: bson.M{"$lt": c.Value()}})
+		case tat.FieldOperatorLowerOrEqual:
+			query = append(query, bson.M{key: bson.M{"$lte": c.Value()}})
+		}
+	}
+	return query, nil
+}
+
+// addPinnedCriteria restricts query to pinned messages of topic if
+// criteria.Pinned is true. Pinned ids are stored on topic, not on messages
+func addPinnedCriteria(c bson.M, criteria *tat.MessageCriteria, topic tat.Topic) bson.M {
+	if criteria.Pinned != tat.True {
+		return c
+	}
+	pinned := topic.Pinned
+	if pinned == nil {
+		pinned = []string{}
+	}
+	return bson.M{"$and": []bson.M{c, {"_id": bson.M{"$in": pinned}}}}
+}
+
 // ListMessages list messages with given criteria
 func ListMessages(criteria *tat.MessageCriteria, username string, topic tat.Topic) ([]tat.Message, error) {
 	c, errc := buildMessageCriteria(criteria)
 	if errc != nil {
 		return []tat.Message{}, errc
 	}
+	c = addPinnedCriteria(c, criteria, topic)
 
 	var messages []tat.Message
 	var err error
@@ -518,9 +620,14 @@ func ListMessages(criteria *tat.MessageCriteria, username string, topic tat.Topi
 			criteria.Limit, username, topic.Topic, criteria.GetURL())
 	}
 
-	err = store.GetCMessages(topic.Collection).Find(c).
-		Sort(criteria.SortBy).
-		Skip(criteria.Skip).
+	query := store.GetCMessages(topic.Collection).Find(c)
+	if criteria.SortBy == tat.SortByRelevance {
+		query = query.Select(bson.M{"score": bson.M{"$meta": "textScore"}}).Sort("$textScore:score")
+	} else {
+		query = query.Sort(tat.CursorSortFields(criteria.SortBy, tat.MessageCursorSortFields)...)
+	}
+
+	err = query.Skip(criteria.Skip).
 		Limit(criteria.Limit).
 		All(&messages)
 	if err != nil {
@@ -770,6 +877,14 @@ func Insert(message *tat.Message, user tat.User, topic tat.Topic, text, inReplyO
 		}
 	}
 
+	if message.Fields != nil {
+		fields, err := tat.CheckMessageFields(message.Fields)
+		if err != nil {
+			return err
+		}
+		message.Fields = fields
+	}
+
 	message.InReplyOfID = inReplyOfID
 	idToReply := inReplyOfID
 
@@ -857,7 +972,9 @@ func Insert(message *tat.Message, user tat.User, topic tat.Topic, text, inReplyO
 	}
 
 	if message.Text != "" { // if no text, no reply to insert, but try after to add reply with "replies" attr
-		message.ID = bson.NewObjectId().Hex()
+		if message.ID == "" { // ID is given only on import
+			message.ID = bson.NewObjectId().Hex()
+		}
 		idToReply = message.ID
 		message.NbLikes = 0
 		var author = tat.Author{}
@@ -898,7 +1015,7 @@ func Insert(message *tat.Message, user tat.User, topic tat.Topic, text, inReplyO
 	}
 	if len(repliesJSON) > 0 {
 		for _, r := range repliesJSON {
-			reply := tat.Message{}
+			reply := tat.Message{Fields: r.Fields}
 			Insert(&reply, user, topic, r.Text, idToReply, r.DateCreation, r.Labels, nil, r.Messages, message)
 		}
 	}
@@ -1015,6 +1132,12 @@ func checkLabels(labels []tat.Label, labelsToAdd []tat.Label, labelsToRemove []s
 	return labelsChecked
 }
 
+// MergeLabels returns labels without labelsToRemove, with labelsToAdd, as they
+// would be saved on a message
+func MergeLabels(labels []tat.Label, labelsToAdd []tat.Label, labelsToRemove []string) []tat.Label {
+	return checkLabels(labels, labelsToAdd, labelsToRemove)
+}
+
 // CheckAndFixText truncates to maxLength (parameter on topic) characters
 // if len < 1, return error
 func CheckAndFixText(message *tat.Message, topic tat.Topic) error {
@@ -1037,7 +1160,10 @@ func CheckAndFixText(message *tat.Message, topic tat.Topic) error {
 
 // Update updates a message from database
 // action could be concat (for adding additional text to message or update)
-func Update(message *tat.Message, user tat.User, topic tat.Topic, newText string, action string) error {
+// If ifDateUpdate is not 0, message is updated only if it was not updated since, ErrConflict otherwise
+func Update(message *tat.Message, user tat.User, topic tat.Topic, newText string, action string, ifDateUpdate float64) error {
+	previous := *message
+
 	if action == "concat" {
 		message.Text += newText
 	} else {
@@ -1048,18 +1174,30 @@ func Update(message *tat.Message, user tat.User, topic tat.Topic, newText string
 		return err
 	}
 
-	err := store.GetCMessages(topic.Collection).Update(
-		bson.M{"_id": message.ID},
+	idRevision, err := addRevision(previous, user, topic)
+	if err != nil {
+		return err
+	}
+
+	dateUpdate := tat.TSFromNow()
+	err = store.GetCMessages(topic.Collection).Update(
+		selectorIfDateUpdate(message, ifDateUpdate),
 		bson.M{"$set": bson.M{
 			"text":         message.Text,
-			"dateUpdate":   tat.TSFromNow(),
+			"dateUpdate":   dateUpdate,
 			"tags":         hashtag.ExtractHashtags(message.Text),
 			"userMentions": hashtag.ExtractMentions(message.Text),
 			"urls":         xurls.Strict.FindAllString(message.Text, -1),
 		}})
 	if err != nil {
 		log.Errorf("Error while update a message %s", err)
+		if err = conflictOr(err, ifDateUpdate); err == ErrConflict {
+			// message is not updated, revision is not kept
+			removeRevisions(bson.M{"_id": idRevision})
+			return err
+		}
 	}
+	message.DateUpdate = dateUpdate
 
 	//Clean the cache for this topic
 	cache.CleanMessagesLists(topic.Topic)
@@ -1069,6 +1207,375 @@ func Update(message *tat.Message, user tat.User, topic tat.Topic, newText string
 	return nil
 }
 
+// addRevision saves current version of message, before an update. Returns ID of revision
+func addRevision(message tat.Message, user tat.User, topic tat.Topic) (string, error) {
+	revision := tat.MessageRevision{
+		ID:              bson.NewObjectId().Hex(),
+		IDMessage:       message.ID,
+		InReplyOfIDRoot: message.InReplyOfIDRoot,
+		Topic:           topic.Topic,
+		Text:            message.Text,
+		Labels:          message.Labels,
+		DateUpdate:      message.DateUpdate,
+		DateRevision:    tat.TSFromNow(),
+		UpdatedBy:       tat.Author{Username: user.Username, Fullname: user.Fullname},
+	}
+	if err := store.Tat().CRevisions.Insert(revision); err != nil {
+		log.Errorf("Error while saving revision of message %s: %s", message.ID, err)
+		return "", fmt.Errorf("Error while saving revision of message %s", message.ID)
+	}
+	return revision.ID, nil
+}
+
+func removeRevisions(selector bson.M) {
+	if _, err := store.Tat().CRevisions.RemoveAll(selector); err != nil {
+		log.Errorf("Error while removing revisions %v: %s", selector, err)
+	}
+}
+
+// ListRevisions returns previous versions of a message, last first
+func ListRevisions(message tat.Message) ([]tat.MessageRevision, error) {
+	revisions := []tat.MessageRevision{}
+	err := store.Tat().CRevisions.Find(bson.M{"idMessage": message.ID}).Sort("-dateRevision").All(&revisions)
+	if err != nil {
+		log.Errorf("Error while listing revisions of message %s: %s", message.ID, err)
+	}
+	return revisions, err
+}
+
+// FindRevision returns a revision of a message
+func FindRevision(revision *tat.MessageRevision, id string, message tat.Message) error {
+	err := store.Tat().CRevisions.Find(bson.M{"_id": id, "idMessage": message.ID}).One(revision)
+	if err != nil && err != mgo.ErrNotFound {
+		log.Errorf("Error while fetching revision %s of message %s: %s", id, message.ID, err)
+	}
+	return err
+}
+
+// RestoreRevision replaces text and labels of a message with the ones of a revision.
+// Current version of message is saved as a new revision
+func RestoreRevision(message *tat.Message, revision tat.MessageRevision, user tat.User, topic tat.Topic) error {
+	if _, err := addRevision(*message, user, topic); err != nil {
+		return err
+	}
+
+	message.Text = revision.Text
+	message.Labels = revision.Labels
+	message.Tags = hashtag.ExtractHashtags(message.Text)
+	message.UserMentions = hashtag.ExtractMentions(message.Text)
+	message.Urls = xurls.Strict.FindAllString(message.Text, -1)
+	message.DateUpdate = tat.TSFromNow()
+
+	err := store.GetCMessages(topic.Collection).Update(
+		bson.M{"_id": message.ID},
+		bson.M{"$set": bson.M{
+			"text":         message.Text,
+			"labels":       message.Labels,
+			"dateUpdate":   message.DateUpdate,
+			"tags":         message.Tags,
+			"userMentions": message.UserMentions,
+			"urls":         message.Urls,
+		}})
+	if err != nil {
+		log.Errorf("Error while restoring revision %s of message %s: %s", revision.ID, message.ID, err)
+		return err
+	}
+
+	//Clean the cache for this topic
+	cache.CleanMessagesLists(topic.Topic)
+
+	go topicDB.UpdateTopicTags(&topic, message.Tags)
+	go topicDB.UpdateTopicLabels(&topic, message.Labels)
+
+	return nil
+}
+
+// AttachmentsQuota returns max size in bytes of all attachments on a topic
+func AttachmentsQuota(topic tat.Topic) int64 {
+	if topic.AttachmentsQuota > 0 {
+		return topic.AttachmentsQuota
+	}
+	return viper.GetInt64("default_attachments_quota")
+}
+
+// CheckAttachmentsQuota returns an error if adding files on topic exceeds
+// attachments quota of topic
+func CheckAttachmentsQuota(topic tat.Topic, files []*multipart.FileHeader) error {
+	var size int64
+	for _, f := range files {
+		size += f.Size
+	}
+	used, err := store.AttachmentsSize(topic.Topic)
+	if err != nil {
+		log.Errorf("Error while computing size of attachments on topic %s: %s", topic.Topic, err)
+		return fmt.Errorf("Error while computing size of attachments on topic %s", topic.Topic)
+	}
+	if quota := AttachmentsQuota(topic); used+size > quota {
+		return fmt.Errorf("Attachments quota exceeded on topic %s: %d bytes used, %d bytes allowed", topic.Topic, used, quota)
+	}
+	return nil
+}
+
+// AddAttachments saves files on GridFS and adds them to attachments of message.
+// Size of all attachments of topic can't exceed quota of topic
+func AddAttachments(message *tat.Message, user tat.User, topic tat.Topic, files []*multipart.FileHeader) error {
+	if err := CheckAttachmentsQuota(topic, files); err != nil {
+		return err
+	}
+
+	gfs := store.GridFSAttachments()
+	attachments := []tat.Attachment{}
+	for _, f := range files {
+		a, err := saveAttachment(gfs, message, user, topic, f)
+		if err != nil {
+			for _, saved := range attachments {
+				gfs.RemoveId(saved.ID)
+			}
+			return err
+		}
+		attachments = append(attachments, a)
+	}
+
+	if err := store.GetCMessages(topic.Collection).Update(
+		bson.M{"_id": message.ID},
+		bson.M{"$push": bson.M{"attachments": bson.M{"$each": attachments}}}); err != nil {
+		log.Errorf("Error while adding attachments on message %s: %s", message.ID, err)
+		for _, saved := range attachments {
+			gfs.RemoveId(saved.ID)
+		}
+		return err
+	}
+	message.Attachments = append(message.Attachments, attachments...)
+
+	//Clean the cache for this topic
+	cache.CleanMessagesLists(topic.Topic)
+	return nil
+}
+
+func saveAttachment(gfs *mgo.GridFS, message *tat.Message, user tat.User, topic tat.Topic, f *multipart.FileHeader) (tat.Attachment, error) {
+	src, err := f.Open()
+	if err != nil {
+		return tat.Attachment{}, fmt.Errorf("Error while reading attachment %s: %s", f.Filename, err)
+	}
+	defer src.Close()
+
+	file, err := gfs.Create(f.Filename)
+	if err != nil {
+		log.Errorf("Error while creating attachment %s on message %s: %s", f.Filename, message.ID, err)
+		return tat.Attachment{}, fmt.Errorf("Error while saving attachment %s", f.Filename)
+	}
+
+	a := tat.Attachment{
+		ID:          bson.NewObjectId().Hex(),
+		Filename:    f.Filename,
+		ContentType: f.Header.Get("Content-Type"),
+	}
+	if a.ContentType == "" {
+		a.ContentType = "application/octet-stream"
+	}
+	file.SetId(a.ID)
+	file.SetContentType(a.ContentType)
+	file.SetMeta(store.AttachmentMeta{
+		Topic:           topic.Topic,
+		IDMessage:       message.ID,
+		InReplyOfIDRoot: message.InReplyOfIDRoot,
+		Username:        user.Username,
+	})
+
+	if _, err := io.Copy(file, src); err != nil {
+		file.Abort()
+		file.Close()
+		log.Errorf("Error while writing attachment %s on message %s: %s", f.Filename, message.ID, err)
+		return tat.Attachment{}, fmt.Errorf("Error while saving attachment %s", f.Filename)
+	}
+	if err := file.Close(); err != nil {
+		log.Errorf("Error while closing attachment %s on message %s: %s", f.Filename, message.ID, err)
+		return tat.Attachment{}, fmt.Errorf("Error while saving attachment %s", f.Filename)
+	}
+	a.Size = file.Size()
+	return a, nil
+}
+
+// OpenAttachment opens content of an attachment of a message.
+// Caller have to close returned file
+func OpenAttachment(message tat.Message, id string) (*tat.Attachment, *mgo.GridFile, error) {
+	for _, a := range message.Attachments {
+		if a.ID == id {
+			file, err := store.GridFSAttachments().OpenId(id)
+			if err != nil {
+				log.Errorf("Error while opening attachment %s of message %s: %s", id, message.ID, err)
+				return nil, nil, err
+			}
+			return &a, file, nil
+		}
+	}
+	return nil, nil, mgo.ErrNotFound
+}
+
+// InsertScheduled saves a message to post later, at s.DateScheduled
+func InsertScheduled(s *tat.ScheduledMessage) error {
+	s.ID = bson.NewObjectId().Hex()
+	s.DateCreation = tat.TSFromNow()
+	if err := store.Tat().CScheduled.Insert(s); err != nil {
+		log.Errorf("Error while inserting scheduled message on topic %s: %s", s.Topic, err)
+		return fmt.Errorf("Error while saving scheduled message")
+	}
+	return nil
+}
+
+// ListScheduled returns messages scheduled by a user, next first
+func ListScheduled(username string) ([]tat.ScheduledMessage, error) {
+	scheduled := []tat.ScheduledMessage{}
+	err := store.Tat().CScheduled.Find(bson.M{"author.username": username}).Sort("dateScheduled").All(&scheduled)
+	if err != nil {
+		log.Errorf("Error while listing scheduled messages of user %s: %s", username, err)
+	}
+	return scheduled, err
+}
+
+// DeleteScheduled cancels a message scheduled by a user
+func DeleteScheduled(id, username string) error {
+	err := store.Tat().CScheduled.Remove(bson.M{"_id": id, "author.username": username})
+	if err != nil && err != mgo.ErrNotFound {
+		log.Errorf("Error while removing scheduled message %s of user %s: %s", id, username, err)
+	}
+	return err
+}
+
+// scheduledLease is the time given to an instance to post a claimed scheduled
+// message. After it, the instance is considered dead and message can be claimed again
+const scheduledLease = 5 * 60
+
+// ClaimScheduled returns next scheduled message to post, nil if none. Claiming is
+// atomic, so only one tat instance gets each scheduled message. Message stays in
+// collection until RemoveScheduled or FailScheduled is called
+func ClaimScheduled() (*tat.ScheduledMessage, error) {
+	now := tat.TSFromNow()
+	s := &tat.ScheduledMessage{}
+	_, err := store.Tat().CScheduled.
+		Find(bson.M{
+			"dateScheduled": bson.M{"$lte": now},
+			"error":         bson.M{"$exists": false},
+			"$or": []bson.M{
+				{"dateLease": bson.M{"$exists": false}},
+				{"dateLease": bson.M{"$lt": now - scheduledLease}},
+			},
+		}).
+		Sort("dateScheduled").
+		Apply(mgo.Change{Update: bson.M{"$set": bson.M{"dateLease": now}}, ReturnNew: true}, s)
+	if err == mgo.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		log.Errorf("Error while claiming next scheduled message: %s", err)
+		return nil, err
+	}
+	return s, nil
+}
+
+// RemoveScheduled removes a scheduled message, once posted
+func RemoveScheduled(id string) error {
+	err := store.Tat().CScheduled.RemoveId(id)
+	if err != nil {
+		log.Errorf("Error while removing posted scheduled message %s: %s", id, err)
+	}
+	return err
+}
+
+// ReleaseScheduled releases a claimed scheduled message, to post it later
+func ReleaseScheduled(id string) error {
+	err := store.Tat().CScheduled.UpdateId(id, bson.M{"$unset": bson.M{"dateLease": ""}})
+	if err != nil {
+		log.Errorf("Error while releasing scheduled message %s: %s", id, err)
+	}
+	return err
+}
+
+// FailScheduled keeps a scheduled message which can't be posted, with the error
+// for its author. It will not be retried, author can only cancel it
+func FailScheduled(id string, errPost error) error {
+	err := store.Tat().CScheduled.UpdateId(id, bson.M{
+		"$set":   bson.M{"error": errPost.Error()},
+		"$unset": bson.M{"dateLease": ""},
+	})
+	if err != nil {
+		log.Errorf("Error while saving error on scheduled message %s: %s", id, err)
+	}
+	return err
+}
+
+// ReserveIdempotencyKey reserves an Idempotency-Key for a user on a topic before
+// creating a message. If key was already used in idempotency_window seconds,
+// the result of first creation is returned
+func ReserveIdempotencyKey(key, username, topic string) (*tat.MessageJSONOut, error) {
+	sel := bson.M{"username": username, "topic": topic, "key": key}
+	entry := idempotencyEntry{Username: username, Topic: topic, Key: key, DateExpiration: time.Now().Add(idempotencyPendingTimeout)}
+
+	err := store.Tat().CIdempotency.Insert(entry)
+	if err == nil {
+		return nil, nil
+	}
+	if !mgo.IsDup(err) {
+		log.Errorf("Error while reserving Idempotency-Key %s for %s on topic %s: %s", key, username, topic, err)
+		return nil, fmt.Errorf("Error while checking Idempotency-Key")
+	}
+
+	var existing idempotencyEntry
+	if err := store.Tat().CIdempotency.Find(sel).One(&existing); err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, ErrIdempotencyKeyInProgress
+		}
+		log.Errorf("Error while fetching Idempotency-Key %s for %s on topic %s: %s", key, username, topic, err)
+		return nil, fmt.Errorf("Error while checking Idempotency-Key")
+	}
+
+	if existing.DateExpiration.Before(time.Now()) {
+		// expired, but not yet removed by mongo: take it if nobody else did
+		sel["dateExpiration"] = existing.DateExpiration
+		if err := store.Tat().CIdempotency.Update(sel, entry); err != nil {
+			return nil, ErrIdempotencyKeyInProgress
+		}
+		return nil, nil
+	}
+
+	if existing.Out == "" {
+		return nil, ErrIdempotencyKeyInProgress
+	}
+
+	out := &tat.MessageJSONOut{}
+	if err := json.Unmarshal([]byte(existing.Out), out); err != nil {
+		log.Errorf("Error while reading result of Idempotency-Key %s for %s on topic %s: %s", key, username, topic, err)
+		return nil, fmt.Errorf("Error while checking Idempotency-Key")
+	}
+	return out, nil
+}
+
+// SaveIdempotencyKey saves result of a message creation, returned on next
+// requests with the same key, until idempotency_window expires
+func SaveIdempotencyKey(key, username, topic string, out *tat.MessageJSONOut) {
+	b, err := json.Marshal(out)
+	if err != nil {
+		log.Errorf("Error while marshalling result of Idempotency-Key %s: %s", key, err)
+		ReleaseIdempotencyKey(key, username, topic)
+		return
+	}
+	window := time.Duration(viper.GetInt("idempotency_window")) * time.Second
+	err = store.Tat().CIdempotency.Update(
+		bson.M{"username": username, "topic": topic, "key": key},
+		bson.M{"$set": bson.M{"out": string(b), "dateExpiration": time.Now().Add(window)}})
+	if err != nil {
+		log.Errorf("Error while saving Idempotency-Key %s for %s on topic %s: %s", key, username, topic, err)
+	}
+}
+
+// ReleaseIdempotencyKey removes a reserved key, when creation failed
+func ReleaseIdempotencyKey(key, username, topic string) {
+	err := store.Tat().CIdempotency.Remove(bson.M{"username": username, "topic": topic, "key": key})
+	if err != nil && err != mgo.ErrNotFound {
+		log.Errorf("Error while releasing Idempotency-Key %s for %s on topic %s: %s", key, username, topic, err)
+	}
+}
+
 // Move moves a message to another topic
 func Move(message *tat.Message, user tat.User, fromTopic tat.Topic, toTopic tat.Topic) error {
 
@@ -1114,6 +1621,16 @@ func Move(message *tat.Message, user tat.User, fromTopic tat.Topic, toTopic tat.
 		log.Errorf("Error while update messages (move topic to %s) idMsgRoot:%s err:%s", toTopic.Topic, message.ID, err)
 	}
 
+	if _, errRev := store.Tat().CRevisions.UpdateAll(
+		bson.M{"$or": []bson.M{{"idMessage": message.ID}, {"inReplyOfIDRoot": message.ID}}},
+		bson.M{"$set": bson.M{"topic": toTopic.Topic}}); errRev != nil {
+		log.Errorf("Error while update revisions (move topic to %s) idMsgRoot:%s err:%s", toTopic.Topic, message.ID, errRev)
+	}
+	store.MoveAttachments(bson.M{"$or": []bson.M{{"metadata.idMessage": message.ID}, {"metadata.inReplyOfIDRoot": message.ID}}}, toTopic.Topic)
+	if fromTopic.Topic != toTopic.Topic {
+		removePinned(&fromTopic, message.ID)
+	}
+
 	cache.CleanMessagesLists(fromTopic.Topic)
 	cache.CleanMessagesLists(toTopic.Topic)
 
@@ -1122,6 +1639,13 @@ func Move(message *tat.Message, user tat.User, fromTopic tat.Topic, toTopic tat.
 
 // Delete deletes a message from database
 func Delete(message *tat.Message, cascade bool, topic tat.Topic) error {
+	return deleteMessage(message, cascade, topic, true)
+}
+
+// deleteMessage removes a message, and its replies if cascade. Revisions
+// and attachments are removed only if withRelated, they are kept for trash
+func deleteMessage(message *tat.Message, cascade bool, topic tat.Topic, withRelated bool) error {
+	removePinned(&topic, message.ID)
 	if message.InReplyOfID != "" {
 		var messageParent = &tat.Message{}
 		if err := FindByID(messageParent, message.InReplyOfID, topic); err != nil {
@@ -1137,11 +1661,19 @@ func Delete(message *tat.Message, cascade bool, topic tat.Topic) error {
 	}
 
 	if cascade {
+		if withRelated {
+			removeRevisions(bson.M{"$or": []bson.M{{"idMessage": message.ID}, {"inReplyOfIDRoot": message.ID}}})
+			store.RemoveAttachments(bson.M{"$or": []bson.M{{"metadata.idMessage": message.ID}, {"metadata.inReplyOfIDRoot": message.ID}}})
+		}
 		_, err := store.GetCMessages(topic.Collection).RemoveAll(bson.M{"$or": []bson.M{{"_id": message.ID}, {"inReplyOfIDRoot": message.ID}}})
 		//Clean the cache for this topic
 		cache.CleanMessagesLists(topic.Topic)
 		return err
 	}
+	if withRelated {
+		removeRevisions(bson.M{"idMessage": message.ID})
+		store.RemoveAttachments(bson.M{"metadata.idMessage": message.ID})
+	}
 	if err := store.GetCMessages(topic.Collection).Remove(bson.M{"_id": message.ID}); err != nil {
 		//Clean the cache for this topic
 		cache.CleanMessagesLists(topic.Topic)
@@ -1153,9 +1685,269 @@ func Delete(message *tat.Message, cascade bool, topic tat.Topic) error {
 	return nil
 }
 
+// Trash moves a message to trash, and its replies if cascade. Messages stay in trash
+// trash_retention seconds with their revisions and attachments, until PurgeTrash.
+// If trash_retention is 0, message is deleted for good
+func Trash(message *tat.Message, cascade bool, user tat.User, topic tat.Topic) error {
+	if viper.GetInt("trash_retention") <= 0 {
+		return Delete(message, cascade, topic)
+	}
+
+	messages := []tat.Message{*message}
+	if cascade {
+		var replies []tat.Message
+		if err := store.GetCMessages(topic.Collection).Find(bson.M{"inReplyOfIDRoot": message.ID}).All(&replies); err != nil {
+			log.Errorf("Trash: Error while fetching replies of message %s: %s", message.ID, err)
+			return err
+		}
+		messages = append(messages, replies...)
+	}
+
+	ids := make([]string, 0, len(messages))
+	docs := make([]interface{}, 0, len(messages))
+	now := tat.TSFromNow()
+	for _, m := range messages {
+		t := tat.TrashedMessage{
+			ID:           m.ID,
+			IDDeletion:   message.ID,
+			Topic:        topic.Topic,
+			Message:      m,
+			DeletedBy:    user.Username,
+			DateDeletion: now,
+		}
+		if m.ID == message.ID {
+			t.NbMessages = len(messages)
+		}
+		ids = append(ids, m.ID)
+		docs = append(docs, t)
+	}
+
+	// a message can be trashed again after an undelete
+	if _, err := store.Tat().CTrash.RemoveAll(bson.M{"_id": bson.M{"$in": ids}}); err != nil {
+		return err
+	}
+	if err := store.Tat().CTrash.Insert(docs...); err != nil {
+		log.Errorf("Trash: Error while inserting message %s in trash: %s", message.ID, err)
+		return err
+	}
+	if err := deleteMessage(message, cascade, topic, false); err != nil {
+		store.Tat().CTrash.RemoveAll(bson.M{"idDeletion": message.ID})
+		return err
+	}
+	return nil
+}
+
+// ListTrash returns messages deleted from a topic, last deleted first.
+// Replies deleted with a message are not returned
+func ListTrash(topic tat.Topic, skip, limit int) ([]tat.TrashedMessage, error) {
+	var trashed []tat.TrashedMessage
+	err := store.Tat().CTrash.Find(bson.M{"topic": topic.Topic, "nbMessages": bson.M{"$gt": 0}}).
+		Sort("-dateDeletion").
+		Skip(skip).
+		Limit(limit).
+		All(&trashed)
+	return trashed, err
+}
+
+// Undelete restores a message from trash, with replies deleted with it.
+// Returns restored messages
+func Undelete(idMessage string, topic tat.Topic) ([]tat.Message, error) {
+	var trashed tat.TrashedMessage
+	if err := store.Tat().CTrash.Find(bson.M{"_id": idMessage, "topic": topic.Topic}).One(&trashed); err != nil {
+		return nil, fmt.Errorf("Message %s is not in trash of topic %s", idMessage, topic.Topic)
+	}
+	if trashed.IDDeletion != trashed.ID {
+		return nil, fmt.Errorf("Message %s was deleted with message %s, please undelete message %s", idMessage, trashed.IDDeletion, trashed.IDDeletion)
+	}
+
+	message := trashed.Message
+	if message.InReplyOfID != "" {
+		var parent tat.Message
+		if err := FindByID(&parent, message.InReplyOfID, topic); err != nil {
+			return nil, fmt.Errorf("Message %s can't be undeleted, message %s does not exist anymore", idMessage, message.InReplyOfID)
+		}
+	}
+
+	var entries []tat.TrashedMessage
+	if err := store.Tat().CTrash.Find(bson.M{"idDeletion": trashed.ID}).Sort("message.dateCreation").All(&entries); err != nil {
+		return nil, err
+	}
+	messages := make([]tat.Message, 0, len(entries))
+	docs := make([]interface{}, 0, len(entries))
+	for _, e := range entries {
+		e.Message.Topic = topic.Topic
+		messages = append(messages, e.Message)
+		docs = append(docs, e.Message)
+	}
+	if err := store.GetCMessages(topic.Collection).Insert(docs...); err != nil {
+		log.Errorf("Undelete: Error while restoring message %s: %s", idMessage, err)
+		return nil, err
+	}
+	if message.InReplyOfID != "" {
+		if err := store.GetCMessages(topic.Collection).Update(
+			bson.M{"_id": message.InReplyOfID},
+			bson.M{"$inc": bson.M{"nbReplies": 1}}); err != nil {
+			log.Errorf("Undelete: Error while updating message parent %s: %s", message.InReplyOfID, err)
+		}
+	}
+	if _, err := store.Tat().CTrash.RemoveAll(bson.M{"idDeletion": trashed.ID}); err != nil {
+		log.Errorf("Undelete: Error while removing message %s from trash: %s", idMessage, err)
+	}
+
+	//Clean the cache for this topic
+	cache.CleanMessagesLists(topic.Topic)
+	return messages, nil
+}
+
+// PurgeTrash removes for good messages in trash for more than trash_retention
+// seconds, with their revisions and attachments. Returns number of messages removed
+func PurgeTrash() (int, error) {
+	dateLimit := tat.TSFromDate(time.Now().Add(-time.Duration(viper.GetInt("trash_retention")) * time.Second))
+
+	nb := 0
+	ids := []string{}
+	var trashed struct {
+		ID string `bson:"_id"`
+	}
+	iter := store.Tat().CTrash.Find(bson.M{"dateDeletion": bson.M{"$lt": dateLimit}}).Select(bson.M{"_id": 1}).Iter()
+	for iter.Next(&trashed) {
+		ids = append(ids, trashed.ID)
+		if len(ids) == 1000 {
+			n, err := removeFromTrash(ids)
+			nb += n
+			if err != nil {
+				iter.Close()
+				return nb, err
+			}
+			ids = ids[:0]
+		}
+	}
+	if err := iter.Close(); err != nil {
+		log.Errorf("PurgeTrash: Error while listing expired messages in trash, err:%s", err)
+		return nb, err
+	}
+	if len(ids) > 0 {
+		n, err := removeFromTrash(ids)
+		nb += n
+		if err != nil {
+			return nb, err
+		}
+	}
+	return nb, nil
+}
+
+func removeFromTrash(ids []string) (int, error) {
+	removeRevisions(bson.M{"idMessage": bson.M{"$in": ids}})
+	store.RemoveAttachments(bson.M{"metadata.idMessage": bson.M{"$in": ids}})
+	changeInfo, err := store.Tat().CTrash.RemoveAll(bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		log.Errorf("Error while removing expired messages from trash, err:%s", err)
+		return 0, err
+	}
+	return changeInfo.Removed, nil
+}
+
+// PurgeExpired deletes threads of a topic, root message and its replies, older than
+// retentionMaxAge seconds or beyond the retentionMaxMessages last updated threads.
+// Threads in tasks of a user (with a doing label) and pinned threads are kept.
+// Returns number of deleted messages
+func PurgeExpired(topic tat.Topic) (int, error) {
+	roots := bson.M{"topic": topic.Topic, "inReplyOfIDRoot": ""}
+
+	var dateLimit float64
+	if topic.RetentionMaxAge > 0 {
+		dateLimit = tat.TSFromDate(time.Now().Add(-time.Duration(topic.RetentionMaxAge) * time.Second))
+	}
+	if topic.RetentionMaxMessages > 0 {
+		var lastKeep tat.Message
+		err := store.GetCMessages(topic.Collection).Find(roots).
+			Sort("-dateUpdate").
+			Skip(topic.RetentionMaxMessages - 1).
+			Limit(1).
+			Select(bson.M{"dateUpdate": 1}).
+			One(&lastKeep)
+		if err != nil && err != mgo.ErrNotFound {
+			log.Errorf("PurgeExpired: Error while finding last message to keep on topic %s, err:%s", topic.Topic, err)
+			return 0, err
+		}
+		if err == nil && lastKeep.DateUpdate > dateLimit {
+			dateLimit = lastKeep.DateUpdate
+		}
+	}
+	if dateLimit == 0 {
+		return 0, nil
+	}
+
+	query := bson.M{"$and": []bson.M{
+		roots,
+		{"dateUpdate": bson.M{"$lt": dateLimit}},
+		{"labels.text": bson.M{"$not": bson.RegEx{Pattern: "^doing(:.*)?$"}}},
+	}}
+	if len(topic.Pinned) > 0 {
+		query["$and"] = append(query["$and"].([]bson.M), bson.M{"_id": bson.M{"$nin": topic.Pinned}})
+	}
+
+	nb := 0
+	ids := []string{}
+	var msg struct {
+		ID string `bson:"_id"`
+	}
+	iter := store.GetCMessages(topic.Collection).Find(query).Select(bson.M{"_id": 1}).Iter()
+	for iter.Next(&msg) {
+		ids = append(ids, msg.ID)
+		if len(ids) == 1000 {
+			n, err := deleteThreads(ids, topic)
+			nb += n
+			if err != nil {
+				iter.Close()
+				return nb, err
+			}
+			ids = ids[:0]
+		}
+	}
+	if err := iter.Close(); err != nil {
+		log.Errorf("PurgeExpired: Error while listing expired messages on topic %s, err:%s", topic.Topic, err)
+		return nb, err
+	}
+	if len(ids) > 0 {
+		n, err := deleteThreads(ids, topic)
+		nb += n
+		if err != nil {
+			return nb, err
+		}
+	}
+
+	//Clean the cache for this topic
+	cache.CleanMessagesLists(topic.Topic)
+	return nb, nil
+}
+
+// removePinned removes a message from pinned messages of its topic, if pinned
+func removePinned(topic *tat.Topic, idMessage string) {
+	if !tat.ArrayContains(topic.Pinned, idMessage) {
+		return
+	}
+	if err := topicDB.RemovePinned(topic, idMessage); err != nil {
+		log.Errorf("Error while removing pinned message %s on topic %s: %s", idMessage, topic.Topic, err)
+	}
+}
+
+// deleteThreads deletes root messages and their replies, as Delete with cascade
+func deleteThreads(ids []string, topic tat.Topic) (int, error) {
+	removeRevisions(bson.M{"$or": []bson.M{{"idMessage": bson.M{"$in": ids}}, {"inReplyOfIDRoot": bson.M{"$in": ids}}}})
+	store.RemoveAttachments(bson.M{"$or": []bson.M{{"metadata.idMessage": bson.M{"$in": ids}}, {"metadata.inReplyOfIDRoot": bson.M{"$in": ids}}}})
+	changeInfo, err := store.GetCMessages(topic.Collection).RemoveAll(bson.M{"$or": []bson.M{{"_id": bson.M{"$in": ids}}, {"inReplyOfIDRoot": bson.M{"$in": ids}}}})
+	if err != nil {
+		log.Errorf("Error while deleting expired messages on topic %s, err:%s", topic.Topic, err)
+		return 0, err
+	}
+	return changeInfo.Removed, nil
+}
+
 //AddLabel add a label to a message
 //truncated to 100 char in text label
-func AddLabel(message *tat.Message, topic tat.Topic, label string, color string) (tat.Label, error) {
+//If ifDateUpdate is not 0, label is added only if message was not updated since, ErrConflict otherwise
+func AddLabel(message *tat.Message, topic tat.Topic, label string, color string, ifDateUpdate float64) (tat.Label, error) {
 	if len(label) > lengthLabel {
 		label = label[0:lengthLabel]
 	}
@@ -1166,15 +1958,17 @@ func AddLabel(message *tat.Message, topic tat.Topic, label string, color string)
 		return newLabel, nil
 	}
 
+	dateUpdate := tat.TSFromNow()
 	err := store.GetCMessages(topic.Collection).Update(
-		bson.M{"_id": message.ID},
-		bson.M{"$set": bson.M{"dateUpdate": tat.TSFromNow()},
+		selectorIfDateUpdate(message, ifDateUpdate),
+		bson.M{"$set": bson.M{"dateUpdate": dateUpdate},
 			"$push": bson.M{"labels": newLabel}})
 
 	if err != nil {
-		return tat.Label{}, err
+		return tat.Label{}, conflictOr(err, ifDateUpdate)
 	}
 	message.Labels = append(message.Labels, newLabel)
+	message.DateUpdate = dateUpdate
 	//Clean the cache for this topic
 	cache.CleanMessagesLists(topic.Topic)
 
@@ -1183,23 +1977,26 @@ func AddLabel(message *tat.Message, topic tat.Topic, label string, color string)
 }
 
 // RemoveLabel removes label from on message (label text matching)
-func RemoveLabel(message *tat.Message, label string, topic tat.Topic) error {
+// If ifDateUpdate is not 0, label is removed only if message was not updated since, ErrConflict otherwise
+func RemoveLabel(message *tat.Message, label string, topic tat.Topic, ifDateUpdate float64) error {
 	idxLabel, l, err := message.GetLabel(label)
 	if err != nil {
 		log.Debugf("Remove Label is not possible, %s is not a label of this message", label)
 		return nil
 	}
 
+	dateUpdate := tat.TSFromNow()
 	err = store.GetCMessages(topic.Collection).Update(
-		bson.M{"_id": message.ID},
-		bson.M{"$set": bson.M{"dateUpdate": tat.TSFromNow()},
+		selectorIfDateUpdate(message, ifDateUpdate),
+		bson.M{"$set": bson.M{"dateUpdate": dateUpdate},
 			"$pull": bson.M{"labels": l}})
 
 	if err != nil {
-		return err
+		return conflictOr(err, ifDateUpdate)
 	}
 
 	message.Labels = append(message.Labels[:idxLabel], message.Labels[idxLabel+1:]...)
+	message.DateUpdate = dateUpdate
 
 	//Clean the cache for this topic
 	cache.CleanMessagesLists(topic.Topic)
@@ -1207,16 +2004,20 @@ func RemoveLabel(message *tat.Message, label string, topic tat.Topic) error {
 }
 
 // RemoveAllAndAddNewLabel removes all labels and add new label on message
-func RemoveAllAndAddNewLabel(message *tat.Message, labels []tat.Label, topic tat.Topic) error {
-	message.Labels = checkLabels(labels, nil, nil)
+// If ifDateUpdate is not 0, labels are replaced only if message was not updated since, ErrConflict otherwise
+func RemoveAllAndAddNewLabel(message *tat.Message, labels []tat.Label, topic tat.Topic, ifDateUpdate float64) error {
+	newLabels := checkLabels(labels, nil, nil)
+	dateUpdate := tat.TSFromNow()
 	err := store.GetCMessages(topic.Collection).Update(
-		bson.M{"_id": message.ID},
+		selectorIfDateUpdate(message, ifDateUpdate),
 		bson.M{"$set": bson.M{
-			"dateUpdate": tat.TSFromNow(),
-			"labels":     message.Labels}})
+			"dateUpdate": dateUpdate,
+			"labels":     newLabels}})
 	if err != nil {
-		return err
+		return conflictOr(err, ifDateUpdate)
 	}
+	message.Labels = newLabels
+	message.DateUpdate = dateUpdate
 
 	//Clean the cache for this topic
 	cache.CleanMessagesLists(topic.Topic)
@@ -1230,9 +2031,9 @@ func RemoveAllAndAddNewLabelOrCreate(message *tat.Message, labels []tat.Label, t
 }
 
 // RemoveSomeAndAddNewLabel removes some labels and add new label on message
-func RemoveSomeAndAddNewLabel(message *tat.Message, labelsToAdd []tat.Label, labelsToRemove []string, topic tat.Topic) error {
+func RemoveSomeAndAddNewLabel(message *tat.Message, labelsToAdd []tat.Label, labelsToRemove []string, topic tat.Topic, ifDateUpdate float64) error {
 	//message.Labels = append(message.Labels, labels...)
-	return RemoveAllAndAddNewLabel(message, checkLabels(message.Labels, labelsToAdd, labelsToRemove), topic)
+	return RemoveAllAndAddNewLabel(message, checkLabels(message.Labels, labelsToAdd, labelsToRemove), topic, ifDateUpdate)
 }
 
 // Like add a like to a message
@@ -1256,6 +2057,160 @@ func Like(message *tat.Message, user tat.User, topic tat.Topic) error {
 	return err
 }
 
+// checkReaction returns reaction trimmed, or an error if it can't be used
+// as a key of reactions
+func checkReaction(reaction string) (string, error) {
+	r := strings.TrimSpace(reaction)
+	if r == "" || len(r) > lengthReaction || strings.ContainsAny(r, ". \t\n,") || strings.HasPrefix(r, "$") {
+		return "", fmt.Errorf("Invalid reaction:%s", reaction)
+	}
+	return r, nil
+}
+
+// CheckReactionsCriteria returns an error if one of reactions, comma separated, can't be used as criteria
+func CheckReactionsCriteria(reactions string) error {
+	for _, val := range strings.Split(reactions, ",") {
+		if _, err := checkReaction(val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// React adds a reaction of user on one message
+func React(message *tat.Message, reaction string, user tat.User, topic tat.Topic) error {
+	r, err := checkReaction(reaction)
+	if err != nil {
+		return err
+	}
+	if tat.ArrayContains(message.Reactions[r], user.Username) {
+		return fmt.Errorf("React not possible, %s has already reacted %s on this message", user.Username, r)
+	}
+	err = store.GetCMessages(topic.Collection).Update(
+		bson.M{"_id": message.ID},
+		bson.M{"$set": bson.M{"dateUpdate": tat.TSFromNow()},
+			"$addToSet": bson.M{"reactions." + r: user.Username}})
+
+	if err == nil {
+		if message.Reactions == nil {
+			message.Reactions = make(map[string][]string)
+		}
+		message.Reactions[r] = append(message.Reactions[r], user.Username)
+		//Clean the cache for this topic
+		cache.CleanMessagesLists(topic.Topic)
+	}
+
+	return err
+}
+
+// Unreact removes a reaction of user from one message
+func Unreact(message *tat.Message, reaction string, user tat.User, topic tat.Topic) error {
+	r, err := checkReaction(reaction)
+	if err != nil {
+		return err
+	}
+	if !tat.ArrayContains(message.Reactions[r], user.Username) {
+		return fmt.Errorf("Unreact not possible, %s has not reacted %s on this message", user.Username, r)
+	}
+	err = store.GetCMessages(topic.Collection).Update(
+		bson.M{"_id": message.ID},
+		bson.M{"$set": bson.M{"dateUpdate": tat.TSFromNow()},
+			"$pull": bson.M{"reactions." + r: user.Username}})
+	if err != nil {
+		return err
+	}
+
+	// remove reaction without users
+	if err := store.GetCMessages(topic.Collection).Update(
+		bson.M{"_id": message.ID, "reactions." + r: bson.M{"$size": 0}},
+		bson.M{"$unset": bson.M{"reactions." + r: ""}}); err != nil && err != mgo.ErrNotFound {
+		log.Errorf("Error while removing empty reaction %s on message %s: %s", r, message.ID, err)
+	}
+
+	users := []string{}
+	for _, u := range message.Reactions[r] {
+		if u != user.Username {
+			users = append(users, u)
+		}
+	}
+	if len(users) > 0 {
+		message.Reactions[r] = users
+	} else {
+		delete(message.Reactions, r)
+	}
+	//Clean the cache for this topic
+	cache.CleanMessagesLists(topic.Topic)
+	return nil
+}
+
+// selectorIfDateUpdate selects message by its id and, if ifDateUpdate is not 0,
+// only if message was not updated since ifDateUpdate
+func selectorIfDateUpdate(message *tat.Message, ifDateUpdate float64) bson.M {
+	if ifDateUpdate == 0 {
+		return bson.M{"_id": message.ID}
+	}
+	return bson.M{"_id": message.ID, "dateUpdate": ifDateUpdate}
+}
+
+// conflictOr returns ErrConflict if an update with ifDateUpdate as precondition
+// did not find message, err otherwise
+func conflictOr(err error, ifDateUpdate float64) error {
+	if err == mgo.ErrNotFound && ifDateUpdate != 0 {
+		return ErrConflict
+	}
+	return err
+}
+
+// SetFields sets fields on one message, other fields are kept. A nil value removes field
+func SetFields(message *tat.Message, fields map[string]interface{}, topic tat.Topic) error {
+	merged := make(map[string]interface{}, len(message.Fields))
+	for name, value := range message.Fields {
+		merged[name] = value
+	}
+
+	toSet := make(map[string]interface{}, len(fields))
+	unset := bson.M{}
+	for name, value := range fields {
+		if value != nil {
+			toSet[name] = value
+			continue
+		}
+		if err := tat.CheckFieldName(name); err != nil {
+			return err
+		}
+		unset["fields."+name] = ""
+		delete(merged, name)
+	}
+
+	checked, err := tat.CheckMessageFields(toSet)
+	if err != nil {
+		return err
+	}
+	dateUpdate := tat.TSFromNow()
+	set := bson.M{"dateUpdate": dateUpdate}
+	for name, value := range checked {
+		set["fields."+name] = value
+		merged[name] = value
+	}
+	if len(merged) > tat.MaxMessageFields {
+		return fmt.Errorf("Too many fields on message, %d max", tat.MaxMessageFields)
+	}
+
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	if err := store.GetCMessages(topic.Collection).Update(bson.M{"_id": message.ID}, update); err != nil {
+		log.Errorf("Error while setting fields on message %s: %s", message.ID, err)
+		return err
+	}
+	message.Fields = merged
+	message.DateUpdate = dateUpdate
+	//Clean the cache for this topic
+	cache.CleanMessagesLists(topic.Topic)
+	return nil
+}
+
 // Unlike removes a like from one message
 func Unlike(message *tat.Message, user tat.User, topic tat.Topic) error {
 	if !tat.ArrayContains(message.Likers, user.Username) {
@@ -1371,20 +2326,20 @@ func addOrRemoveFromTasks(message *tat.Message, action string, user tat.User, to
 	text := "Take this thread into my tasks"
 	if action == "pull" {
 		text = "Remove this thread from my tasks"
-		RemoveLabel(message, "doing:"+user.Username, topic)
-		RemoveLabel(message, "done:"+user.Username, topic)
-		RemoveLabel(message, "done", topic)
-		RemoveLabel(message, "doing", topic)
+		RemoveLabel(message, "doing:"+user.Username, topic, 0)
+		RemoveLabel(message, "done:"+user.Username, topic, 0)
+		RemoveLabel(message, "done", topic, 0)
+		RemoveLabel(message, "doing", topic, 0)
 	} else { // push
 		if !message.ContainsLabel("doing") {
-			AddLabel(message, topic, "doing", "#5484ed")
+			AddLabel(message, topic, "doing", "#5484ed", 0)
 		}
 		if !message.ContainsLabel("doing:" + user.Username) {
-			AddLabel(message, topic, "doing:"+user.Username, "#5484ed")
+			AddLabel(message, topic, "doing:"+user.Username, "#5484ed", 0)
 		}
-		RemoveLabel(message, "open", topic)
-		RemoveLabel(message, "done", topic)
-		RemoveLabel(message, "done:"+user.Username, topic)
+		RemoveLabel(message, "open", topic, 0)
+		RemoveLabel(message, "done", topic, 0)
+		RemoveLabel(message, "done:"+user.Username, topic, 0)
 	}
 
 	return Insert(msgReply, user, topic, text, idRoot, -1, nil, nil, nil, nil)
@@ -1400,6 +2355,149 @@ func RemoveFromTasks(message *tat.Message, user tat.User, topic tat.Topic) error
 	return addOrRemoveFromTasks(message, "pull", user, topic)
 }
 
+// SetTask assigns message to assignees and groups of task. A nil task removes task from message
+func SetTask(message *tat.Message, task *tat.Task, topic tat.Topic) error {
+	dateUpdate := tat.TSFromNow()
+	update := bson.M{"$set": bson.M{"dateUpdate": dateUpdate, "task": task}}
+	if task == nil {
+		update = bson.M{"$set": bson.M{"dateUpdate": dateUpdate}, "$unset": bson.M{"task": ""}}
+	}
+	if err := store.GetCMessages(topic.Collection).Update(bson.M{"_id": message.ID}, update); err != nil {
+		log.Errorf("Error while setting task on message %s: %s", message.ID, err)
+		return err
+	}
+	message.Task = task
+	message.DateUpdate = dateUpdate
+	//Clean the cache for this topic
+	cache.CleanMessagesLists(topic.Topic)
+	return nil
+}
+
+// SetTaskState changes state of the task of a message
+func SetTaskState(message *tat.Message, state string, topic tat.Topic) error {
+	if message.Task == nil {
+		return fmt.Errorf("Message %s is not a task", message.ID)
+	}
+	if err := tat.CheckTaskState(state); err != nil {
+		return err
+	}
+	dateUpdate := tat.TSFromNow()
+	err := store.GetCMessages(topic.Collection).Update(
+		bson.M{"_id": message.ID},
+		bson.M{"$set": bson.M{"dateUpdate": dateUpdate, "task.state": state}})
+	if err != nil {
+		log.Errorf("Error while setting state of task %s: %s", message.ID, err)
+		return err
+	}
+	message.Task.State = state
+	message.DateUpdate = dateUpdate
+	//Clean the cache for this topic
+	cache.CleanMessagesLists(topic.Topic)
+	return nil
+}
+
+// ListTasks returns messages of topics assigned to username or to one of groups,
+// with a state in states, ordered by due date. Tasks without due date are last,
+// last created first
+func ListTasks(criteria *tat.MessageCriteria, username string, groups []string, topics []tat.Topic, states []string) ([]tat.Message, error) {
+	c, errc := buildMessageCriteria(criteria)
+	if errc != nil {
+		return nil, errc
+	}
+	if groups == nil {
+		groups = []string{}
+	}
+	assigned := bson.M{"$or": []bson.M{
+		{"task.assignees": username},
+		{"task.groups": bson.M{"$in": groups}},
+	}}
+	inStates := bson.M{"task.state": bson.M{"$in": states}}
+
+	messages := []tat.Message{}
+	for collection, query := range queriesByCollection(topics, c, assigned, inStates) {
+		// skip is applied after merging messages of all collections
+		var withDue, withoutDue []tat.Message
+		err := store.GetCMessages(collection).Find(bson.M{"$and": []bson.M{query, {"task.dateDue": bson.M{"$gt": 0}}}}).
+			Sort("task.dateDue").
+			Limit(criteria.Skip + criteria.Limit).
+			All(&withDue)
+		if err != nil {
+			log.Errorf("Error while listing tasks of %s: %s", username, err)
+			return nil, err
+		}
+		err = store.GetCMessages(collection).Find(bson.M{"$and": []bson.M{query, {"task.dateDue": bson.M{"$not": bson.M{"$gt": 0}}}}}).
+			Sort("-dateCreation").
+			Limit(criteria.Skip + criteria.Limit).
+			All(&withoutDue)
+		if err != nil {
+			log.Errorf("Error while listing tasks of %s: %s", username, err)
+			return nil, err
+		}
+		messages = append(append(messages, withDue...), withoutDue...)
+	}
+
+	sort.Sort(byDateDue(messages))
+	if criteria.Skip >= len(messages) {
+		return []tat.Message{}, nil
+	}
+	messages = messages[criteria.Skip:]
+	if len(messages) > criteria.Limit {
+		messages = messages[:criteria.Limit]
+	}
+	return messages, nil
+}
+
+// byDateDue sorts tasks by due date, tasks without due date last, last created first
+type byDateDue []tat.Message
+
+func (a byDateDue) Len() int      { return len(a) }
+func (a byDateDue) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a byDateDue) Less(i, j int) bool {
+	di, dj := a[i].Task.DateDue, a[j].Task.DateDue
+	if di > 0 && dj > 0 && di != dj {
+		return di < dj
+	}
+	if (di > 0) != (dj > 0) {
+		return di > 0
+	}
+	return a[i].DateCreation > a[j].DateCreation
+}
+
+// PopOverdueTasks returns tasks of messages collections with a due date exceeded and not yet
+// notified, and marks them as notified. A task is returned only once, even with several instances of engine
+func PopOverdueTasks(collections []string) ([]tat.Message, error) {
+	now := tat.TSFromNow()
+	overdue := bson.M{
+		"task.dateDue":         bson.M{"$gt": 0, "$lt": now},
+		"task.state":           bson.M{"$ne": tat.TaskStateDone},
+		"task.overdueNotified": bson.M{"$ne": true},
+	}
+
+	messages := []tat.Message{}
+	for _, collection := range collections {
+		var msgs []tat.Message
+		if err := store.GetCMessages(collection).Find(overdue).All(&msgs); err != nil {
+			log.Errorf("Error while listing overdue tasks: %s", err)
+			return messages, err
+		}
+		for _, m := range msgs {
+			err := store.GetCMessages(collection).Update(
+				bson.M{"_id": m.ID, "task.overdueNotified": bson.M{"$ne": true}},
+				bson.M{"$set": bson.M{"task.overdueNotified": true}})
+			if err == mgo.ErrNotFound {
+				// notified by another instance
+				continue
+			} else if err != nil {
+				log.Errorf("Error while marking task %s as notified: %s", m.ID, err)
+				continue
+			}
+			m.Task.OverdueNotified = true
+			messages = append(messages, m)
+		}
+	}
+	return messages, nil
+}
+
 // CountMsgSinceDate return number of messages created on one topic from a given date
 func CountMsgSinceDate(topic tat.Topic, date int64) (int, error) {
 	nb, err := store.GetCMessages(topic.Collection).Find(bson.M{"topic": topic.Topic, "dateCreation": bson.M{"$gte": date}}).Count()
@@ -1417,6 +2515,12 @@ func ChangeUsernameOnMessages(oldUsername, newUsername string) error {
 	if err := ChangeUsernameOnMessagesTopics(oldUsername, newUsername); err != nil {
 		return err
 	}
+	if _, err := store.Tat().CScheduled.UpdateAll(
+		bson.M{"author.username": oldUsername},
+		bson.M{"$set": bson.M{"author.username": newUsername}}); err != nil {
+		log.Errorf("Error while update username on scheduled messages: %s", err)
+		return err
+	}
 	return nil
 }
 
@@ -1452,6 +2556,31 @@ func changeAuthorUsernameOnMessages(oldUsername, newUsername string) error {
 	return nil
 }
 
+// ChangeTopicOnMessages sets new name of a renamed topic on its messages,
+// trashed and scheduled messages
+func ChangeTopicOnMessages(topic tat.Topic, oldName string) error {
+	if _, err := store.GetCMessages(topic.Collection).UpdateAll(
+		bson.M{"topic": oldName},
+		bson.M{"$set": bson.M{"topic": topic.Topic}}); err != nil {
+		log.Errorf("Error while update topic from %s to %s on messages err:%s", oldName, topic.Topic, err)
+		return err
+	}
+	if _, err := store.Tat().CTrash.UpdateAll(
+		bson.M{"topic": oldName},
+		bson.M{"$set": bson.M{"topic": topic.Topic, "message.topic": topic.Topic}}); err != nil {
+		log.Errorf("Error while update topic from %s to %s on trash err:%s", oldName, topic.Topic, err)
+		return err
+	}
+	if _, err := store.Tat().CScheduled.UpdateAll(
+		bson.M{"topic": oldName},
+		bson.M{"$set": bson.M{"topic": topic.Topic}}); err != nil {
+		log.Errorf("Error while update topic from %s to %s on scheduled messages err:%s", oldName, topic.Topic, err)
+		return err
+	}
+	cache.CleanMessagesLists(oldName)
+	return nil
+}
+
 // ChangeUsernameOnMessagesTopics change username on topics
 func ChangeUsernameOnMessagesTopics(oldUsername, newUsername string) error {
 	var topics []tat.Topic
@@ -1522,6 +2651,7 @@ func CountMessages(criteria *tat.MessageCriteria, topic tat.Topic) (int, error)
 	if errc != nil {
 		return -1, errc
 	}
+	c = addPinnedCriteria(c, criteria, topic)
 	count, err := store.GetCMessages(topic.Collection).Find(c).Count()
 	if err != nil {
 		log.Errorf("Error while Count Messages %s", err)
@@ -1529,6 +2659,294 @@ func CountMessages(criteria *tat.MessageCriteria, topic tat.Topic) (int, error)
 	return count, err
 }
 
+// Aggregate counts messages matching criteria, grouped by dimensions
+// (label, tag, author, topic) and by time bucket on dateCreation.
+// Messages without label (or tag) are not counted if grouped by label (or tag),
+// messages with many labels are counted once per label.
+func Aggregate(criteria *tat.MessageCriteria, aggregate *tat.MessageAggregateCriteria, topic tat.Topic) (*tat.MessageAggregateJSON, error) {
+	c, errc := buildMessageCriteria(criteria)
+	if errc != nil {
+		return nil, errc
+	}
+	c = addPinnedCriteria(c, criteria, topic)
+
+	pipeline := []bson.M{{"$match": c}}
+	id := bson.M{}
+	for _, g := range aggregate.GroupBy {
+		switch g {
+		case tat.AggregateByLabel:
+			pipeline = append(pipeline, bson.M{"$unwind": "$labels"})
+			if aggregate.LabelPrefix != "" {
+				pipeline = append(pipeline, bson.M{"$match": bson.M{"labels.text": bson.RegEx{Pattern: "^" + regexp.QuoteMeta(aggregate.LabelPrefix)}}})
+			}
+			id["label"] = "$labels.text"
+		case tat.AggregateByTag:
+			pipeline = append(pipeline, bson.M{"$unwind": "$tags"})
+			id["tag"] = "$tags"
+		case tat.AggregateByAuthor:
+			id["author"] = "$author.username"
+		case tat.AggregateByTopic:
+			id["topic"] = "$topic"
+		}
+	}
+
+	if aggregate.Bucket != "" {
+		size, offset, err := tat.AggregateBucket(aggregate.Bucket)
+		if err != nil {
+			return nil, err
+		}
+		// beginning of bucket: dateCreation - ((dateCreation - offset) % size)
+		id["date"] = bson.M{"$subtract": []interface{}{
+			"$dateCreation",
+			bson.M{"$mod": []interface{}{bson.M{"$subtract": []interface{}{"$dateCreation", offset}}, size}},
+		}}
+	}
+
+	pipeline = append(pipeline,
+		bson.M{"$group": bson.M{"_id": id, "count": bson.M{"$sum": 1}}},
+		bson.M{"$sort": bson.D{{Name: "_id.date", Value: 1}, {Name: "count", Value: -1}}},
+	)
+
+	var results []struct {
+		ID struct {
+			Date   float64 `bson:"date"`
+			Label  string  `bson:"label"`
+			Tag    string  `bson:"tag"`
+			Author string  `bson:"author"`
+			Topic  string  `bson:"topic"`
+		} `bson:"_id"`
+		Count int `bson:"count"`
+	}
+	if err := store.GetCMessages(topic.Collection).Pipe(pipeline).AllowDiskUse().All(&results); err != nil {
+		log.Errorf("Error while aggregate messages with criterias:%s on topic:%s, err:%s", criteria.GetURL(), topic.Topic, err)
+		return nil, err
+	}
+
+	total, err := store.GetCMessages(topic.Collection).Find(c).Count()
+	if err != nil {
+		log.Errorf("Error while Count Messages %s", err)
+		return nil, err
+	}
+
+	out := &tat.MessageAggregateJSON{
+		GroupBy: aggregate.GroupBy,
+		Bucket:  aggregate.Bucket,
+		Total:   total,
+		Results: make([]tat.MessageAggregateResult, 0, len(results)),
+	}
+	for _, r := range results {
+		out.Results = append(out.Results, tat.MessageAggregateResult{
+			Date:   int64(r.ID.Date),
+			Label:  r.ID.Label,
+			Tag:    r.ID.Tag,
+			Author: r.ID.Author,
+			Topic:  r.ID.Topic,
+			Count:  r.Count,
+		})
+	}
+	return out, nil
+}
+
+// Export calls fn on each root message of topic, from oldest to newest, with
+// all its replies in Replies, sorted by dateCreation. Attachments are not exported
+func Export(topic tat.Topic, fn func(tat.Message) error) error {
+	iter := store.GetCMessages(topic.Collection).
+		Find(bson.M{"topic": topic.Topic, "inReplyOfIDRoot": ""}).
+		Sort("dateCreation", "_id").
+		Iter()
+
+	var root tat.Message
+	for iter.Next(&root) {
+		var replies []tat.Message
+		err := store.GetCMessages(topic.Collection).
+			Find(bson.M{"topic": topic.Topic, "inReplyOfIDRoot": root.ID}).
+			Sort("dateCreation", "_id").
+			All(&replies)
+		if err != nil {
+			iter.Close()
+			return err
+		}
+		for i := range replies {
+			replies[i].Attachments = nil
+		}
+		root.Attachments = nil
+		root.Replies = replies
+		if err := fn(root); err != nil {
+			iter.Close()
+			return err
+		}
+		root = tat.Message{}
+	}
+	return iter.Close()
+}
+
+// Import inserts a root message returned by Export, then its replies.
+// Authors, labels, fields, tasks, likes, votes and reactions are kept. IDs and dates are kept
+// if topic.CanForceDate, new ones are generated otherwise.
+// Returns the number of inserted messages, replies included
+func Import(in tat.Message, topic tat.Topic) (int, error) {
+	root := &tat.Message{}
+	if err := importOne(root, in, nil, topic); err != nil {
+		if mgo.IsDup(err) {
+			return 0, fmt.Errorf("Message %s already exists", in.ID)
+		}
+		return 0, err
+	}
+
+	// key: ID in export, replies are sorted by dateCreation: parent is imported before
+	imported := map[string]*tat.Message{in.ID: root}
+	toRestore := []tat.Message{in}
+	for _, r := range in.Replies {
+		parent, ok := imported[r.InReplyOfID]
+		if !ok {
+			parent = root
+		}
+		reply := &tat.Message{}
+		if err := importOne(reply, r, parent, topic); err != nil {
+			return len(imported), fmt.Errorf("Error while importing reply %s: %s", r.ID, err)
+		}
+		imported[r.ID] = reply
+		toRestore = append(toRestore, r)
+	}
+
+	// after all inserts, as a reply updates dateUpdate of its parent
+	for _, in := range toRestore {
+		set := bson.M{
+			"likers":      in.Likers,
+			"nbLikes":     in.NbLikes,
+			"votersUP":    in.VotersUP,
+			"votersDown":  in.VotersDown,
+			"nbVotesUP":   in.NbVotesUP,
+			"nbVotesDown": in.NbVotesDown,
+			"reactions":   in.Reactions,
+		}
+		if in.Task != nil {
+			set["task"] = in.Task
+		}
+		if topic.CanForceDate {
+			set["dateUpdate"] = in.DateUpdate
+		}
+		if err := store.GetCMessages(topic.Collection).Update(bson.M{"_id": imported[in.ID].ID}, bson.M{"$set": set}); err != nil {
+			log.Errorf("Error while restoring likes and votes on imported message %s: %s", imported[in.ID].ID, err)
+			return len(imported), err
+		}
+	}
+	return len(imported), nil
+}
+
+// importOne inserts message, on behalf of its author, as a reply of parent if not nil
+func importOne(message *tat.Message, in tat.Message, parent *tat.Message, topic tat.Topic) error {
+	// author is flagged as system user only to keep date, Insert checks topic.CanForceDate
+	author := tat.User{Username: in.Author.Username, Fullname: in.Author.Fullname, IsSystem: topic.CanForceDate}
+	dateCreation := float64(-1)
+	if topic.CanForceDate {
+		message.ID = in.ID
+		dateCreation = in.DateCreation
+	}
+	inReplyOfID := ""
+	if parent != nil {
+		inReplyOfID = parent.ID
+	}
+	message.Fields = in.Fields
+	return Insert(message, author, topic, in.Text, inReplyOfID, dateCreation, in.Labels, nil, nil, parent)
+}
+
+// mentionsQueries returns, per collection, the query selecting messages of topics
+// mentioning username
+func mentionsQueries(username string, topics []tat.Topic, query bson.M) map[string]bson.M {
+	return queriesByCollection(topics, query, bson.M{"userMentions": username})
+}
+
+// queriesByCollection returns, per collection, the query selecting messages of
+// topics matching all conditions
+func queriesByCollection(topics []tat.Topic, conditions ...bson.M) map[string]bson.M {
+	byCollection := make(map[string][]string)
+	for _, t := range topics {
+		byCollection[t.Collection] = append(byCollection[t.Collection], t.Topic)
+	}
+	queries := make(map[string]bson.M, len(byCollection))
+	for collection, names := range byCollection {
+		and := append([]bson.M{}, conditions...)
+		queries[collection] = bson.M{"$and": append(and, bson.M{"topic": bson.M{"$in": names}})}
+	}
+	return queries
+}
+
+// ListMentions returns messages of topics mentioning username, last created first.
+// Only unread mentions if onlyUnread. Returns also the number of unread mentions
+func ListMentions(criteria *tat.MessageCriteria, username string, topics []tat.Topic, onlyUnread bool) ([]tat.Message, int, error) {
+	c, errc := buildMessageCriteria(criteria)
+	if errc != nil {
+		return nil, 0, errc
+	}
+
+	messages := []tat.Message{}
+	nbUnread := 0
+	for collection, query := range mentionsQueries(username, topics, c) {
+		unread := bson.M{"$and": []bson.M{query, {"mentionsRead": bson.M{"$ne": username}}}}
+		n, err := store.GetCMessages(collection).Find(unread).Count()
+		if err != nil {
+			log.Errorf("Error while counting unread mentions of %s: %s", username, err)
+			return nil, 0, err
+		}
+		nbUnread += n
+
+		if onlyUnread {
+			query = unread
+		}
+		var msgs []tat.Message
+		// skip is applied after merging messages of all collections
+		err = store.GetCMessages(collection).Find(query).
+			Sort("-dateCreation").
+			Limit(criteria.Skip + criteria.Limit).
+			All(&msgs)
+		if err != nil {
+			log.Errorf("Error while listing mentions of %s: %s", username, err)
+			return nil, 0, err
+		}
+		messages = append(messages, msgs...)
+	}
+
+	sort.Sort(byDateCreationDesc(messages))
+	if criteria.Skip >= len(messages) {
+		return []tat.Message{}, nbUnread, nil
+	}
+	messages = messages[criteria.Skip:]
+	if len(messages) > criteria.Limit {
+		messages = messages[:criteria.Limit]
+	}
+	return messages, nbUnread, nil
+}
+
+type byDateCreationDesc []tat.Message
+
+func (a byDateCreationDesc) Len() int           { return len(a) }
+func (a byDateCreationDesc) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byDateCreationDesc) Less(i, j int) bool { return a[i].DateCreation > a[j].DateCreation }
+
+// MarkMentionsRead marks messages of topics mentioning username as read,
+// all of them if idMessages is empty. Returns the number of messages updated
+func MarkMentionsRead(username string, topics []tat.Topic, idMessages []string) (int, error) {
+	query := bson.M{"mentionsRead": bson.M{"$ne": username}}
+	if len(idMessages) > 0 {
+		query["_id"] = bson.M{"$in": idMessages}
+	}
+
+	nb := 0
+	for collection, q := range mentionsQueries(username, topics, query) {
+		changeInfo, err := store.GetCMessages(collection).UpdateAll(q, bson.M{"$addToSet": bson.M{"mentionsRead": username}})
+		if err != nil {
+			log.Errorf("Error while marking mentions of %s as read: %s", username, err)
+			return nb, err
+		}
+		nb += changeInfo.Updated
+	}
+	for _, t := range topics {
+		cache.CleanMessagesLists(t.Topic)
+	}
+	return nb, nil
+}
+
 // ComputeReplies re-compute replies for all messages in one topic
 func ComputeReplies(topic tat.Topic) (int, error) {
 
@@ -1,22 +1,35 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"math"
+	"mime/multipart"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/ovh/tat"
+	groupDB "github.com/ovh/tat/api/group"
 	"github.com/ovh/tat/api/hook"
 	messageDB "github.com/ovh/tat/api/message"
 	presenceDB "github.com/ovh/tat/api/presence"
+	"github.com/ovh/tat/api/ratelimit"
 	topicDB "github.com/ovh/tat/api/topic"
 	userDB "github.com/ovh/tat/api/user"
 	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 )
 
+const lengthIdempotencyKey = 255
+
+// aggregatePrefix is the prefix of topic param on GET /messages/aggregate/*topic
+const aggregatePrefix = "/aggregate/"
+
 // MessagesController contains all methods about messages manipulation
 type MessagesController struct{}
 
@@ -76,11 +89,22 @@ func (*MessagesController) buildCriteria(ctx *gin.Context) *tat.MessageCriteria
 	c.OnlyMsgReply = ctx.Query("onlyMsgReply")
 	c.OnlyCount = ctx.Query("onlyCount")
 	c.SortBy = ctx.Query("sortBy")
+	c.TextSearch = ctx.Query("textSearch")
+	c.Cursor = ctx.Query("cursor")
+	c.Reaction = ctx.Query("reaction")
+	c.Pinned = ctx.Query("pinned")
+	c.Field = tat.GetFieldConditionsFromURLValues(ctx.Request.URL.Query())
 	return &c
 }
 
 // List messages on one topic, with given criteria
 func (m *MessagesController) List(ctx *gin.Context) {
+	// GET /messages/aggregate/*topic can't be routed next to GET /messages/*topic
+	if strings.HasPrefix(ctx.Param("topic"), aggregatePrefix) {
+		m.Aggregate(ctx)
+		return
+	}
+
 	out, user, topic, criteria, httpCode, err := m.innerList(ctx)
 
 	if err != nil {
@@ -114,8 +138,63 @@ func (m *MessagesController) List(ctx *gin.Context) {
 		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 	}
 	out.Messages = messages
+	if len(messages) > 0 && len(messages) >= criteria.Limit {
+		out.NextCursor = messages[len(messages)-1].GetCursor(criteria.SortBy)
+	}
+	if criteria.TextSearch != "" {
+		out.Highlights = make(map[string][]string)
+		highlightMessages(out.Highlights, messages, criteria.TextSearch)
+	}
+	ctx.JSON(http.StatusOK, out)
+
+}
+
+// Aggregate counts messages matching criteria, grouped by dimensions
+// (query param groupBy) and time bucket (query param bucket)
+func (m *MessagesController) Aggregate(ctx *gin.Context) {
+	criteria := m.buildCriteria(ctx)
+	criteria.Topic = strings.TrimPrefix(ctx.Param("topic"), aggregatePrefix)
+	if criteria.Topic == "" || criteria.Topic[0] != '/' {
+		criteria.Topic = "/" + criteria.Topic
+	}
+
+	aggregate, err := tat.GetMessageAggregateCriteriaFromURLValues(ctx.Request.URL.Query())
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user tat.User
+	if getCtxUsername(ctx) != "" {
+		if user, err = PreCheckUser(ctx); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	topic, err := m.findTopicToRead(ctx, criteria.Topic, &user)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	criteria.Topic = topic.Topic
+
+	out, err := messageDB.Aggregate(criteria, aggregate, *topic)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 	ctx.JSON(http.StatusOK, out)
+}
 
+// highlightMessages adds snippets of messages and replies matching textSearch
+func highlightMessages(highlights map[string][]string, messages []tat.Message, textSearch string) {
+	for _, msg := range messages {
+		if snippets := tat.HighlightText(msg.Text, textSearch); len(snippets) > 0 {
+			highlights[msg.ID] = snippets
+		}
+		highlightMessages(highlights, msg.Replies, textSearch)
+	}
 }
 
 func (m *MessagesController) innerList(ctx *gin.Context) (*tat.MessagesJSON, tat.User, tat.Topic, *tat.MessageCriteria, int, error) {
@@ -135,6 +214,12 @@ func (m *MessagesController) innerList(ctx *gin.Context) (*tat.MessagesJSON, tat
 		return out, tat.User{}, tat.Topic{}, criteria, http.StatusBadRequest, fmt.Errorf("You can't use fulltree or onetree with NotLabel or NotTag")
 	}
 
+	if criteria.Reaction != "" {
+		if err := messageDB.CheckReactionsCriteria(criteria.Reaction); err != nil {
+			return out, tat.User{}, tat.Topic{}, criteria, http.StatusBadRequest, err
+		}
+	}
+
 	topicIn, err := GetParam(ctx, "topic")
 	if err != nil {
 		return out, tat.User{}, tat.Topic{}, criteria, http.StatusBadRequest, fmt.Errorf("Invalid topic")
@@ -157,24 +242,96 @@ func (m *MessagesController) innerList(ctx *gin.Context) (*tat.MessagesJSON, tat
 		}
 	}
 
-	topic, errt := topicDB.FindByTopic(criteria.Topic, true, false, false, &user)
+	topic, err := m.findTopicToRead(ctx, criteria.Topic, &user)
+	if err != nil {
+		return out, tat.User{}, tat.Topic{}, criteria, http.StatusBadRequest, err
+	}
+	criteria.Topic = topic.Topic
+
+	out.IsTopicRw, out.IsTopicAdmin = topicDB.GetUserRights(topic, &user)
+
+	return out, user, *topic, criteria, -1, nil
+}
+
+// findTopicToRead returns topic if user has read access on it
+func (m *MessagesController) findTopicToRead(ctx *gin.Context, topicName string, user *tat.User) (*tat.Topic, error) {
+	topic, errt := topicDB.FindByTopic(topicName, true, false, false, user)
 	if errt != nil {
-		var topicCriteria string
-		_, topicCriteria, err = checkDMTopic(ctx, criteria.Topic)
+		_, topicCriteria, err := checkDMTopic(ctx, topicName)
 		if err != nil {
-			return out, tat.User{}, tat.Topic{}, criteria, http.StatusBadRequest, fmt.Errorf("topic " + criteria.Topic + " does not exist or you have no read access on it")
+			return nil, fmt.Errorf("topic " + topicName + " does not exist or you have no read access on it")
 		}
 		// hack to get new created DM Topic
-		topic, errt = topicDB.FindByTopic(topicCriteria, true, false, false, &user)
+		topic, errt = topicDB.FindByTopic(topicCriteria, true, false, false, user)
 		if errt != nil {
-			return out, tat.User{}, tat.Topic{}, criteria, http.StatusBadRequest, fmt.Errorf("topic " + criteria.Topic + " does not exist or you have no read access on it (2)")
+			return nil, fmt.Errorf("topic " + topicName + " does not exist or you have no read access on it (2)")
 		}
-		criteria.Topic = topicCriteria
 	}
+	return topic, nil
+}
 
-	out.IsTopicRw, out.IsTopicAdmin = topicDB.GetUserRights(topic, &user)
+// Stream sends events (create, update, label, vote, delete...) on messages
+// of one or many topics as Server-Sent Events. Topics are the topic in url
+// and topics in query param "topics", comma separated. Criteria are the same
+// as List. Stream is closed after stream_max_duration, client have to reconnect.
+func (m *MessagesController) Stream(ctx *gin.Context) {
+	topicIn, err := GetParam(ctx, "topic")
+	if err != nil {
+		return
+	}
 
-	return out, user, *topic, criteria, -1, nil
+	user, err := PreCheckUser(ctx)
+	if err != nil {
+		return
+	}
+
+	criteria := m.buildCriteria(ctx)
+	names := []string{topicIn}
+	if ctx.Query("topics") != "" {
+		names = append(names, strings.Split(ctx.Query("topics"), ",")...)
+	}
+
+	topics := []string{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || name == "/" {
+			continue
+		}
+		if string(name[0]) != "/" {
+			name = "/" + name
+		}
+		topic, errf := m.findTopicToRead(ctx, name, &user)
+		if errf != nil {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": errf.Error()})
+			return
+		}
+		topics = append(topics, topic.Topic)
+	}
+
+	if len(topics) == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid topic"})
+		return
+	}
+
+	s := hook.Subscribe(user.Username, topics, criteria)
+	defer hook.Unsubscribe(s)
+
+	heartbeat := time.NewTicker(time.Duration(viper.GetInt("stream_heartbeat")) * time.Second)
+	defer heartbeat.Stop()
+	timeout := time.After(time.Duration(viper.GetInt("stream_max_duration")) * time.Second)
+
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case event := <-s.Events:
+			ctx.SSEvent(event.Action, event)
+			return true
+		case <-heartbeat.C:
+			ctx.SSEvent("heartbeat", time.Now().Unix())
+			return true
+		case <-timeout:
+			return false
+		}
+	})
 }
 
 func (m *MessagesController) preCheckTopic(ctx *gin.Context, messageIn *tat.MessageJSON) (tat.Message, tat.Topic, *tat.User, error) {
@@ -267,7 +424,7 @@ func (m *MessagesController) preCheckTopic(ctx *gin.Context, messageIn *tat.Mess
 		}
 
 		topicName := ""
-		if messageIn.Action == tat.MessageActionUpdate {
+		if messageIn.Action == tat.MessageActionUpdate || messageIn.Action == tat.MessageActionRestore {
 			topicName = messageIn.Topic
 		} else if messageIn.Action == "" || messageIn.Action == tat.MessageActionReply ||
 			messageIn.Action == tat.MessageActionLike || messageIn.Action == tat.MessageActionUnlike ||
@@ -275,11 +432,16 @@ func (m *MessagesController) preCheckTopic(ctx *gin.Context, messageIn *tat.Mess
 			messageIn.Action == tat.MessageActionVoteup || messageIn.Action == tat.MessageActionVotedown ||
 			messageIn.Action == tat.MessageActionUnvoteup || messageIn.Action == tat.MessageActionUnvotedown ||
 			messageIn.Action == tat.MessageActionRelabel || messageIn.Action == tat.MessageActionRelabelOrCreate ||
-			messageIn.Action == tat.MessageActionConcat {
+			messageIn.Action == tat.MessageActionConcat ||
+			messageIn.Action == tat.MessageActionReact || messageIn.Action == tat.MessageActionUnreact ||
+			messageIn.Action == tat.MessageActionPin || messageIn.Action == tat.MessageActionUnpin ||
+			messageIn.Action == tat.MessageActionFields {
 			topicName = m.inverseIfDMTopic(ctx, message.Topic)
 		} else if messageIn.Action == tat.MessageActionMove {
 			topicName = topicIn
-		} else if messageIn.Action == tat.MessageActionTask || messageIn.Action == tat.MessageActionUntask {
+		} else if messageIn.Action == tat.MessageActionTask || messageIn.Action == tat.MessageActionUntask ||
+			messageIn.Action == tat.MessageActionAssign || messageIn.Action == tat.MessageActionUnassign ||
+			messageIn.Action == tat.MessageActionTaskState {
 			topicName = m.inverseIfDMTopic(ctx, message.Topic)
 		} else {
 			e := errors.New("Invalid Call. IDReference not empty with unknown action")
@@ -305,12 +467,23 @@ func (m *MessagesController) preCheckTopic(ctx *gin.Context, messageIn *tat.Mess
 }
 
 // CreateBulk creates messages on one topic
+// Each message can have its idempotencyKey. With an Idempotency-Key header,
+// messages without key get header value suffixed by their index
 func (m *MessagesController) CreateBulk(ctx *gin.Context) {
 	messagesIn := &tat.MessagesJSONIn{}
 	ctx.Bind(messagesIn)
+	key := ctx.Request.Header.Get(tat.IdempotencyKeyHeader)
 	var msgs []*tat.MessageJSONOut
-	for _, messageIn := range messagesIn.Messages {
-		m, code, err := m.createSingle(ctx, messageIn)
+	for i, messageIn := range messagesIn.Messages {
+		if messageIn.IdempotencyKey == "" && key != "" {
+			messageIn.IdempotencyKey = key + "-" + strconv.Itoa(i)
+		}
+		// all messages are counted in rate limits with the first one
+		count := 0
+		if i == 0 {
+			count = len(messagesIn.Messages)
+		}
+		m, code, err := m.createSingle(ctx, messageIn, nil, count)
 		if err != nil {
 			ctx.JSON(code, gin.H{"error": err.Error()})
 			return
@@ -321,10 +494,24 @@ func (m *MessagesController) CreateBulk(ctx *gin.Context) {
 }
 
 // Create a new message on one topic
+// Message can be sent as multipart/form-data, with message as JSON in "message"
+// field and files in "attachments" fields
 func (m *MessagesController) Create(ctx *gin.Context) {
 	messageIn := &tat.MessageJSON{}
-	ctx.Bind(messageIn)
-	out, code, err := m.createSingle(ctx, messageIn)
+	var files []*multipart.FileHeader
+	if ctx.ContentType() == gin.MIMEMultipartPOSTForm {
+		var err error
+		if files, err = m.bindMultipart(ctx, messageIn); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	} else {
+		ctx.Bind(messageIn)
+	}
+	if messageIn.IdempotencyKey == "" {
+		messageIn.IdempotencyKey = ctx.Request.Header.Get(tat.IdempotencyKeyHeader)
+	}
+	out, code, err := m.createSingle(ctx, messageIn, files, 1)
 	if err != nil {
 		ctx.JSON(code, gin.H{"error": err})
 		return
@@ -332,7 +519,36 @@ func (m *MessagesController) Create(ctx *gin.Context) {
 	ctx.JSON(code, out)
 }
 
-func (m *MessagesController) createSingle(ctx *gin.Context, messageIn *tat.MessageJSON) (*tat.MessageJSONOut, int, error) {
+// checkRateLimit checks rate limits of user before creating count messages on
+// topic, once rights of user are checked. Sets Retry-After header if a limit is exceeded
+func checkRateLimit(ctx *gin.Context, topic string, count int) (int, error) {
+	err := ratelimit.Allow(getCtxUsername(ctx), map[string]int{topic: count})
+	if e, ok := err.(*ratelimit.ExceededError); ok {
+		ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(e.RetryAfter.Seconds()))))
+		return http.StatusTooManyRequests, err
+	} else if e, ok := err.(*tat.APIError); ok {
+		return e.Code, err
+	}
+	return http.StatusOK, nil
+}
+
+// bindMultipart reads message and attachments of a multipart/form-data request
+func (m *MessagesController) bindMultipart(ctx *gin.Context, messageIn *tat.MessageJSON) ([]*multipart.FileHeader, error) {
+	if err := ctx.Request.ParseMultipartForm(32 << 20); err != nil {
+		return nil, fmt.Errorf("Invalid multipart request: %s", err)
+	}
+	if v := ctx.Request.FormValue("message"); v != "" {
+		if err := json.Unmarshal([]byte(v), messageIn); err != nil {
+			return nil, fmt.Errorf("Invalid message field: %s", err)
+		}
+	} else {
+		messageIn.Text = ctx.Request.FormValue("text")
+	}
+	return ctx.Request.MultipartForm.File["attachments"], nil
+}
+
+// createSingle creates a message, counting rateLimitCount messages in rate limits
+func (m *MessagesController) createSingle(ctx *gin.Context, messageIn *tat.MessageJSON, files []*multipart.FileHeader, rateLimitCount int) (*tat.MessageJSONOut, int, error) {
 
 	msg, topic, user, e := m.preCheckTopic(ctx, messageIn)
 	if e != nil {
@@ -343,7 +559,46 @@ func (m *MessagesController) createSingle(ctx *gin.Context, messageIn *tat.Messa
 		return nil, http.StatusForbidden, fmt.Errorf("No RW Access to topic %s", messageIn.Topic)
 	}
 
-	var message = tat.Message{}
+	if err := checkNotArchived(topic); err != nil {
+		return nil, http.StatusForbidden, err
+	}
+
+	if rateLimitCount > 0 {
+		if code, err := checkRateLimit(ctx, topic.Topic, rateLimitCount); err != nil {
+			return nil, code, err
+		}
+	}
+
+	if messageIn.IdempotencyKey == "" {
+		return m.insertSingle(messageIn, msg, topic, user, files)
+	}
+
+	if len(messageIn.IdempotencyKey) > lengthIdempotencyKey {
+		return nil, http.StatusBadRequest, fmt.Errorf("Invalid Idempotency-Key, max length is %d", lengthIdempotencyKey)
+	}
+	out, err := messageDB.ReserveIdempotencyKey(messageIn.IdempotencyKey, user.Username, topic.Topic)
+	if err == messageDB.ErrIdempotencyKeyInProgress {
+		return nil, http.StatusConflict, err
+	} else if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	if out != nil {
+		// replay, returns result of first request
+		return out, http.StatusCreated, nil
+	}
+
+	out, code, err := m.insertSingle(messageIn, msg, topic, user, files)
+	if err != nil {
+		messageDB.ReleaseIdempotencyKey(messageIn.IdempotencyKey, user.Username, topic.Topic)
+		return nil, code, err
+	}
+	messageDB.SaveIdempotencyKey(messageIn.IdempotencyKey, user.Username, topic.Topic, out)
+	return out, code, nil
+}
+
+// insertSingle inserts or schedules a new message, rights are checked by caller
+func (m *MessagesController) insertSingle(messageIn *tat.MessageJSON, msg tat.Message, topic tat.Topic, user *tat.User, files []*multipart.FileHeader) (*tat.MessageJSONOut, int, error) {
+	var message = tat.Message{Fields: messageIn.Fields}
 
 	idRef := ""
 	if msg.ID != "" {
@@ -355,19 +610,100 @@ func (m *MessagesController) createSingle(ctx *gin.Context, messageIn *tat.Messa
 		text = ""
 	}
 
+	transition, err := checkWorkflowNewMessage(topic, *user, messageIn.Labels, messageIn.Messages)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	if messageIn.DateScheduled > 0 {
+		if len(files) > 0 {
+			return nil, http.StatusBadRequest, fmt.Errorf("Attachments can't be added on a scheduled message")
+		}
+		return m.scheduleMessage(messageIn, user, topic, text, idRef)
+	}
+
+	if len(files) > 0 {
+		if text == "" {
+			return nil, http.StatusBadRequest, fmt.Errorf("Attachments need a message with a text")
+		}
+		if err := messageDB.CheckAttachmentsQuota(topic, files); err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+	}
+
 	// New root message or reply
-	err := messageDB.Insert(&message, *user, topic, text, idRef, messageIn.DateCreation, messageIn.Labels, messageIn.Replies, messageIn.Messages, nil)
+	err = messageDB.Insert(&message, *user, topic, text, idRef, messageIn.DateCreation, messageIn.Labels, messageIn.Replies, messageIn.Messages, nil)
 	if err != nil {
 		log.Errorf("%s", err.Error())
 		return nil, http.StatusInternalServerError, err
 	}
+
+	if len(files) > 0 {
+		if err := messageDB.AddAttachments(&message, *user, topic, files); err != nil {
+			if errDelete := messageDB.Delete(&message, false, topic); errDelete != nil {
+				log.Errorf("Error while removing message %s without its attachments: %s", message.ID, errDelete)
+			}
+			return nil, http.StatusInternalServerError, err
+		}
+	}
 	info := fmt.Sprintf("Message created in %s", topic.Topic)
 	out := &tat.MessageJSONOut{Message: message, Info: info}
-	hook.SendHook(&tat.HookJSON{HookMessage: &tat.HookMessageJSON{MessageJSONOut: out, Action: tat.MessageActionCreate}}, topic)
+	hook.SendHook(&tat.HookJSON{HookMessage: &tat.HookMessageJSON{MessageJSONOut: out, Action: tat.MessageActionCreate, Transition: transition}}, topic)
 	return out, http.StatusCreated, nil
 }
 
+// checkNotArchived returns an error if topic is archived: messages of an
+// archived topic can be read, but not created, updated or deleted
+func checkNotArchived(topic tat.Topic) error {
+	if topic.IsArchived {
+		return fmt.Errorf("Topic %s is archived, it is read only", topic.Topic)
+	}
+	return nil
+}
+
+// checkWorkflow returns transition of a message from its labels to newLabels, nil if
+// topic has no workflow or if state of message does not change. Returns an error if
+// workflow of topic does not allow user to make this transition
+func checkWorkflow(topic tat.Topic, user tat.User, labels, newLabels []tat.Label) (*tat.WorkflowTransition, error) {
+	w := topic.Workflow
+	if w == nil {
+		return nil, nil
+	}
+	// a message labelled before workflow could have several states, it leaves them all
+	from, _ := w.State(labels)
+	to, err := w.State(newLabels)
+	if err != nil {
+		return nil, err
+	}
+	if from == to {
+		return nil, nil
+	}
+	var groups []string
+	if w.NeedGroups() {
+		if groups, err = groupDB.GetUserGroupsOnlyName(user.Username); err != nil {
+			return nil, fmt.Errorf("Error while fetching groups of user %s", user.Username)
+		}
+	}
+	if err := w.CheckTransition(from, to, user.Username, groups); err != nil {
+		return nil, err
+	}
+	return &tat.WorkflowTransition{From: from, To: to}, nil
+}
+
+// checkWorkflowNewMessage checks state of a new message, and of replies created
+// with it. Returns transition of the new message
+func checkWorkflowNewMessage(topic tat.Topic, user tat.User, labels []tat.Label, replies []tat.MessageJSON) (*tat.WorkflowTransition, error) {
+	for _, r := range replies {
+		if _, err := checkWorkflowNewMessage(topic, user, r.Labels, r.Messages); err != nil {
+			return nil, err
+		}
+	}
+	return checkWorkflow(topic, user, nil, messageDB.MergeLabels(labels, nil, nil))
+}
+
 // Update a message : like, unlike, add label, etc...
+// With ifDateUpdate, actions label, unlabel, relabel, relabelorcreate, update and concat
+// return 409 and current message if message was updated since ifDateUpdate
 func (m *MessagesController) Update(ctx *gin.Context) {
 	messageIn := &tat.MessageJSON{}
 	ctx.Bind(messageIn)
@@ -376,8 +712,24 @@ func (m *MessagesController) Update(ctx *gin.Context) {
 		return
 	}
 
+	if err := checkNotArchived(topic); err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
 	if messageIn.Action == "like" || messageIn.Action == "unlike" {
-		m.likeOrUnlike(ctx, messageIn.Action, messageReference, topic, *user)
+		out, code, err := m.likeOrUnlike(messageIn.Action, messageReference, topic, *user)
+		writeUpdate(ctx, out, code, err)
+		return
+	}
+
+	if messageIn.Action == tat.MessageActionReact || messageIn.Action == tat.MessageActionUnreact {
+		m.reactOrUnreact(ctx, messageIn, messageReference, topic, *user)
+		return
+	}
+
+	if messageIn.Action == tat.MessageActionPin || messageIn.Action == tat.MessageActionUnpin {
+		m.pinOrUnpin(ctx, messageIn, messageReference, topic, *user)
 		return
 	}
 
@@ -387,9 +739,23 @@ func (m *MessagesController) Update(ctx *gin.Context) {
 		return
 	}
 
+	if messageIn.IfDateUpdate != 0 && messageReference.ID != "" && messageReference.DateUpdate != messageIn.IfDateUpdate {
+		switch messageIn.Action {
+		case tat.MessageActionLabel, tat.MessageActionUnlabel, tat.MessageActionRelabel, tat.MessageActionRelabelOrCreate,
+			tat.MessageActionUpdate, tat.MessageActionConcat:
+			m.writeConflict(ctx, messageReference.ID, topic)
+			return
+		}
+	}
+
 	if messageIn.Action == tat.MessageActionLabel || messageIn.Action == tat.MessageActionUnlabel ||
 		messageIn.Action == tat.MessageActionRelabel || messageIn.Action == tat.MessageActionRelabelOrCreate {
-		m.addOrRemoveLabel(ctx, messageIn, messageReference, *user, topic)
+		out, code, err := m.addOrRemoveLabel(ctx, messageIn, messageReference, *user, topic)
+		if code == http.StatusConflict {
+			m.writeConflict(ctx, messageReference.ID, topic)
+			return
+		}
+		writeUpdate(ctx, out, code, err)
 		return
 	}
 
@@ -400,7 +766,26 @@ func (m *MessagesController) Update(ctx *gin.Context) {
 	}
 
 	if messageIn.Action == tat.MessageActionTask || messageIn.Action == tat.MessageActionUntask {
-		m.addOrRemoveTask(ctx, messageIn, messageReference, *user, topic)
+		out, code, err := m.addOrRemoveTask(messageIn, messageReference, *user, topic)
+		writeUpdate(ctx, out, code, err)
+		return
+	}
+
+	if messageIn.Action == tat.MessageActionFields {
+		out, code, err := m.setFields(messageIn, messageReference, topic)
+		writeUpdate(ctx, out, code, err)
+		return
+	}
+
+	if messageIn.Action == tat.MessageActionAssign || messageIn.Action == tat.MessageActionUnassign {
+		out, code, err := m.assignOrUnassign(messageIn, messageReference, *user, topic)
+		writeUpdate(ctx, out, code, err)
+		return
+	}
+
+	if messageIn.Action == tat.MessageActionTaskState {
+		out, code, err := m.setTaskState(messageIn, messageReference, topic)
+		writeUpdate(ctx, out, code, err)
 		return
 	}
 
@@ -409,15 +794,44 @@ func (m *MessagesController) Update(ctx *gin.Context) {
 		return
 	}
 
+	if messageIn.Action == tat.MessageActionRestore {
+		m.restoreMessage(ctx, messageIn, messageReference, *user, topic, isAdminOnTopic)
+		return
+	}
+
 	if messageIn.Action == tat.MessageActionMove {
 		// topic here is fromTopic
-		m.moveMessage(ctx, messageIn, messageReference, *user, topic)
+		out, code, err := m.moveMessage(messageIn, messageReference, *user, topic)
+		writeUpdate(ctx, out, code, err)
 		return
 	}
 
 	ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Action"})
 }
 
+// writeConflict writes current version of a message which was updated
+// since the ifDateUpdate given on request
+func (m *MessagesController) writeConflict(ctx *gin.Context, idMessage string, topic tat.Topic) {
+	message := tat.Message{}
+	if err := messageDB.FindByID(&message, idMessage, topic); err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Message %s does not exist", idMessage)})
+		return
+	}
+	ctx.JSON(http.StatusConflict, &tat.MessageConflictJSON{
+		Error:   fmt.Sprintf("Message %s was updated since ifDateUpdate", message.ID),
+		Message: message,
+	})
+}
+
+// writeUpdate writes result of an action on a message
+func writeUpdate(ctx *gin.Context, out *tat.MessageJSONOut, code int, err error) {
+	if err != nil {
+		ctx.JSON(code, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(code, out)
+}
+
 // Delete a message
 func (m *MessagesController) Delete(ctx *gin.Context) {
 	m.messageDelete(ctx, false, false)
@@ -496,151 +910,242 @@ func (m *MessagesController) messageDelete(ctx *gin.Context, cascade, force bool
 		return
 	}
 
-	if err = messageDB.Delete(&message, cascade, *topic); err != nil {
+	if err = messageDB.Trash(&message, cascade, user, *topic); err != nil {
 		log.Errorf("Error while delete a message %s", err)
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	sendDeleteHooks(message, msgs, cascade, *topic)
 	ctx.JSON(http.StatusOK, gin.H{"info": fmt.Sprintf("Message deleted from %s", topic.Topic)})
 }
 
-// checkBeforeDelete checks
+// checkBeforeDelete checks if user can delete message, see canDelete
+func (m *MessagesController) checkBeforeDelete(ctx *gin.Context, message tat.Message, user tat.User, force bool, topic tat.Topic) error {
+	code, err := m.canDelete(message, user, force, topic)
+	if err != nil {
+		ctx.JSON(code, gin.H{"error": err.Error()})
+	}
+	return err
+}
+
+// canDelete checks
 // - if user is RW on topic
 // - if topic is Private OR is CanDeleteMsg or CanDeleteAllMsg
-func (m *MessagesController) checkBeforeDelete(ctx *gin.Context, message tat.Message, user tat.User, force bool, topic tat.Topic) error {
+func (m *MessagesController) canDelete(message tat.Message, user tat.User, force bool, topic tat.Topic) (int, error) {
+	if err := checkNotArchived(topic); err != nil {
+		return http.StatusForbidden, err
+	}
 
 	isRW, isTopicAdmin := topicDB.GetUserRights(&topic, &user)
 	if !isRW {
-		e := fmt.Sprintf("No RW Access to topic %s", message.Topic)
-		ctx.JSON(http.StatusForbidden, gin.H{"error": e})
-		return fmt.Errorf(e)
+		return http.StatusForbidden, fmt.Errorf("No RW Access to topic %s", message.Topic)
 	}
 
 	if topic.AdminCanDeleteAllMsg && isTopicAdmin {
-		return nil
+		return 0, nil
 	}
 
 	if !strings.HasPrefix(message.Topic, "/Private/"+user.Username) && !topic.CanDeleteMsg && !topic.CanDeleteAllMsg {
 		if !topic.CanDeleteMsg && !topic.CanDeleteAllMsg {
-			e := fmt.Sprintf("You can't delete a message from topic %s", topic.Topic)
-			ctx.JSON(http.StatusForbidden, gin.H{"error": e})
-			return fmt.Errorf(e)
+			return http.StatusForbidden, fmt.Errorf("You can't delete a message from topic %s", topic.Topic)
 		}
-		e := fmt.Sprintf("Could not delete a message in topic %s", message.Topic)
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": e})
-		return fmt.Errorf(e)
+		return http.StatusBadRequest, fmt.Errorf("Could not delete a message in topic %s", message.Topic)
 	}
 
 	if !topic.CanDeleteAllMsg && message.Author.Username != user.Username && !strings.HasPrefix(message.Topic, "/Private/"+user.Username) {
 		// if it's a reply and force true, allow delete it.
 		if !force || (force && message.InReplyOfIDRoot == "") {
-			e := fmt.Sprintf("Could not delete a message from another user %s than you %s", message.Author.Username, user.Username)
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": e})
-			return fmt.Errorf(e)
+			return http.StatusBadRequest, fmt.Errorf("Could not delete a message from another user %s than you %s", message.Author.Username, user.Username)
 		}
 	}
 
 	// if label done on msg, can delete it
 	if !force && message.IsDoing() {
-		e := fmt.Sprintf("Could not delete a message with a doing label")
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": e})
-		return fmt.Errorf(e)
+		return http.StatusBadRequest, fmt.Errorf("Could not delete a message with a doing label")
 	}
-	return nil
+	return 0, nil
 }
 
-func (m *MessagesController) likeOrUnlike(ctx *gin.Context, action string, message tat.Message, topic tat.Topic, user tat.User) {
+func (m *MessagesController) likeOrUnlike(action string, message tat.Message, topic tat.Topic, user tat.User) (*tat.MessageJSONOut, int, error) {
 
 	info := ""
 	if action == tat.MessageActionLike {
 		if err := messageDB.Like(&message, user, topic); err != nil {
 			log.Errorf("Error while like a message %s", err)
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
+			return nil, http.StatusInternalServerError, err
 		}
 		info = "like added"
 	} else if action == tat.MessageActionUnlike {
 		if err := messageDB.Unlike(&message, user, topic); err != nil {
 			log.Errorf("Error while unlike a message %s", err)
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
+			return nil, http.StatusInternalServerError, err
 		}
 		info = "like removed"
 	} else {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid action: " + action)})
-		return
+		return nil, http.StatusBadRequest, fmt.Errorf("Invalid action: %s", action)
 	}
 	out := &tat.MessageJSONOut{Info: info, Message: message}
 	hook.SendHook(&tat.HookJSON{HookMessage: &tat.HookMessageJSON{MessageJSONOut: out, Action: action}}, topic)
+	return out, http.StatusCreated, nil
+}
+
+func (m *MessagesController) reactOrUnreact(ctx *gin.Context, messageIn *tat.MessageJSON, message tat.Message, topic tat.Topic, user tat.User) {
+
+	info := ""
+	if messageIn.Action == tat.MessageActionReact {
+		if err := messageDB.React(&message, messageIn.Text, user, topic); err != nil {
+			log.Errorf("Error while react on a message %s", err)
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		info = "reaction added"
+	} else {
+		if err := messageDB.Unreact(&message, messageIn.Text, user, topic); err != nil {
+			log.Errorf("Error while unreact on a message %s", err)
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		info = "reaction removed"
+	}
+	out := &tat.MessageJSONOut{Info: info, Message: message}
+	hook.SendHook(&tat.HookJSON{HookMessage: &tat.HookMessageJSON{MessageJSONOut: out, Action: messageIn.Action}}, topic)
 	ctx.JSON(http.StatusCreated, out)
 }
 
-func (m *MessagesController) addOrRemoveLabel(ctx *gin.Context, messageIn *tat.MessageJSON, message tat.Message, user tat.User, topic tat.Topic) {
-	if messageIn.Text == "" && messageIn.Action != tat.MessageActionRelabel {
-		ctx.AbortWithError(http.StatusBadRequest, errors.New("Invalid Text for label"))
+func (m *MessagesController) pinOrUnpin(ctx *gin.Context, messageIn *tat.MessageJSON, message tat.Message, topic tat.Topic, user tat.User) {
+	if !topicDB.IsUserAdmin(&topic, &user) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("No Admin Access to topic %s", topic.Topic)})
 		return
 	}
+	if message.InReplyOfIDRoot != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Only a root message can be pinned"})
+		return
+	}
+
+	info := ""
+	if messageIn.Action == tat.MessageActionPin {
+		if err := topicDB.Pin(&topic, message.ID, user.Username); err != nil {
+			log.Errorf("Error while pin a message %s", err)
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		info = fmt.Sprintf("message pinned on %s", topic.Topic)
+		auditTopic(ctx, tat.AuditActionPin, topic.Topic, false, nil, message.ID)
+	} else {
+		if err := topicDB.Unpin(&topic, message.ID, user.Username); err != nil {
+			log.Errorf("Error while unpin a message %s", err)
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		info = fmt.Sprintf("message unpinned from %s", topic.Topic)
+		auditTopic(ctx, tat.AuditActionUnpin, topic.Topic, false, message.ID, nil)
+	}
+	out := &tat.MessageJSONOut{Info: info, Message: message}
+	hook.SendHook(&tat.HookJSON{HookMessage: &tat.HookMessageJSON{MessageJSONOut: out, Action: messageIn.Action}}, topic)
+	ctx.JSON(http.StatusCreated, out)
+}
+
+func (m *MessagesController) addOrRemoveLabel(ctx *gin.Context, messageIn *tat.MessageJSON, message tat.Message, user tat.User, topic tat.Topic) (*tat.MessageJSONOut, int, error) {
+	if messageIn.Text == "" && messageIn.Action != tat.MessageActionRelabel {
+		return nil, http.StatusBadRequest, errors.New("Invalid Text for label")
+	}
+
+	var transition *tat.WorkflowTransition
+	if message.ID != "" {
+		var newLabels []tat.Label
+		switch {
+		case messageIn.Action == tat.MessageActionLabel:
+			newLabels = messageDB.MergeLabels(message.Labels, []tat.Label{{Text: messageIn.Text, Color: messageIn.Option}}, nil)
+		case messageIn.Action == tat.MessageActionUnlabel:
+			newLabels = messageDB.MergeLabels(message.Labels, nil, []string{messageIn.Text})
+		case len(messageIn.Options) > 0:
+			newLabels = messageDB.MergeLabels(message.Labels, messageIn.Labels, messageIn.Options)
+		default:
+			newLabels = messageDB.MergeLabels(messageIn.Labels, nil, nil)
+		}
+		var err error
+		if transition, err = checkWorkflow(topic, user, message.Labels, newLabels); err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+	}
+
 	out := &tat.MessageJSONOut{}
 	if messageIn.Action == tat.MessageActionLabel {
-		addedLabel, err := messageDB.AddLabel(&message, topic, messageIn.Text, messageIn.Option)
-		if err != nil {
+		addedLabel, err := messageDB.AddLabel(&message, topic, messageIn.Text, messageIn.Option, messageIn.IfDateUpdate)
+		if err == messageDB.ErrConflict {
+			return nil, http.StatusConflict, err
+		} else if err != nil {
 			errInfo := fmt.Sprintf("Error while adding a label to a message %s", err.Error())
 			log.Errorf(errInfo)
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": errInfo})
-			return
+			return nil, http.StatusInternalServerError, errors.New(errInfo)
 		}
 		out = &tat.MessageJSONOut{Info: fmt.Sprintf("label %s added to message", addedLabel.Text), Message: message}
 	} else if messageIn.Action == tat.MessageActionUnlabel {
-		if err := messageDB.RemoveLabel(&message, messageIn.Text, topic); err != nil {
+		if err := messageDB.RemoveLabel(&message, messageIn.Text, topic, messageIn.IfDateUpdate); err == messageDB.ErrConflict {
+			return nil, http.StatusConflict, err
+		} else if err != nil {
 			errInfo := fmt.Sprintf("Error while removing a label from a message %s", err.Error())
 			log.Errorf(errInfo)
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": errInfo})
-			return
+			return nil, http.StatusInternalServerError, errors.New(errInfo)
 		}
 		out = &tat.MessageJSONOut{Info: fmt.Sprintf("label %s removed from message", messageIn.Text), Message: message}
 	} else if messageIn.Action == tat.MessageActionRelabelOrCreate && len(messageIn.Options) == 0 {
 		if message.ID != "" {
-			if err := messageDB.RemoveAllAndAddNewLabel(&message, messageIn.Labels, topic); err != nil {
+			if err := messageDB.RemoveAllAndAddNewLabel(&message, messageIn.Labels, topic, messageIn.IfDateUpdate); err == messageDB.ErrConflict {
+				return nil, http.StatusConflict, err
+			} else if err != nil {
 				errInfo := fmt.Sprintf("Error while removing all labels and add new ones for a message %s", err.Error())
 				log.Errorf(errInfo)
-				ctx.JSON(http.StatusInternalServerError, gin.H{"error": errInfo})
-				return
+				return nil, http.StatusInternalServerError, errors.New(errInfo)
 			}
 			out = &tat.MessageJSONOut{Info: fmt.Sprintf("all labels removed and new labels %s added to message", messageIn.Text), Message: message}
 		} else {
 			// create new message
 			var code int
 			var errCreate error
-			out, code, errCreate = m.createSingle(ctx, messageIn)
+			out, code, errCreate = m.createSingle(ctx, messageIn, nil, 1)
 			if errCreate != nil {
-				ctx.JSON(code, gin.H{"error": errCreate})
-				return
+				return nil, code, errCreate
 			}
 		}
 	} else if messageIn.Action == tat.MessageActionRelabel && len(messageIn.Options) == 0 {
-		if err := messageDB.RemoveAllAndAddNewLabel(&message, messageIn.Labels, topic); err != nil {
+		if err := messageDB.RemoveAllAndAddNewLabel(&message, messageIn.Labels, topic, messageIn.IfDateUpdate); err == messageDB.ErrConflict {
+			return nil, http.StatusConflict, err
+		} else if err != nil {
 			errInfo := fmt.Sprintf("Error while removing all labels and add new ones for a message %s", err.Error())
 			log.Errorf(errInfo)
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": errInfo})
-			return
+			return nil, http.StatusInternalServerError, errors.New(errInfo)
 		}
 		out = &tat.MessageJSONOut{Info: fmt.Sprintf("all labels removed and new labels %s added to message", messageIn.Text), Message: message}
 	} else if messageIn.Action == tat.MessageActionRelabel && len(messageIn.Options) > 0 {
-		if err := messageDB.RemoveSomeAndAddNewLabel(&message, messageIn.Labels, messageIn.Options, topic); err != nil {
+		if err := messageDB.RemoveSomeAndAddNewLabel(&message, messageIn.Labels, messageIn.Options, topic, messageIn.IfDateUpdate); err == messageDB.ErrConflict {
+			return nil, http.StatusConflict, err
+		} else if err != nil {
 			errInfo := fmt.Sprintf("Error while removing some labels and add new ones for a message %s", err.Error())
 			log.Errorf(errInfo)
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": errInfo})
-			return
+			return nil, http.StatusInternalServerError, errors.New(errInfo)
 		}
 		out = &tat.MessageJSONOut{Info: fmt.Sprintf("Some labels removed and new labels %s added to message", messageIn.Text), Message: message}
 
 	} else {
-		ctx.AbortWithError(http.StatusBadRequest, errors.New("Invalid action: "+messageIn.Action))
-		return
+		return nil, http.StatusBadRequest, errors.New("Invalid action: " + messageIn.Action)
+	}
+	hook.SendHook(&tat.HookJSON{HookMessage: &tat.HookMessageJSON{MessageJSONOut: out, Action: messageIn.Action, Transition: transition}}, topic)
+	return out, http.StatusCreated, nil
+}
+
+func (m *MessagesController) setFields(messageIn *tat.MessageJSON, message tat.Message, topic tat.Topic) (*tat.MessageJSONOut, int, error) {
+	if len(messageIn.Fields) == 0 {
+		return nil, http.StatusBadRequest, errors.New("Invalid fields, at least one field is required")
+	}
+	if err := messageDB.SetFields(&message, messageIn.Fields, topic); err != nil {
+		log.Errorf("Error while setting fields on a message %s", err)
+		return nil, http.StatusBadRequest, err
 	}
+	out := &tat.MessageJSONOut{Info: "fields updated on message", Message: message}
 	hook.SendHook(&tat.HookJSON{HookMessage: &tat.HookMessageJSON{MessageJSONOut: out, Action: messageIn.Action}}, topic)
-	ctx.JSON(http.StatusCreated, out)
+	return out, http.StatusCreated, nil
 }
 
 func (m *MessagesController) voteMessage(ctx *gin.Context, messageIn *tat.MessageJSON, message tat.Message, user tat.User, topic tat.Topic) {
@@ -685,54 +1190,130 @@ func (m *MessagesController) voteMessage(ctx *gin.Context, messageIn *tat.Messag
 	ctx.JSON(http.StatusCreated, out)
 }
 
-func (m *MessagesController) addOrRemoveTask(ctx *gin.Context, messageIn *tat.MessageJSON, message tat.Message, user tat.User, topic tat.Topic) {
+func (m *MessagesController) addOrRemoveTask(messageIn *tat.MessageJSON, message tat.Message, user tat.User, topic tat.Topic) (*tat.MessageJSONOut, int, error) {
 	info := ""
 	if messageIn.Action == tat.MessageActionTask {
 		if message.InReplyOfIDRoot != "" {
 			log.Warnf("This message is a reply, you can't task it (%s)", message.ID)
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "This message is a reply, you can't task it"})
-			return
+			return nil, http.StatusInternalServerError, errors.New("This message is a reply, you can't task it")
 		}
 		if err := messageDB.AddToTasks(&message, user, topic); err != nil {
 			log.Errorf("Error while adding a message to tasks %s", err)
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error while adding a message to tasks"})
-			return
+			return nil, http.StatusInternalServerError, errors.New("Error while adding a message to tasks")
 		}
 		info = fmt.Sprintf("New Task created")
 	} else if messageIn.Action == tat.MessageActionUntask {
 		if err := messageDB.RemoveFromTasks(&message, user, topic); err != nil {
 			log.Errorf("Error while removing a message from tasks %s", err)
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
+			return nil, http.StatusInternalServerError, err
 		}
 		info = fmt.Sprintf("Task removed")
 	} else {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid action: " + messageIn.Action})
-		return
+		return nil, http.StatusBadRequest, errors.New("Invalid action: " + messageIn.Action)
 	}
 	out := &tat.MessageJSONOut{Info: info, Message: message}
 	hook.SendHook(&tat.HookJSON{HookMessage: &tat.HookMessageJSON{MessageJSONOut: out, Action: messageIn.Action}}, topic)
-	ctx.JSON(http.StatusCreated, out)
+	return out, http.StatusCreated, nil
 }
 
-func (m *MessagesController) updateMessage(ctx *gin.Context, messageIn *tat.MessageJSON, message tat.Message, user tat.User, topic tat.Topic, isAdminOnTopic bool) {
-	var info string
+// assignOrUnassign assigns message to users or groups, to current user if none given,
+// or removes task from message
+func (m *MessagesController) assignOrUnassign(messageIn *tat.MessageJSON, message tat.Message, user tat.User, topic tat.Topic) (*tat.MessageJSONOut, int, error) {
+	var task *tat.Task
+	info := "Task removed from message"
+	if messageIn.Action == tat.MessageActionAssign {
+		if messageIn.Task == nil {
+			return nil, http.StatusBadRequest, errors.New("Invalid task, task is required to assign a message")
+		}
+		task = &tat.Task{
+			Assignees:  messageIn.Task.Assignees,
+			Groups:     messageIn.Task.Groups,
+			State:      messageIn.Task.State,
+			DateDue:    messageIn.Task.DateDue,
+			AssignedBy: user.Username,
+			DateAssign: tat.TSFromNow(),
+		}
+		if len(task.Assignees) == 0 && len(task.Groups) == 0 {
+			task.Assignees = []string{user.Username}
+		}
+		if task.State == "" {
+			task.State = tat.TaskStateTodo
+		}
+		if err := tat.CheckTaskState(task.State); err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+		if task.DateDue < 0 {
+			return nil, http.StatusBadRequest, errors.New("Invalid dateDue, must be a timestamp")
+		}
+		for _, username := range task.Assignees {
+			if found, err := userDB.FindByUsername(&tat.User{}, username); !found || err != nil {
+				return nil, http.StatusBadRequest, fmt.Errorf("User %s does not exist", username)
+			}
+		}
+		for _, groupname := range task.Groups {
+			if !groupDB.IsGroupnameExists(groupname) {
+				return nil, http.StatusBadRequest, fmt.Errorf("Group %s does not exist", groupname)
+			}
+		}
+		info = fmt.Sprintf("Message assigned to %s", strings.Join(append(append([]string{}, task.Assignees...), task.Groups...), ","))
+	} else if message.Task == nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("Message %s is not a task", message.ID)
+	}
+
+	if err := messageDB.SetTask(&message, task, topic); err != nil {
+		return nil, http.StatusInternalServerError, errors.New("Error while setting task on message")
+	}
+	out := &tat.MessageJSONOut{Info: info, Message: message}
+	hook.SendHook(&tat.HookJSON{HookMessage: &tat.HookMessageJSON{MessageJSONOut: out, Action: messageIn.Action}}, topic)
+	return out, http.StatusCreated, nil
+}
+
+// setTaskState changes state of the task of a message
+func (m *MessagesController) setTaskState(messageIn *tat.MessageJSON, message tat.Message, topic tat.Topic) (*tat.MessageJSONOut, int, error) {
+	if messageIn.Task == nil {
+		return nil, http.StatusBadRequest, errors.New("Invalid task, task.state is required")
+	}
+	if err := messageDB.SetTaskState(&message, messageIn.Task.State, topic); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	out := &tat.MessageJSONOut{Info: fmt.Sprintf("Task is now %s", message.Task.State), Message: message}
+	hook.SendHook(&tat.HookJSON{HookMessage: &tat.HookMessageJSON{MessageJSONOut: out, Action: messageIn.Action}}, topic)
+	return out, http.StatusCreated, nil
+}
 
+// checkBeforeUpdate checks if user can update message, according to topic parameters
+func (m *MessagesController) checkBeforeUpdate(ctx *gin.Context, message tat.Message, user tat.User, topic tat.Topic, isAdminOnTopic bool) error {
 	if isAdminOnTopic && (topic.AdminCanUpdateAllMsg || topic.CanUpdateAllMsg) {
 		// ok, user is admin on topic, and admin can update all msg
-	} else {
-		if !topic.CanUpdateMsg && !topic.CanUpdateAllMsg {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("You can't update a message on topic %s", topic.Topic)})
-			return
-		}
+		return nil
+	}
 
-		if !topic.CanUpdateAllMsg && message.Author.Username != user.Username {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Could not update a message from another user %s than you %s", message.Author.Username, user.Username)})
-			return
-		}
+	if !topic.CanUpdateMsg && !topic.CanUpdateAllMsg {
+		e := fmt.Errorf("You can't update a message on topic %s", topic.Topic)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": e.Error()})
+		return e
+	}
+
+	if !topic.CanUpdateAllMsg && message.Author.Username != user.Username {
+		e := fmt.Errorf("Could not update a message from another user %s than you %s", message.Author.Username, user.Username)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": e.Error()})
+		return e
+	}
+	return nil
+}
+
+func (m *MessagesController) updateMessage(ctx *gin.Context, messageIn *tat.MessageJSON, message tat.Message, user tat.User, topic tat.Topic, isAdminOnTopic bool) {
+	var info string
+
+	if err := m.checkBeforeUpdate(ctx, message, user, topic, isAdminOnTopic); err != nil {
+		// ctx writes in checkBeforeUpdate
+		return
 	}
 
-	if err := messageDB.Update(&message, user, topic, messageIn.Text, messageIn.Action); err != nil {
+	if err := messageDB.Update(&message, user, topic, messageIn.Text, messageIn.Action, messageIn.IfDateUpdate); err == messageDB.ErrConflict {
+		m.writeConflict(ctx, message.ID, topic)
+		return
+	} else if err != nil {
 		log.Errorf("Error while update a message %s", err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -743,31 +1324,149 @@ func (m *MessagesController) updateMessage(ctx *gin.Context, messageIn *tat.Mess
 	ctx.JSON(http.StatusOK, out)
 }
 
-func (m *MessagesController) moveMessage(ctx *gin.Context, messageIn *tat.MessageJSON, message tat.Message, user tat.User, fromTopic tat.Topic) {
+func (m *MessagesController) restoreMessage(ctx *gin.Context, messageIn *tat.MessageJSON, message tat.Message, user tat.User, topic tat.Topic, isAdminOnTopic bool) {
+	if err := m.checkBeforeUpdate(ctx, message, user, topic, isAdminOnTopic); err != nil {
+		// ctx writes in checkBeforeUpdate
+		return
+	}
 
-	// Check if user can delete msg on from topic
-	if err := m.checkBeforeDelete(ctx, message, user, true, fromTopic); err != nil {
-		// ctx writes in checkBeforeDelete
+	revision := tat.MessageRevision{}
+	if err := messageDB.FindRevision(&revision, messageIn.IDRevision, message); err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Revision %s does not exist on message %s", messageIn.IDRevision, message.ID)})
 		return
 	}
 
-	toTopic, err := topicDB.FindByTopic(messageIn.Option, true, false, false, &user)
+	transition, err := checkWorkflow(topic, user, message.Labels, messageDB.MergeLabels(revision.Labels, nil, nil))
 	if err != nil {
-		e := fmt.Sprintf("Topic destination %s does not exist", messageIn.Option)
-		ctx.JSON(http.StatusNotFound, gin.H{"error": e})
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	if err := messageDB.RestoreRevision(&message, revision, user, topic); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	out := &tat.MessageJSONOut{Info: fmt.Sprintf("Revision %s restored in %s", revision.ID, topic.Topic), Message: message}
+	hook.SendHook(&tat.HookJSON{HookMessage: &tat.HookMessageJSON{MessageJSONOut: out, Action: messageIn.Action, Transition: transition}}, topic)
+	ctx.JSON(http.StatusOK, out)
+}
+
+// Revisions returns previous versions of a message
+func (m *MessagesController) Revisions(ctx *gin.Context) {
+	idMessageIn, err := GetParam(ctx, "idMessage")
+	if err != nil {
+		return
+	}
+
+	topicIn, err := GetParam(ctx, "topic")
+	if err != nil {
+		return
+	}
+
+	user, err := PreCheckUser(ctx)
+	if err != nil {
+		return
+	}
+
+	topic, err := m.findTopicToRead(ctx, topicIn, &user)
+	if err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	message := tat.Message{}
+	if err = messageDB.FindByID(&message, idMessageIn, *topic); err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Message %s does not exist", idMessageIn)})
+		return
+	}
+
+	revisions, err := messageDB.ListRevisions(message)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error while listing revisions"})
+		return
+	}
+	ctx.JSON(http.StatusOK, &tat.MessageRevisionsJSON{Revisions: revisions})
+}
+
+// Attachment returns content of an attachment of a message
+func (m *MessagesController) Attachment(ctx *gin.Context) {
+	idMessageIn, err := GetParam(ctx, "idMessage")
+	if err != nil {
+		return
+	}
+
+	idAttachmentIn, err := GetParam(ctx, "idAttachment")
+	if err != nil {
+		return
+	}
+
+	topicIn, err := GetParam(ctx, "topic")
+	if err != nil {
+		return
+	}
+
+	user, err := PreCheckUser(ctx)
+	if err != nil {
+		return
+	}
+
+	topic, err := m.findTopicToRead(ctx, topicIn, &user)
+	if err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	message := tat.Message{}
+	if err = messageDB.FindByID(&message, idMessageIn, *topic); err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Message %s does not exist", idMessageIn)})
+		return
+	}
+
+	attachment, file, err := messageDB.OpenAttachment(message, idAttachmentIn)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Attachment %s does not exist on message %s", idAttachmentIn, idMessageIn)})
+		return
+	}
+	defer file.Close()
+
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", attachment.Filename))
+	ctx.Header("Content-Length", strconv.FormatInt(file.Size(), 10))
+	ctx.Header("Content-Type", attachment.ContentType)
+	ctx.Status(http.StatusOK)
+	if _, err := io.Copy(ctx.Writer, file); err != nil {
+		log.Errorf("Error while sending attachment %s of message %s: %s", idAttachmentIn, idMessageIn, err)
+	}
+}
+
+func (m *MessagesController) moveMessage(messageIn *tat.MessageJSON, message tat.Message, user tat.User, fromTopic tat.Topic) (*tat.MessageJSONOut, int, error) {
+
+	// Check if user can delete msg on from topic
+	if code, err := m.canDelete(message, user, true, fromTopic); err != nil {
+		return nil, code, err
+	}
+
+	toTopic, err := topicDB.FindByTopic(messageIn.Option, true, false, false, &user)
+	if err != nil {
+		return nil, http.StatusNotFound, fmt.Errorf("Topic destination %s does not exist", messageIn.Option)
+	}
+
 	// Check if user can write msg from dest topic
 	if isRW, _ := topicDB.GetUserRights(toTopic, &user); !isRW {
-		ctx.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("No RW Access to topic %s", toTopic.Topic)})
-		return
+		return nil, http.StatusForbidden, fmt.Errorf("No RW Access to topic %s", toTopic.Topic)
+	}
+	if err := checkNotArchived(*toTopic); err != nil {
+		return nil, http.StatusForbidden, err
 	}
 
 	// check if message is a reply -> not possible
 	if message.InReplyOfIDRoot != "" {
-		ctx.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("You can't move a reply message")})
-		return
+		return nil, http.StatusForbidden, fmt.Errorf("You can't move a reply message")
+	}
+
+	// message enters workflow of destination topic
+	transition, err := checkWorkflow(*toTopic, user, nil, message.Labels)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
 	}
 
 	info := ""
@@ -775,17 +1474,15 @@ func (m *MessagesController) moveMessage(ctx *gin.Context, messageIn *tat.Messag
 		err := messageDB.Move(&message, user, fromTopic, *toTopic)
 		if err != nil {
 			log.Errorf("Error while move a message to topic: %s err: %s", toTopic.Topic, err)
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Error while move a message to topic %s", toTopic.Topic)})
-			return
+			return nil, http.StatusInternalServerError, fmt.Errorf("Error while move a message to topic %s", toTopic.Topic)
 		}
 		info = fmt.Sprintf("Message move to %s", toTopic.Topic)
 	} else {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid action: " + messageIn.Action})
-		return
+		return nil, http.StatusBadRequest, errors.New("Invalid action: " + messageIn.Action)
 	}
 	out := &tat.MessageJSONOut{Info: info, Message: message}
-	hook.SendHook(&tat.HookJSON{HookMessage: &tat.HookMessageJSON{MessageJSONOut: out, Action: messageIn.Action}}, *toTopic)
-	ctx.JSON(http.StatusCreated, out)
+	hook.SendHook(&tat.HookJSON{HookMessage: &tat.HookMessageJSON{MessageJSONOut: out, Action: messageIn.Action, Transition: transition}}, *toTopic)
+	return out, http.StatusCreated, nil
 }
 
 func (m *MessagesController) getTopicNameFromAction(username, action string) string {
@@ -943,14 +1640,96 @@ func (m *MessagesController) messagesDeleteBulk(ctx *gin.Context, cascade, force
 
 	nbDelete := 0
 	for _, msg := range out.Messages {
-		if err = messageDB.Delete(&msg, cascade, topic); err != nil {
+		if err = messageDB.Trash(&msg, cascade, user, topic); err != nil {
 			log.Errorf("Error while delete a message %s", err)
 			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		sendDeleteHooks(msg, msg.Replies, cascade, topic)
 		nbDelete++
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{"info": fmt.Sprintf("%d messages (cascade:%t) deleted from %s, limit criteria to %d messages root",
 		nbDelete, cascade, topic.Topic, criteria.Limit)})
 }
+
+// UpdateBulk runs an action on all messages matching criterias: label, unlabel,
+// relabel, like, unlike, task, untask, move or fields. Each message is checked as
+// with Update, a message in error does not stop others
+func (m *MessagesController) UpdateBulk(ctx *gin.Context) {
+	messageIn := &tat.MessageJSON{}
+	ctx.Bind(messageIn)
+
+	switch messageIn.Action {
+	case tat.MessageActionLabel, tat.MessageActionUnlabel, tat.MessageActionRelabel,
+		tat.MessageActionLike, tat.MessageActionUnlike,
+		tat.MessageActionTask, tat.MessageActionUntask, tat.MessageActionMove,
+		tat.MessageActionFields:
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid action for bulk update: %s", messageIn.Action)})
+		return
+	}
+
+	out, user, topic, criteria, httpCode, err := m.innerList(ctx)
+	if err != nil {
+		ctx.JSON(httpCode, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !out.IsTopicRw && messageIn.Action != tat.MessageActionLike && messageIn.Action != tat.MessageActionUnlike {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("No RW Access to topic %s", topic.Topic)})
+		return
+	}
+	if err := checkNotArchived(topic); err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	messages, err := messageDB.ListMessages(criteria, user.Username, topic)
+	if err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	bulk := &tat.MessagesBulkJSONOut{Updated: []string{}}
+	for _, msg := range messages {
+		in := *messageIn
+		in.IDReference = msg.ID
+		// ifDateUpdate is a precondition on one message, not on messages matching criteria
+		in.IfDateUpdate = 0
+		var errUpdate error
+		switch in.Action {
+		case tat.MessageActionLike, tat.MessageActionUnlike:
+			_, _, errUpdate = m.likeOrUnlike(in.Action, msg, topic, user)
+		case tat.MessageActionTask, tat.MessageActionUntask:
+			_, _, errUpdate = m.addOrRemoveTask(&in, msg, user, topic)
+		case tat.MessageActionMove:
+			_, _, errUpdate = m.moveMessage(&in, msg, user, topic)
+		case tat.MessageActionFields:
+			_, _, errUpdate = m.setFields(&in, msg, topic)
+		default:
+			_, _, errUpdate = m.addOrRemoveLabel(ctx, &in, msg, user, topic)
+		}
+		if errUpdate != nil {
+			bulk.Failures = append(bulk.Failures, tat.MessageBulkFailure{IDMessage: msg.ID, Error: errUpdate.Error()})
+			continue
+		}
+		bulk.Updated = append(bulk.Updated, msg.ID)
+	}
+
+	bulk.Info = fmt.Sprintf("%d messages updated (%s), %d failures on %s, limit criteria to %d messages",
+		len(bulk.Updated), messageIn.Action, len(bulk.Failures), topic.Topic, criteria.Limit)
+	ctx.JSON(http.StatusOK, bulk)
+}
+
+// sendDeleteHooks sends a delete event on streams for a message and, if cascade, for its replies.
+// Delete events are not sent to hooks of topic parameters and filters
+func sendDeleteHooks(message tat.Message, replies []tat.Message, cascade bool, topic tat.Topic) {
+	hook.SendStreamHook(&tat.HookJSON{HookMessage: &tat.HookMessageJSON{MessageJSONOut: &tat.MessageJSONOut{Message: message}, Action: tat.MessageActionDelete}}, topic)
+	if !cascade {
+		return
+	}
+	for _, r := range replies {
+		hook.SendStreamHook(&tat.HookJSON{HookMessage: &tat.HookMessageJSON{MessageJSONOut: &tat.MessageJSONOut{Message: r}, Action: tat.MessageActionDelete}}, topic)
+	}
+}
@@ -4,9 +4,12 @@ import (
 	"fmt"
 	"log"
 	"testing"
+	"time"
 
 	"github.com/ovh/tat"
+	messageDB "github.com/ovh/tat/api/message"
 	"github.com/ovh/tat/api/tests"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -219,3 +222,180 @@ func TestMessagesInsert(t *testing.T) {
 	assert.Equal(t, 30, len(replies.Messages[0].Replies))
 
 }
+
+func TestMessagesListReaction(t *testing.T) {
+	tests.Init(t)
+	router := tests.Router(t)
+	client := tests.TATClient(t, tests.AdminUser)
+
+	initRoutesMessages(router, tests.FakeAuthHandler(t, tests.AdminUser, "X-TAT-TEST", true, false))
+	initRoutesTopics(router, tests.FakeAuthHandler(t, tests.AdminUser, "X-TAT-TEST", true, false))
+	initRoutesUsers(router, tests.FakeAuthHandler(t, tests.AdminUser, "X-TAT-TEST", true, false))
+
+	topic, err := client.TopicCreate(tat.TopicCreateJSON{Topic: "/" + tests.RandomString(t, 10), Description: "this is a test"})
+	assert.NoError(t, err)
+	if topic == nil {
+		t.Fail()
+		return
+	}
+	defer client.TopicDelete(tat.TopicNameJSON{Topic: topic.Topic})
+	defer client.TopicTruncate(tat.TopicNameJSON{Topic: topic.Topic})
+
+	message, err := client.MessageAdd(tat.MessageJSON{Text: "test test", Topic: topic.Topic})
+	assert.NoError(t, err)
+	_, err = client.MessageReact(topic.Topic, message.Message.ID, "ok")
+	assert.NoError(t, err)
+
+	messages, err := client.MessageList(topic.Topic, &tat.MessageCriteria{Reaction: "ok,ko"})
+	assert.NoError(t, err)
+	assert.Len(t, messages.Messages, 1)
+
+	for _, reaction := range []string{"a.b", "$ok", "ok,", " "} {
+		_, err = client.MessageList(topic.Topic, &tat.MessageCriteria{Reaction: reaction})
+		assert.Error(t, err, "reaction %q is invalid", reaction)
+	}
+}
+
+func TestMessagesIdempotency(t *testing.T) {
+	tests.Init(t)
+	router := tests.Router(t)
+	client := tests.TATClient(t, tests.AdminUser)
+
+	initRoutesMessages(router, tests.FakeAuthHandler(t, tests.AdminUser, "X-TAT-TEST", true, false))
+	initRoutesTopics(router, tests.FakeAuthHandler(t, tests.AdminUser, "X-TAT-TEST", true, false))
+	initRoutesUsers(router, tests.FakeAuthHandler(t, tests.AdminUser, "X-TAT-TEST", true, false))
+
+	topic, err := client.TopicCreate(tat.TopicCreateJSON{Topic: "/" + tests.RandomString(t, 10), Description: "this is a test"})
+	assert.NoError(t, err)
+	if topic == nil {
+		t.Fail()
+		return
+	}
+	defer client.TopicDelete(tat.TopicNameJSON{Topic: topic.Topic})
+	defer client.TopicTruncate(tat.TopicNameJSON{Topic: topic.Topic})
+
+	// replay with the same key returns the first message
+	key := tat.NewIdempotencyKey()
+	first, err := client.MessageAdd(tat.MessageJSON{Text: "only once", Topic: topic.Topic, IdempotencyKey: key})
+	assert.NoError(t, err)
+	second, err := client.MessageAdd(tat.MessageJSON{Text: "only once", Topic: topic.Topic, IdempotencyKey: key})
+	assert.NoError(t, err)
+	if first != nil && second != nil {
+		assert.Equal(t, first.Message.ID, second.Message.ID)
+	}
+
+	messages, err := client.MessageList(topic.Topic, &tat.MessageCriteria{Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, messages.Messages, 1)
+
+	// a key reserved by a request not finished returns a conflict
+	pending := tat.NewIdempotencyKey()
+	_, err = messageDB.ReserveIdempotencyKey(pending, tests.AdminUser, topic.Topic)
+	assert.NoError(t, err)
+	_, err = client.MessageAdd(tat.MessageJSON{Text: "in progress", Topic: topic.Topic, IdempotencyKey: pending})
+	assert.Error(t, err, "key is in progress")
+
+	messageDB.ReleaseIdempotencyKey(pending, tests.AdminUser, topic.Topic)
+	_, err = client.MessageAdd(tat.MessageJSON{Text: "in progress", Topic: topic.Topic, IdempotencyKey: pending})
+	assert.NoError(t, err)
+
+	// without key, each request creates a message
+	for i := 0; i < 2; i++ {
+		_, err = client.MessageAdd(tat.MessageJSON{Text: "without key", Topic: topic.Topic})
+		assert.NoError(t, err)
+	}
+	messages, err = client.MessageList(topic.Topic, &tat.MessageCriteria{Limit: 10, Text: "without key"})
+	assert.NoError(t, err)
+	assert.Len(t, messages.Messages, 2)
+}
+
+func TestMessagesTrash(t *testing.T) {
+	tests.Init(t)
+	router := tests.Router(t)
+	client := tests.TATClient(t, tests.AdminUser)
+
+	initRoutesMessages(router, tests.FakeAuthHandler(t, tests.AdminUser, "X-TAT-TEST", true, false))
+	initRoutesTopics(router, tests.FakeAuthHandler(t, tests.AdminUser, "X-TAT-TEST", true, false))
+	initRoutesUsers(router, tests.FakeAuthHandler(t, tests.AdminUser, "X-TAT-TEST", true, false))
+
+	viper.Set("trash_retention", 3600)
+	defer viper.Set("trash_retention", 0)
+
+	name := "/" + tests.RandomString(t, 10)
+	topic, err := client.TopicCreate(tat.TopicCreateJSON{Topic: name, Description: "this is a test"})
+	assert.NoError(t, err)
+	if topic == nil {
+		t.Fail()
+		return
+	}
+	defer client.TopicDelete(tat.TopicNameJSON{Topic: name})
+	_, err = client.TopicParameter(tat.TopicParameters{Topic: name, CanDeleteMsg: true, AdminCanDeleteAllMsg: true})
+	assert.NoError(t, err)
+
+	addAndDelete := func() string {
+		message, errm := client.MessageAdd(tat.MessageJSON{Text: "to delete", Topic: name})
+		assert.NoError(t, errm)
+		if message == nil {
+			t.FailNow()
+		}
+		_, errm = client.MessageAdd(tat.MessageJSON{Text: "a reply", Topic: name, IDReference: message.Message.ID})
+		assert.NoError(t, errm)
+		_, errm = client.MessageDelete(message.Message.ID, name, true, false)
+		assert.NoError(t, errm)
+		return message.Message.ID
+	}
+	assertTrash := func(nb int) {
+		trash, errt := client.TrashList(name, 0, 10)
+		assert.NoError(t, errt)
+		if trash != nil {
+			assert.Len(t, trash.Messages, nb)
+		}
+	}
+
+	// delete and undelete a message with its reply
+	id := addAndDelete()
+	trash, err := client.TrashList(name, 0, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, trash.Messages, 1) {
+		assert.Equal(t, id, trash.Messages[0].ID)
+		assert.Equal(t, 2, trash.Messages[0].NbMessages)
+	}
+	messages, err := client.MessageList(name, &tat.MessageCriteria{Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, messages.Messages, 0)
+
+	_, err = client.MessageUndelete(name, id)
+	assert.NoError(t, err)
+	messages, err = client.MessageList(name, &tat.MessageCriteria{Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, messages.Messages, 2)
+	assertTrash(0)
+
+	_, err = client.MessageUndelete(name, id)
+	assert.Error(t, err, "message is not in trash anymore")
+
+	// purge removes messages in trash for more than trash_retention
+	_, err = client.MessageDelete(id, name, true, false)
+	assert.NoError(t, err)
+	viper.Set("trash_retention", 1)
+	time.Sleep(2 * time.Second)
+	_, err = messageDB.PurgeTrash()
+	assert.NoError(t, err)
+	assertTrash(0)
+	viper.Set("trash_retention", 3600)
+
+	// truncate and delete of topic empty its trash
+	addAndDelete()
+	assertTrash(1)
+	_, err = client.TopicTruncate(tat.TopicNameJSON{Topic: name})
+	assert.NoError(t, err)
+	assertTrash(0)
+
+	addAndDelete()
+	assertTrash(1)
+	_, err = client.TopicDelete(tat.TopicNameJSON{Topic: name})
+	assert.NoError(t, err)
+	_, err = client.TopicCreate(tat.TopicCreateJSON{Topic: name, Description: "same name"})
+	assert.NoError(t, err)
+	assertTrash(0)
+}
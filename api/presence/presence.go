@@ -196,6 +196,19 @@ func ChangeAuthorUsernameOnPresences(oldUsername, newUsername string) error {
 	return err
 }
 
+// ChangeTopicOnPresences sets new name of a renamed topic on presences collection
+func ChangeTopicOnPresences(oldName, newName string) error {
+	_, err := store.Tat().CPresences.UpdateAll(
+		bson.M{"topic": oldName},
+		bson.M{"$set": bson.M{"topic": newName}})
+
+	if err != nil {
+		log.Errorf("Error while update topic from %s to %s on Presences %s", oldName, newName, err)
+	}
+
+	return err
+}
+
 // CountPresences returns the total number of presences in db
 func CountPresences() (int, error) {
 	return store.Tat().CPresences.Count()
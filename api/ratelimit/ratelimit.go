@@ -0,0 +1,218 @@
+package ratelimit
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ovh/tat"
+	"github.com/ovh/tat/api/cache"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"gopkg.in/redis.v4"
+)
+
+// Kinds of rate limits
+const (
+	KindUser   = "user"
+	KindTopic  = "topic"
+	KindGlobal = "global"
+)
+
+// throttledWindow is the duration while a throttled user or topic is listed
+const throttledWindow = time.Hour
+
+// script consumes tokens on all buckets, only if all buckets have enough tokens.
+// A bucket has capacity tokens and gets back rate tokens per second.
+// KEYS are buckets, ARGV[1] is now in seconds, followed by capacity, rate and cost
+// of each bucket. Returns index of the first exhausted bucket (0 if none) and seconds
+// to wait before retrying, as string since redis truncates lua numbers to integers
+const script = `
+local now = tonumber(ARGV[1])
+local tokens = {}
+local denied = 0
+local wait = 0
+for i, key in ipairs(KEYS) do
+	local capacity = tonumber(ARGV[i * 3 - 1])
+	local rate = tonumber(ARGV[i * 3])
+	local cost = tonumber(ARGV[i * 3 + 1])
+	local b = redis.call("HMGET", key, "tokens", "ts")
+	local t = tonumber(b[1]) or capacity
+	local ts = tonumber(b[2]) or now
+	t = math.min(capacity, t + math.max(0, now - ts) * rate)
+	if t < cost and denied == 0 then
+		denied = i
+		wait = (cost - t) / rate
+	end
+	tokens[i] = t
+end
+if denied > 0 then
+	return {denied, tostring(wait)}
+end
+for i, key in ipairs(KEYS) do
+	local capacity = tonumber(ARGV[i * 3 - 1])
+	local rate = tonumber(ARGV[i * 3])
+	local cost = tonumber(ARGV[i * 3 + 1])
+	redis.call("HMSET", key, "tokens", tokens[i] - cost, "ts", now)
+	redis.call("EXPIRE", key, math.ceil(capacity / rate) + 1)
+end
+return {0, "0"}
+`
+
+// ExceededError is returned by Allow when a rate limit is exceeded
+type ExceededError struct {
+	Kind       string
+	Name       string
+	Limit      int
+	RetryAfter time.Duration
+}
+
+func (e *ExceededError) Error() string {
+	on := "all topics"
+	if e.Kind != KindGlobal {
+		on = e.Kind + " " + e.Name
+	}
+	return fmt.Sprintf("Rate limit of %d messages per minute exceeded on %s, retry in %s", e.Limit, on, e.RetryAfter)
+}
+
+type bucket struct {
+	kind  string
+	name  string
+	limit int
+	cost  int
+}
+
+// key returns redis key of bucket. All buckets share the same hash tag, to
+// be checked by one script on a redis cluster
+func (b bucket) key() string {
+	if b.name == "" {
+		return cache.Key("tat", "ratelimit", "{buckets}", b.kind)
+	}
+	return cache.Key("tat", "ratelimit", "{buckets}", b.kind, b.name)
+}
+
+func (b bucket) member() string {
+	return b.kind + ":" + b.name
+}
+
+// Limits returns rate limits configured on this instance
+func Limits() tat.RateLimits {
+	return tat.RateLimits{
+		User:   viper.GetInt("rate_limit_user"),
+		Topic:  viper.GetInt("rate_limit_topic"),
+		Global: viper.GetInt("rate_limit_global"),
+	}
+}
+
+// CheckAtStartup displays a warning log if limits are configured without redis
+func CheckAtStartup() {
+	l := Limits()
+	if l.User <= 0 && l.Topic <= 0 && l.Global <= 0 {
+		return
+	}
+	if viper.GetString("redis_hosts") == "" && viper.GetString("redis_sentinels") == "" {
+		log.Warnf("Rate limits are configured but TAT is NOT linked to a redis, messages creation will not be limited")
+	}
+}
+
+// Allow checks that username can create messages, with number of messages by topic.
+// Messages are counted on all buckets only if no limit is exceeded: returns an
+// *ExceededError otherwise. Messages are allowed if redis is not available
+func Allow(username string, topics map[string]int) error {
+	limits := Limits()
+	total := 0
+	for _, n := range topics {
+		total += n
+	}
+
+	buckets := []bucket{}
+	if limits.User > 0 {
+		buckets = append(buckets, bucket{kind: KindUser, name: username, limit: limits.User, cost: total})
+	}
+	if limits.Topic > 0 {
+		for topic, n := range topics {
+			buckets = append(buckets, bucket{kind: KindTopic, name: topic, limit: limits.Topic, cost: n})
+		}
+	}
+	if limits.Global > 0 {
+		buckets = append(buckets, bucket{kind: KindGlobal, limit: limits.Global, cost: total})
+	}
+	if len(buckets) == 0 {
+		return nil
+	}
+
+	now := float64(time.Now().UnixNano()) / 1e9
+	keys := make([]string, len(buckets))
+	args := []interface{}{now}
+	for i, b := range buckets {
+		if b.cost > b.limit {
+			return tat.NewError(http.StatusBadRequest, "Too many messages in one request, rate limit is %d messages per minute on %s", b.limit, b.kind)
+		}
+		keys[i] = b.key()
+		args = append(args, b.limit, float64(b.limit)/60, b.cost)
+	}
+
+	res, err := cache.Client().Eval(script, keys, args...).Result()
+	if err != nil {
+		log.Errorf("Error while checking rate limits of user %s: %s", username, err)
+		return nil
+	}
+	r, ok := res.([]interface{})
+	if !ok || len(r) != 2 {
+		return nil
+	}
+	denied, _ := r[0].(int64)
+	if denied <= 0 || int(denied) > len(buckets) {
+		return nil
+	}
+	wait, _ := r[1].(string)
+	seconds, _ := strconv.ParseFloat(wait, 64)
+
+	b := buckets[denied-1]
+	throttle(b, now)
+	return &ExceededError{Kind: b.kind, Name: b.name, Limit: b.limit, RetryAfter: time.Duration(seconds * float64(time.Second))}
+}
+
+// throttle records a rejected request on bucket, for admins
+func throttle(b bucket, now float64) {
+	log.Warnf("Rate limit of %d messages per minute exceeded on %s %s", b.limit, b.kind, b.name)
+	c := cache.Client()
+	c.ZAdd(cache.Key("tat", "ratelimit", "throttled"), redis.Z{Score: now, Member: b.member()})
+	c.HIncrBy(cache.Key("tat", "ratelimit", "rejected"), b.member(), 1)
+}
+
+// Throttled returns users, topics and global limit throttled during last hour,
+// last throttled first
+func Throttled() ([]tat.RateLimitThrottled, error) {
+	c := cache.Client()
+	keyThrottled := cache.Key("tat", "ratelimit", "throttled")
+	keyRejected := cache.Key("tat", "ratelimit", "rejected")
+
+	since := strconv.FormatFloat(float64(time.Now().Add(-throttledWindow).Unix()), 'f', -1, 64)
+	old, err := c.ZRangeByScore(keyThrottled, redis.ZRangeBy{Min: "-inf", Max: since}).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(old) > 0 {
+		c.ZRemRangeByScore(keyThrottled, "-inf", since)
+		c.HDel(keyRejected, old...)
+	}
+
+	zs, err := c.ZRevRangeWithScores(keyThrottled, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	out := []tat.RateLimitThrottled{}
+	for _, z := range zs {
+		member, _ := z.Member.(string)
+		t := strings.SplitN(member, ":", 2)
+		if len(t) != 2 {
+			continue
+		}
+		rejected, _ := c.HGet(keyRejected, member).Int64()
+		out = append(out, tat.RateLimitThrottled{Kind: t[0], Name: t[1], Rejected: rejected, DateLastThrottled: z.Score})
+	}
+	return out, nil
+}
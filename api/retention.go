@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	messageDB "github.com/ovh/tat/api/message"
+	topicDB "github.com/ovh/tat/api/topic"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+var tasksTopic = regexp.MustCompile("^/Private/[^/]+/Tasks")
+
+// purgeExpiredMessages enforces retention policy of topics and purges trash,
+// every retention_purge_interval seconds. Disabled if interval is 0
+func purgeExpiredMessages() {
+	interval := viper.GetInt("retention_purge_interval")
+	if interval <= 0 {
+		log.Warnf("retention_purge_interval is %d, retention of topics and trash will not be enforced by this instance", interval)
+		return
+	}
+	ticker := time.NewTicker(time.Duration(interval) * time.Second)
+	for range ticker.C {
+		purgeExpiredTopics()
+		purgeTrash()
+	}
+}
+
+func purgeTrash() {
+	nb, err := messageDB.PurgeTrash()
+	if err != nil {
+		log.Errorf("Error while purging trash: %s", err)
+	}
+	if nb > 0 {
+		log.Infof("Trash: %d messages purged", nb)
+	}
+}
+
+func purgeExpiredTopics() {
+	topics, err := topicDB.FindAllTopicsWithRetention()
+	if err != nil {
+		log.Errorf("Error while listing topics with retention: %s", err)
+		return
+	}
+
+	for _, topic := range topics {
+		if tasksTopic.MatchString(topic.Topic) {
+			continue
+		}
+		nb, err := messageDB.PurgeExpired(topic)
+		if err != nil {
+			log.Errorf("Error while purging expired messages on topic %s: %s", topic.Topic, err)
+		}
+		if nb == 0 {
+			continue
+		}
+		log.Infof("Retention: %d messages purged on topic %s", nb, topic.Topic)
+		h := fmt.Sprintf("retention purge: %d messages deleted (retentionMaxAge:%d, retentionMaxMessages:%d)", nb, topic.RetentionMaxAge, topic.RetentionMaxMessages)
+		if err := topicDB.AddToHistory(&topic, "tat", h); err != nil {
+			log.Errorf("Error while adding retention purge to history of topic %s: %s", topic.Topic, err)
+		}
+	}
+}
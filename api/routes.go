@@ -4,6 +4,17 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// initRoutesAudit initialized routes for Audit Controller
+func initRoutesAudit(router *gin.RouterGroup, checkPassword gin.HandlerFunc) {
+	auditCtrl := &AuditController{}
+
+	admin := router.Group("/audit")
+	admin.Use(checkPassword, CheckAdmin())
+	{
+		admin.GET("", auditCtrl.List)
+	}
+}
+
 // initRoutesGroups initialized routes for Groups Controller
 func initRoutesGroups(router *gin.RouterGroup, checkPassword gin.HandlerFunc) {
 	groupsCtrl := &GroupsController{}
@@ -37,11 +48,39 @@ func initRoutesMessages(router *gin.RouterGroup, checkPassword gin.HandlerFunc)
 	{
 		g.POST("/*topic", messagesCtrl.CreateBulk)
 		g.GET("/*topic", messagesCtrl.List)
+		g.PUT("/*topic", messagesCtrl.UpdateBulk)
 		g.DELETE("/nocascade/*topic", messagesCtrl.DeleteBulk)
 		g.DELETE("/cascade/*topic", messagesCtrl.DeleteBulkCascade)
 		g.DELETE("/cascadeforce/*topic", messagesCtrl.DeleteBulkCascadeForce)
 	}
 
+	st := router.Group("/stream")
+	st.Use(checkPassword)
+	{
+		// Server-Sent Events on messages of topics
+		st.GET("/*topic", messagesCtrl.Stream)
+	}
+
+	sc := router.Group("/scheduled")
+	sc.Use(checkPassword)
+	{
+		// Messages scheduled by current user
+		sc.GET("", messagesCtrl.ListScheduled)
+
+		// Cancel a scheduled message
+		sc.DELETE("/:idScheduled", messagesCtrl.CancelScheduled)
+	}
+
+	tr := router.Group("/trash")
+	tr.Use(checkPassword)
+	{
+		// Messages deleted from a topic
+		tr.GET("/*topic", messagesCtrl.ListTrash)
+
+		// Restore a deleted message, with replies deleted with it
+		tr.PUT("/undelete/:idMessage/*topic", messagesCtrl.Undelete)
+	}
+
 	r := router.Group("/read")
 	r.Use()
 	{
@@ -54,9 +93,15 @@ func initRoutesMessages(router *gin.RouterGroup, checkPassword gin.HandlerFunc)
 		//Create a message, a reply
 		gm.POST("/*topic", messagesCtrl.Create)
 
-		// Like, Unlike, Label, Unlabel a message, mark as task, voteup, votedown, unvoteup, unvotedown
+		// Like, Unlike, Label, Unlabel a message, mark as task, voteup, votedown, unvoteup, unvotedown, restore
 		gm.PUT("/*topic", messagesCtrl.Update)
 
+		// Previous versions of a message
+		gm.GET("/:idMessage/revisions/*topic", messagesCtrl.Revisions)
+
+		// Download an attachment of a message
+		gm.GET("/:idMessage/attachments/:idAttachment/*topic", messagesCtrl.Attachment)
+
 		// Delete a message
 		gm.DELETE("/nocascade/:idMessage/*topic", messagesCtrl.Delete)
 
@@ -119,6 +164,7 @@ func initRoutesSystem(router *gin.RouterGroup, checkPassword gin.HandlerFunc) {
 	{
 		admin.GET("/cache/clean", systemCtrl.CleanCache)
 		admin.GET("/cache/info", systemCtrl.CleanInfo)
+		admin.GET("/ratelimit", systemCtrl.RateLimit)
 	}
 }
 
@@ -153,6 +199,7 @@ func initRoutesTopics(router *gin.RouterGroup, checkPassword gin.HandlerFunc) {
 		g.PUT("/topic/compute/labels", topicsCtrl.ComputeLabels)
 		g.PUT("/topic/truncate/labels", topicsCtrl.TruncateLabels)
 		g.PUT("/topic/truncate", topicsCtrl.Truncate)
+		g.POST("/topic/import/*topic", topicsCtrl.Import)
 		g.PUT("/topic/add/rogroup", topicsCtrl.AddRoGroup)
 		g.PUT("/topic/remove/rogroup", topicsCtrl.RemoveRoGroup)
 		g.PUT("/topic/add/rwgroup", topicsCtrl.AddRwGroup)
@@ -160,6 +207,10 @@ func initRoutesTopics(router *gin.RouterGroup, checkPassword gin.HandlerFunc) {
 		g.PUT("/topic/add/admingroup", topicsCtrl.AddAdminGroup)
 		g.PUT("/topic/remove/admingroup", topicsCtrl.RemoveAdminGroup)
 		g.PUT("/topic/param", topicsCtrl.SetParam)
+		g.PUT("/topic/workflow", topicsCtrl.SetWorkflow)
+		g.PUT("/topic/rename", topicsCtrl.Rename)
+		g.PUT("/topic/archive", topicsCtrl.SetArchived)
+		g.POST("/topic/clone", topicsCtrl.Clone)
 	}
 
 	admin := router.Group("/topics")
@@ -190,6 +241,9 @@ func initRoutesUsers(router *gin.RouterGroup, checkPassword gin.HandlerFunc) {
 		g.GET("/me/contacts/:sinceSeconds", usersCtrl.Contacts)
 		g.POST("/me/contacts/:username", usersCtrl.AddContact)
 		g.DELETE("/me/contacts/:username", usersCtrl.RemoveContact)
+		g.GET("/me/mentions", usersCtrl.Mentions)
+		g.PUT("/me/mentions/read", usersCtrl.MarkMentionsRead)
+		g.GET("/me/tasks", usersCtrl.Tasks)
 		g.POST("/me/topics/*topic", usersCtrl.AddFavoriteTopic)
 		g.DELETE("/me/topics/*topic", usersCtrl.RemoveFavoriteTopic)
 		g.POST("/me/tags/:tag", usersCtrl.AddFavoriteTag)
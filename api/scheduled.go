@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ovh/tat"
+	"github.com/ovh/tat/api/hook"
+	messageDB "github.com/ovh/tat/api/message"
+	"github.com/ovh/tat/api/ratelimit"
+	topicDB "github.com/ovh/tat/api/topic"
+	userDB "github.com/ovh/tat/api/user"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// scheduleMessage saves a message, which will be posted on topic at messageIn.DateScheduled
+func (m *MessagesController) scheduleMessage(messageIn *tat.MessageJSON, user *tat.User, topic tat.Topic, text, idRef string) (*tat.MessageJSONOut, int, error) {
+	if messageIn.DateScheduled <= tat.TSFromNow() {
+		return nil, http.StatusBadRequest, fmt.Errorf("dateScheduled must be in the future")
+	}
+	if messageIn.DateCreation > 0 {
+		return nil, http.StatusBadRequest, fmt.Errorf("You can't force date on a scheduled message")
+	}
+	if text == "" && len(messageIn.Replies) == 0 && len(messageIn.Messages) == 0 {
+		return nil, http.StatusBadRequest, fmt.Errorf("Invalid Text:%s", messageIn.Text)
+	}
+	if _, err := tat.CheckMessageFields(messageIn.Fields); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	s := &tat.ScheduledMessage{
+		Topic:         topic.Topic,
+		InReplyOfID:   idRef,
+		Text:          text,
+		Labels:        messageIn.Labels,
+		Replies:       messageIn.Replies,
+		Messages:      messageIn.Messages,
+		Fields:        messageIn.Fields,
+		Author:        tat.Author{Username: user.Username, Fullname: user.Fullname},
+		DateScheduled: messageIn.DateScheduled,
+	}
+	if err := messageDB.InsertScheduled(s); err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	info := fmt.Sprintf("Message scheduled in %s", topic.Topic)
+	return &tat.MessageJSONOut{Info: info, Scheduled: s}, http.StatusCreated, nil
+}
+
+// ListScheduled returns messages scheduled by current user, not yet posted
+// or which could not be posted, with their error
+func (m *MessagesController) ListScheduled(ctx *gin.Context) {
+	scheduled, err := messageDB.ListScheduled(getCtxUsername(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error while listing scheduled messages"})
+		return
+	}
+	ctx.JSON(http.StatusOK, &tat.ScheduledMessagesJSON{Messages: scheduled})
+}
+
+// CancelScheduled removes a message scheduled by current user, before it's posted
+func (m *MessagesController) CancelScheduled(ctx *gin.Context) {
+	idScheduledIn, err := GetParam(ctx, "idScheduled")
+	if err != nil {
+		return
+	}
+
+	if err := messageDB.DeleteScheduled(idScheduledIn, getCtxUsername(ctx)); err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Scheduled message %s does not exist", idScheduledIn)})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"info": fmt.Sprintf("Scheduled message %s cancelled", idScheduledIn)})
+}
+
+// publishScheduledMessages posts scheduled messages when their time comes,
+// checking every scheduled_messages_interval seconds. Disabled if interval is 0
+func publishScheduledMessages() {
+	interval := viper.GetInt("scheduled_messages_interval")
+	if interval <= 0 {
+		log.Warnf("scheduled_messages_interval is %d, scheduled messages will not be posted by this instance", interval)
+		return
+	}
+	ticker := time.NewTicker(time.Duration(interval) * time.Second)
+	for range ticker.C {
+		for {
+			s, err := messageDB.ClaimScheduled()
+			if err != nil || s == nil {
+				break
+			}
+			if err := publishScheduled(s); err != nil {
+				if _, ok := err.(*ratelimit.ExceededError); ok {
+					// posted on next tick, when author can post again
+					log.Warnf("Scheduled message %s of user %s on topic %s delayed: %s", s.ID, s.Author.Username, s.Topic, err)
+					messageDB.ReleaseScheduled(s.ID)
+					break
+				}
+				log.Errorf("Error while posting scheduled message %s of user %s on topic %s: %s", s.ID, s.Author.Username, s.Topic, err)
+				messageDB.FailScheduled(s.ID, err)
+				continue
+			}
+			messageDB.RemoveScheduled(s.ID)
+		}
+	}
+}
+
+// publishScheduled posts a scheduled message, as it would have been posted
+// by its author at DateScheduled
+func publishScheduled(s *tat.ScheduledMessage) error {
+	user := tat.User{}
+	if found, err := userDB.FindByUsername(&user, s.Author.Username); !found || err != nil {
+		return fmt.Errorf("user %s not found", s.Author.Username)
+	}
+
+	topic, err := topicDB.FindByTopic(s.Topic, true, true, true, &user)
+	if err != nil {
+		return fmt.Errorf("topic not found: %s", err)
+	}
+
+	if isRw, _ := topicDB.GetUserRights(topic, &user); !isRw {
+		return fmt.Errorf("No RW Access to topic %s", s.Topic)
+	}
+	if err := checkNotArchived(*topic); err != nil {
+		return err
+	}
+	// workflow could have changed since message was scheduled
+	transition, err := checkWorkflowNewMessage(*topic, user, s.Labels, s.Messages)
+	if err != nil {
+		return err
+	}
+	if err := ratelimit.Allow(user.Username, map[string]int{topic.Topic: 1}); err != nil {
+		return err
+	}
+
+	message := tat.Message{Fields: s.Fields}
+	if err := messageDB.Insert(&message, user, *topic, s.Text, s.InReplyOfID, -1, s.Labels, s.Replies, s.Messages, nil); err != nil {
+		return err
+	}
+	info := fmt.Sprintf("Message created in %s", topic.Topic)
+	out := &tat.MessageJSONOut{Message: message, Info: info}
+	hook.SendHook(&tat.HookJSON{HookMessage: &tat.HookMessageJSON{MessageJSONOut: out, Action: tat.MessageActionCreate, Transition: transition}}, *topic)
+	return nil
+}
@@ -0,0 +1,71 @@
+package store
+
+import (
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// prefixAttachments is the prefix of GridFS collections storing attachments:
+// attachments.files and attachments.chunks
+const prefixAttachments = "attachments"
+
+// AttachmentMeta is metadata saved with each attachment on GridFS
+type AttachmentMeta struct {
+	Topic           string `bson:"topic"`
+	IDMessage       string `bson:"idMessage"`
+	InReplyOfIDRoot string `bson:"inReplyOfIDRoot"`
+	Username        string `bson:"username"`
+}
+
+// GridFSAttachments returns GridFS used to store attachments of messages
+func GridFSAttachments() *mgo.GridFS {
+	return _instance.Session.DB(DatabaseName).GridFS(prefixAttachments)
+}
+
+func ensureIndexesAttachments() {
+	files := GridFSAttachments().Files
+	ensureIndex(files, mgo.Index{Key: []string{"metadata.topic"}})
+	ensureIndex(files, mgo.Index{Key: []string{"metadata.idMessage"}})
+	ensureIndex(files, mgo.Index{Key: []string{"metadata.inReplyOfIDRoot"}})
+}
+
+// AttachmentsSize returns total size in bytes of attachments of a topic
+func AttachmentsSize(topic string) (int64, error) {
+	var result struct {
+		Size int64 `bson:"size"`
+	}
+	err := GridFSAttachments().Files.Pipe([]bson.M{
+		{"$match": bson.M{"metadata.topic": topic}},
+		{"$group": bson.M{"_id": nil, "size": bson.M{"$sum": "$length"}}},
+	}).One(&result)
+	if err == mgo.ErrNotFound {
+		return 0, nil
+	}
+	return result.Size, err
+}
+
+// RemoveAttachments removes attachments matching selector on metadata,
+// ex: bson.M{"metadata.topic": topic}
+func RemoveAttachments(selector bson.M) {
+	gfs := GridFSAttachments()
+	var file struct {
+		ID interface{} `bson:"_id"`
+	}
+	iter := gfs.Find(selector).Select(bson.M{"_id": 1}).Iter()
+	for iter.Next(&file) {
+		if err := gfs.RemoveId(file.ID); err != nil {
+			log.Errorf("Error while removing attachment %v: %s", file.ID, err)
+		}
+	}
+	if err := iter.Close(); err != nil {
+		log.Errorf("Error while listing attachments to remove %v: %s", selector, err)
+	}
+}
+
+// MoveAttachments sets topic on attachments matching selector
+func MoveAttachments(selector bson.M, topic string) {
+	if _, err := GridFSAttachments().Files.UpdateAll(selector, bson.M{"$set": bson.M{"metadata.topic": topic}}); err != nil {
+		log.Errorf("Error while moving attachments %v to topic %s: %s", selector, topic, err)
+	}
+}
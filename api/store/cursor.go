@@ -0,0 +1,35 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ovh/tat"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// CursorQuery returns the selector of elements after cursor, for sortBy.
+// sortBy must be the same as the one used to compute cursor
+func CursorQuery(cursor, sortBy string, allowed []string) (bson.M, error) {
+	cursorSortBy, value, id, err := tat.DecodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	if cursorSortBy != sortBy {
+		return nil, fmt.Errorf("Invalid cursor, it was computed with sortBy %s and not %s", cursorSortBy, sortBy)
+	}
+
+	field := strings.TrimPrefix(sortBy, "-")
+	if !tat.ArrayContains(allowed, field) {
+		return nil, fmt.Errorf("sortBy %s can't be used with a cursor", sortBy)
+	}
+
+	op := "$gt"
+	if strings.HasPrefix(sortBy, "-") {
+		op = "$lt"
+	}
+	return bson.M{"$or": []bson.M{
+		{field: bson.M{op: value}},
+		{field: value, "_id": bson.M{op: id}},
+	}}, nil
+}
@@ -1,6 +1,7 @@
 package store
 
 import (
+	"fmt"
 	"os"
 	"strings"
 	"time"
@@ -22,8 +23,17 @@ const (
 	collectionTopics          = "topics"
 	collectionUsers           = "users"
 	collectionSockets         = "sockets"
+	collectionRevisions       = "revisions"
+	collectionScheduled       = "scheduled"
+	collectionIdempotency     = "idempotency"
+	collectionTrash           = "trash"
+	collectionAudit           = "audit"
 )
 
+// MaxHistory is the number of last entries kept in history of topics and groups,
+// all changes are recorded as audit events
+const MaxHistory = 100
+
 // MongoStore stores MongoDB Session and collections
 type MongoStore struct {
 	Session          *mgo.Session
@@ -33,6 +43,11 @@ type MongoStore struct {
 	CTopics          *mgo.Collection
 	CUsers           *mgo.Collection
 	CSockets         *mgo.Collection
+	CRevisions       *mgo.Collection
+	CScheduled       *mgo.Collection
+	CIdempotency     *mgo.Collection
+	CTrash           *mgo.Collection
+	CAudit           *mgo.Collection
 }
 
 var _instance *MongoStore
@@ -108,6 +123,11 @@ func NewStore() error {
 		CTopics:          session.DB(DatabaseName).C(collectionTopics),
 		CUsers:           session.DB(DatabaseName).C(collectionUsers),
 		CSockets:         session.DB(DatabaseName).C(collectionSockets),
+		CRevisions:       session.DB(DatabaseName).C(collectionRevisions),
+		CScheduled:       session.DB(DatabaseName).C(collectionScheduled),
+		CIdempotency:     session.DB(DatabaseName).C(collectionIdempotency),
+		CTrash:           session.DB(DatabaseName).C(collectionTrash),
+		CAudit:           session.DB(DatabaseName).C(collectionAudit),
 	}
 
 	EnsureIndexes()
@@ -149,6 +169,36 @@ func EnsureIndexes() {
 	ensureIndex(_instance.CPresences, mgo.Index{Key: []string{"topic", "-dateTimePresence"}})
 	ensureIndex(_instance.CPresences, mgo.Index{Key: []string{"userPresence.username", "-datePresence"}})
 	ensureIndex(_instance.CPresences, mgo.Index{Key: []string{"topic", "userPresence.username"}, Unique: true})
+
+	// revisions
+	ensureIndex(_instance.CRevisions, mgo.Index{Key: []string{"idMessage", "-dateRevision"}})
+	ensureIndex(_instance.CRevisions, mgo.Index{Key: []string{"inReplyOfIDRoot"}})
+	ensureIndex(_instance.CRevisions, mgo.Index{Key: []string{"topic"}})
+
+	// scheduled messages
+	ensureIndex(_instance.CScheduled, mgo.Index{Key: []string{"dateScheduled"}})
+	ensureIndex(_instance.CScheduled, mgo.Index{Key: []string{"author.username", "dateScheduled"}})
+
+	// idempotency keys, removed by mongo after their expiration
+	ensureIndex(_instance.CIdempotency, mgo.Index{Key: []string{"username", "topic", "key"}, Unique: true})
+	ensureIndex(_instance.CIdempotency, mgo.Index{Key: []string{"dateExpiration"}, ExpireAfter: time.Second})
+
+	// trash, purged by tat to remove revisions and attachments too
+	ensureIndex(_instance.CTrash, mgo.Index{Key: []string{"topic", "-dateDeletion"}})
+	ensureIndex(_instance.CTrash, mgo.Index{Key: []string{"idDeletion"}})
+	ensureIndex(_instance.CTrash, mgo.Index{Key: []string{"dateDeletion"}})
+
+	// streams opened on topics, by instance
+	ensureIndex(_instance.CSockets, mgo.Index{Key: []string{"topics"}})
+	ensureIndex(_instance.CSockets, mgo.Index{Key: []string{"instance"}})
+
+	// audit events on topics, groups and users
+	ensureIndex(_instance.CAudit, mgo.Index{Key: []string{"-date"}})
+	ensureIndex(_instance.CAudit, mgo.Index{Key: []string{"actor", "-date"}})
+	ensureIndex(_instance.CAudit, mgo.Index{Key: []string{"targetType", "target", "-date"}})
+
+	// attachments
+	ensureIndexesAttachments()
 }
 
 // EnsureIndexesMessages set indexes on a message collection
@@ -158,21 +208,56 @@ func EnsureIndexesMessages(collection string) {
 		//listIndex(_instance.Session.DB(DatabaseName).C(collection), true)
 		ensureIndex(_instance.Session.DB(DatabaseName).C(collection), mgo.Index{Key: []string{"-dateUpdate", "-dateCreation"}})
 		ensureIndex(_instance.Session.DB(DatabaseName).C(collection), mgo.Index{Key: []string{"-dateCreation"}})
+		ensureIndex(_instance.Session.DB(DatabaseName).C(collection), mgo.Index{Key: []string{"-dateCreation", "-_id"}})
+		ensureIndex(_instance.Session.DB(DatabaseName).C(collection), mgo.Index{Key: []string{"-dateUpdate", "-_id"}})
 		ensureIndex(_instance.Session.DB(DatabaseName).C(collection), mgo.Index{Key: []string{"tags"}})
 		ensureIndex(_instance.Session.DB(DatabaseName).C(collection), mgo.Index{Key: []string{"labels.text"}})
 		ensureIndex(_instance.Session.DB(DatabaseName).C(collection), mgo.Index{Key: []string{"inReplyOfID"}})
 		ensureIndex(_instance.Session.DB(DatabaseName).C(collection), mgo.Index{Key: []string{"inReplyOfIDRoot"}})
+		ensureIndex(_instance.Session.DB(DatabaseName).C(collection), mgo.Index{Key: []string{"userMentions", "-dateCreation"}})
+		ensureIndex(_instance.Session.DB(DatabaseName).C(collection), mgo.Index{Key: []string{"task.assignees", "task.dateDue"}, Sparse: true})
+		ensureIndex(_instance.Session.DB(DatabaseName).C(collection), mgo.Index{Key: []string{"task.groups", "task.dateDue"}, Sparse: true})
+		ensureIndex(_instance.Session.DB(DatabaseName).C(collection), mgo.Index{Key: []string{"task.dateDue"}, Sparse: true})
+		ensureIndex(_instance.Session.DB(DatabaseName).C(collection), textIndex())
 	} else {
 		//listIndex(_instance.Session.DB(DatabaseName).C(collection), false)
 		ensureIndex(_instance.Session.DB(DatabaseName).C(collection), mgo.Index{Key: []string{"topic", "-dateUpdate", "-dateCreation"}})
 		ensureIndex(_instance.Session.DB(DatabaseName).C(collection), mgo.Index{Key: []string{"topic", "-dateCreation"}})
+		ensureIndex(_instance.Session.DB(DatabaseName).C(collection), mgo.Index{Key: []string{"topic", "-dateCreation", "-_id"}})
+		ensureIndex(_instance.Session.DB(DatabaseName).C(collection), mgo.Index{Key: []string{"topic", "-dateUpdate", "-_id"}})
 		ensureIndex(_instance.Session.DB(DatabaseName).C(collection), mgo.Index{Key: []string{"topic", "tags"}})
 		ensureIndex(_instance.Session.DB(DatabaseName).C(collection), mgo.Index{Key: []string{"topic", "labels.text"}})
 		ensureIndex(_instance.Session.DB(DatabaseName).C(collection), mgo.Index{Key: []string{"inReplyOfID"}})
 		ensureIndex(_instance.Session.DB(DatabaseName).C(collection), mgo.Index{Key: []string{"inReplyOfIDRoot"}})
+		ensureIndex(_instance.Session.DB(DatabaseName).C(collection), mgo.Index{Key: []string{"userMentions", "-dateCreation"}})
+		ensureIndex(_instance.Session.DB(DatabaseName).C(collection), mgo.Index{Key: []string{"task.assignees", "task.dateDue"}, Sparse: true})
+		ensureIndex(_instance.Session.DB(DatabaseName).C(collection), mgo.Index{Key: []string{"task.groups", "task.dateDue"}, Sparse: true})
+		ensureIndex(_instance.Session.DB(DatabaseName).C(collection), mgo.Index{Key: []string{"task.dateDue"}, Sparse: true})
+		ensureIndex(_instance.Session.DB(DatabaseName).C(collection), textIndex())
 	}
 }
 
+// EnsureIndexesFields set indexes on fields of messages, used by conditions on
+// fields. Indexes are prefixed by topic on default messages collection
+func EnsureIndexesFields(collection string, fields []string) error {
+	col := GetCMessages(collection)
+	for _, name := range fields {
+		key := []string{"fields." + name}
+		if collection == "" || collection == CollectionDefaultMessages {
+			key = []string{"topic", "fields." + name}
+		}
+		if err := col.EnsureIndex(mgo.Index{Key: key, Background: true}); err != nil {
+			return fmt.Errorf("Error while creating index on field %s: %s", name, err)
+		}
+	}
+	return nil
+}
+
+// textIndex returns full-text index on messages, used by textSearch criteria
+func textIndex() mgo.Index {
+	return mgo.Index{Key: []string{"$text:text"}, DefaultLanguage: viper.GetString("db_text_search_language")}
+}
+
 func listIndex(col *mgo.Collection, drop bool) {
 	indexes, err := col.Indexes()
 	if err != nil {
@@ -7,6 +7,7 @@ import (
 	"github.com/ovh/tat"
 	"github.com/ovh/tat/api/cache"
 	"github.com/ovh/tat/api/hook"
+	"github.com/ovh/tat/api/ratelimit"
 	"github.com/spf13/viper"
 )
 
@@ -43,3 +44,14 @@ func (*SystemController) CleanInfo(ctx *gin.Context) {
 		"error":  err,
 	})
 }
+
+// RateLimit returns rate limits on messages creation, and users and topics
+// throttled during last hour
+func (*SystemController) RateLimit(ctx *gin.Context) {
+	throttled, err := ratelimit.Throttled()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, tat.RateLimitJSON{Limits: ratelimit.Limits(), Throttled: throttled})
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ovh/tat"
+	"github.com/ovh/tat/api/hook"
+	messageDB "github.com/ovh/tat/api/message"
+	topicDB "github.com/ovh/tat/api/topic"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// notifyOverdueTasks sends a taskoverdue event to hooks of topic, once per task,
+// when due date of a task is exceeded. Checks every tasks_overdue_interval seconds,
+// disabled if interval is 0
+func notifyOverdueTasks() {
+	interval := viper.GetInt("tasks_overdue_interval")
+	if interval <= 0 {
+		log.Warnf("tasks_overdue_interval is %d, overdue tasks will not be notified by this instance", interval)
+		return
+	}
+	ticker := time.NewTicker(time.Duration(interval) * time.Second)
+	for range ticker.C {
+		notifyOverdueTasksOnce()
+	}
+}
+
+func notifyOverdueTasksOnce() {
+	topics, err := topicDB.FindAllTopicsWithCollections()
+	if err != nil {
+		log.Errorf("Error while listing collections of topics: %s", err)
+		return
+	}
+	collections := []string{""}
+	for _, t := range topics {
+		if !tat.ArrayContains(collections, t.Collection) {
+			collections = append(collections, t.Collection)
+		}
+	}
+
+	messages, err := messageDB.PopOverdueTasks(collections)
+	if err != nil {
+		log.Errorf("Error while listing overdue tasks: %s", err)
+	}
+
+	byTopic := make(map[string]*tat.Topic)
+	for _, m := range messages {
+		topic, ok := byTopic[m.Topic]
+		if !ok {
+			if topic, err = topicDB.FindByTopic(m.Topic, true, false, false, nil); err != nil {
+				log.Errorf("Error while getting topic %s of overdue task %s: %s", m.Topic, m.ID, err)
+			}
+			byTopic[m.Topic] = topic
+		}
+		if topic == nil {
+			continue
+		}
+		info := fmt.Sprintf("Task overdue since %s", time.Unix(int64(m.Task.DateDue), 0).Format(time.RFC3339))
+		out := &tat.MessageJSONOut{Info: info, Message: m}
+		hook.SendHook(&tat.HookJSON{HookMessage: &tat.HookMessageJSON{MessageJSONOut: out, Action: tat.MessageActionTaskOverdue}}, *topic)
+	}
+}
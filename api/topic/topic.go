@@ -125,6 +125,22 @@ func buildTopicCriteria(criteria *tat.TopicCriteria, user *tat.User) (bson.M, er
 		}
 	}
 
+	if criteria.HideArchived == tat.True {
+		query = append(query, bson.M{"isArchived": bson.M{"$ne": true}})
+	}
+
+	if criteria.Cursor != "" {
+		sortBy := criteria.SortBy
+		if sortBy == "" {
+			sortBy = "topic"
+		}
+		queryCursor, err := store.CursorQuery(criteria.Cursor, sortBy, tat.TopicCursorSortFields)
+		if err != nil {
+			return bson.M{}, err
+		}
+		query = append(query, queryCursor)
+	}
+
 	if len(query) > 0 {
 		return bson.M{"$and": query}, nil
 	} else if len(query) == 1 {
@@ -151,6 +167,9 @@ func GetTopicSelectedFields(isAdmin, withTags, withLabels, oneTopic bool) bson.M
 			"adminGroups":          1,
 			"maxlength":            1,
 			"maxreplies":           1,
+			"attachmentsQuota":     1,
+			"retentionMaxAge":      1,
+			"retentionMaxMessages": 1,
 			"canForceDate":         1,
 			"canUpdateMsg":         1,
 			"canDeleteMsg":         1,
@@ -164,6 +183,8 @@ func GetTopicSelectedFields(isAdmin, withTags, withLabels, oneTopic bool) bson.M
 			"dateCreation":         1,
 			"dateLastMessage":      1,
 			"parameters":           1,
+			"workflow":             1,
+			"isArchived":           1,
 		}
 		if oneTopic {
 			b["history"] = 1
@@ -190,12 +211,20 @@ func GetTopicSelectedFields(isAdmin, withTags, withLabels, oneTopic bool) bson.M
 			"isAutoComputeLabels":  1,
 			"maxlength":            1,
 			"maxreplies":           1,
+			"attachmentsQuota":     1,
+			"retentionMaxAge":      1,
+			"retentionMaxMessages": 1,
+			"dateCreation":         1,
 			"dateLastMessage":      1,
 			"parameters":           1,
+			"workflow":             1,
+			"isArchived":           1,
 		}
 	}
 	if oneTopic {
 		b["filters"] = 1
+		b["pinned"] = 1
+		b["indexedFields"] = 1
 	}
 	if withTags {
 		b["tags"] = 1
@@ -220,6 +249,18 @@ func FindAllTopicsWithCollections() ([]tat.Topic, error) {
 	return topics, err
 }
 
+// FindAllTopicsWithRetention returns all topics with a retention policy
+func FindAllTopicsWithRetention() ([]tat.Topic, error) {
+	var topics []tat.Topic
+	err := store.Tat().CTopics.Find(bson.M{"$or": []bson.M{
+		{"retentionMaxAge": bson.M{"$gt": 0}},
+		{"retentionMaxMessages": bson.M{"$gt": 0}},
+	}}).
+		Select(bson.M{"_id": 1, "collection": 1, "topic": 1, "retentionMaxAge": 1, "retentionMaxMessages": 1, "pinned": 1}).
+		All(&topics)
+	return topics, err
+}
+
 // ListTopics returns list of topics, matching criterias
 // /!\ user arg could be nil
 func ListTopics(criteria *tat.TopicCriteria, u *tat.User, isAdmin, withTags, withLabels bool) (int, []tat.Topic, error) {
@@ -261,7 +302,7 @@ func ListTopics(criteria *tat.TopicCriteria, u *tat.User, isAdmin, withTags, wit
 		sortBy = "topic"
 	}
 	err := cursor.Select(GetTopicSelectedFields(isAdmin, withTags, withLabels, oneTopic)).
-		Sort(sortBy).
+		Sort(tat.CursorSortFields(sortBy, tat.TopicCursorSortFields)...).
 		Skip(criteria.Skip).
 		Limit(criteria.Limit).
 		All(&topics)
@@ -316,27 +357,31 @@ func InitPrivateTopic() {
 	}
 }
 
+// reservedNames can't be used as first part of a topic name, they are used by
+// routes with a topic param, as GET /messages/aggregate/*topic
+var reservedNames = []string{"/aggregate", "/export", "/permissions", "/history"}
+
+func checkReservedName(name string) error {
+	for _, r := range reservedNames {
+		if strings.EqualFold(name, r) || strings.HasPrefix(strings.ToLower(name), r+"/") {
+			return tat.NewError(http.StatusBadRequest, "Invalid topic name %s, %s is reserved", name, r)
+		}
+	}
+	return nil
+}
+
 // Insert creates a new topic. User is read write on topic
 func Insert(topic *tat.Topic, u *tat.User) error {
 	if err := CheckAndFixName(topic); err != nil {
 		return err
 	}
+	if err := checkReservedName(topic.Topic); err != nil {
+		return err
+	}
 
-	isParentRootTopic, parentTopic, err := getParentTopic(topic)
-	if !isParentRootTopic {
-		if err != nil {
-			return tat.NewError(http.StatusNotFound, "Parent Topic not found %s", topic.Topic)
-		}
-		// If user create a Topic in /Private/username, no check or RW to create
-		if !strings.HasPrefix(topic.Topic, "/Private/"+u.Username) {
-			// check if user can create topic in /topic
-			hasRW := IsUserAdmin(parentTopic, u)
-			if !hasRW {
-				return tat.NewError(http.StatusUnauthorized, "No RW access to parent topic %s", parentTopic.Topic)
-			}
-		}
-	} else if !u.IsAdmin { // no parent topic, check admin
-		return tat.NewError(http.StatusUnauthorized, "No write access to create parent topic %s", topic.Topic)
+	isParentRootTopic, parentTopic, err := checkParentRights(topic, u)
+	if err != nil {
+		return err
 	}
 	if _, err = FindByTopic(topic.Topic, true, false, false, nil); err == nil {
 		return tat.NewError(http.StatusConflict, "Topic Already Exists : %s", topic.Topic)
@@ -400,6 +445,114 @@ func Insert(topic *tat.Topic, u *tat.User) error {
 	return AddRwUser(topic, u.Username, u.Username, false)
 }
 
+// checkParentRights returns parent of topic, an error if user can't create topic in it
+func checkParentRights(topic *tat.Topic, u *tat.User) (bool, *tat.Topic, error) {
+	isParentRootTopic, parentTopic, err := getParentTopic(topic)
+	if !isParentRootTopic {
+		if err != nil {
+			return false, nil, tat.NewError(http.StatusNotFound, "Parent Topic not found %s", topic.Topic)
+		}
+		// If user create a Topic in /Private/username, no check or RW to create
+		if !strings.HasPrefix(topic.Topic, "/Private/"+u.Username) {
+			// check if user can create topic in /topic
+			hasRW := IsUserAdmin(parentTopic, u)
+			if !hasRW {
+				return false, nil, tat.NewError(http.StatusUnauthorized, "No RW access to parent topic %s", parentTopic.Topic)
+			}
+		}
+	} else if !u.IsAdmin { // no parent topic, check admin
+		return false, nil, tat.NewError(http.StatusUnauthorized, "No write access to create parent topic %s", topic.Topic)
+	}
+	return isParentRootTopic, parentTopic, nil
+}
+
+// privateTopicsByName are private topics used by their name, as /Private/username/Tasks
+var privateTopicsByName = regexp.MustCompile("(?i)^/Private/[^/]+/(Tasks|Notifications)$")
+
+// CheckRename checks that user can rename topic to newName, and returns fixed
+// newName with topics to rename: topic, and its sub-topics if recursive
+func CheckRename(topic *tat.Topic, newName string, recursive bool, u *tat.User) (string, []tat.Topic, error) {
+	newName, err := tat.CheckAndFixNameTopic(newName)
+	if err != nil {
+		return "", nil, tat.NewError(http.StatusBadRequest, "Invalid new topic name: %s", err)
+	}
+	if newName == topic.Topic || strings.HasPrefix(newName, topic.Topic+"/") {
+		return "", nil, tat.NewError(http.StatusBadRequest, "Invalid new topic name %s, topic can't be renamed to itself or to one of its sub-topics", newName)
+	}
+	if err := checkReservedName(newName); err != nil {
+		return "", nil, err
+	}
+	if privateTopicsByName.MatchString(topic.Topic) || privateTopicsByName.MatchString(newName) {
+		return "", nil, tat.NewError(http.StatusForbidden, "Topics /Private/username/Tasks and /Private/username/Notifications can't be renamed")
+	}
+	userPrivate := "/Private/" + u.Username + "/"
+	if (strings.HasPrefix(topic.Topic, "/Private/") || strings.HasPrefix(newName, "/Private/") || topic.Topic == "/Private") &&
+		!(strings.HasPrefix(topic.Topic, userPrivate) && strings.HasPrefix(newName, userPrivate)) {
+		return "", nil, tat.NewError(http.StatusForbidden, "A private topic can only be renamed in %s", userPrivate)
+	}
+	if _, _, err := checkParentRights(&tat.Topic{Topic: newName}, u); err != nil {
+		return "", nil, err
+	}
+
+	var subTopics []tat.Topic
+	if err := store.Tat().CTopics.Find(bson.M{"topic": bson.RegEx{Pattern: "^" + regexp.QuoteMeta(topic.Topic) + "/"}}).
+		Select(bson.M{"_id": 1, "topic": 1, "collection": 1, "adminUsers": 1, "adminGroups": 1}).
+		All(&subTopics); err != nil {
+		log.Errorf("Error while listing sub-topics of %s: %s", topic.Topic, err)
+		return "", nil, fmt.Errorf("Error while listing sub-topics of %s", topic.Topic)
+	}
+	if len(subTopics) > 0 && !recursive {
+		return "", nil, tat.NewError(http.StatusBadRequest, "Topic %s has %d sub-topics, rename it with recursive", topic.Topic, len(subTopics))
+	}
+	if err := checkAdminOnTopics(subTopics, u); err != nil {
+		return "", nil, err
+	}
+
+	topics := append([]tat.Topic{*topic}, subTopics...)
+	for _, t := range topics {
+		name := newName + strings.TrimPrefix(t.Topic, topic.Topic)
+		if IsTopicExists(name) {
+			return "", nil, tat.NewError(http.StatusConflict, "Topic Already Exists : %s", name)
+		}
+	}
+	return newName, topics, nil
+}
+
+// checkAdminOnTopics returns an error if user is not admin on one of topics
+func checkAdminOnTopics(topics []tat.Topic, u *tat.User) error {
+	for i := range topics {
+		if !IsUserAdmin(&topics[i], u) {
+			return tat.NewError(http.StatusForbidden, "User %s is not admin on topic %s", u.Username, topics[i].Topic)
+		}
+	}
+	return nil
+}
+
+// Rename changes name of topic, on its revisions and its attachments, then on topic.
+// Messages, users and presences are updated by caller before, so that a failed
+// renaming can be done again with the old name
+func Rename(topic *tat.Topic, username, newName string) error {
+	oldName := topic.Topic
+	if _, err := store.Tat().CRevisions.UpdateAll(bson.M{"topic": oldName}, bson.M{"$set": bson.M{"topic": newName}}); err != nil {
+		log.Errorf("Error while renaming topic %s to %s on revisions: %s", oldName, newName, err)
+		return fmt.Errorf("Error while renaming topic %s to %s on revisions", oldName, newName)
+	}
+	store.MoveAttachments(bson.M{"metadata.topic": oldName}, newName)
+
+	if err := store.Tat().CTopics.Update(bson.M{"_id": topic.ID}, bson.M{"$set": bson.M{"topic": newName}}); err != nil {
+		log.Errorf("Error while renaming topic %s to %s: %s", oldName, newName, err)
+		return fmt.Errorf("Error while renaming topic %s to %s", oldName, newName)
+	}
+	topic.Topic = newName
+
+	if err := addToHistory(topic, bson.M{"_id": topic.ID}, username, fmt.Sprintf("rename topic from %s to %s", oldName, newName)); err != nil {
+		log.Errorf("Error while inserting history for renaming topic %s: %s", oldName, err)
+	}
+	cache.CleanTopicByName(oldName)
+	cache.CleanMessagesLists(oldName)
+	return nil
+}
+
 // Delete deletes a topic from database
 func Delete(topic *tat.Topic, u *tat.User) error {
 	log.Debugf("Delete: Clean topics cache for user %s", u.Username)
@@ -420,6 +573,9 @@ func Delete(topic *tat.Topic, u *tat.User) error {
 	if err := store.Tat().CTopics.Remove(bson.M{"_id": topic.ID}); err != nil {
 		return fmt.Errorf("Error while remove topic from topics collection: %s", err)
 	}
+	removeRevisions(topic)
+	removeTrash(topic)
+	store.RemoveAttachments(bson.M{"metadata.topic": topic.Topic})
 	cache.CleanAllTopicsLists()
 	return nil
 }
@@ -444,10 +600,32 @@ func Truncate(topic *tat.Topic) (int, error) {
 	if err != nil {
 		return 0, err
 	}
+	removeRevisions(topic)
+	removeTrash(topic)
+	store.RemoveAttachments(bson.M{"metadata.topic": topic.Topic})
+	if err := store.Tat().CTopics.Update(bson.M{"_id": topic.ID}, bson.M{"$unset": bson.M{"pinned": ""}}); err != nil {
+		log.Errorf("Error while removing pinned messages on topic %s: %s", topic.Topic, err)
+	}
+	cache.CleanTopicByName(topic.Topic)
 	cache.CleanMessagesLists(topic.Topic)
 	return changeInfo.Removed, err
 }
 
+// removeRevisions removes revisions of all messages of a topic
+func removeRevisions(topic *tat.Topic) {
+	if _, err := store.Tat().CRevisions.RemoveAll(bson.M{"topic": topic.Topic}); err != nil {
+		log.Errorf("Error while removing revisions of topic %s: %s", topic.Topic, err)
+	}
+}
+
+// removeTrash removes deleted messages of a topic from trash, their revisions
+// and attachments are removed with those of the topic
+func removeTrash(topic *tat.Topic) {
+	if _, err := store.Tat().CTrash.RemoveAll(bson.M{"topic": topic.Topic}); err != nil {
+		log.Errorf("Error while removing trash of topic %s: %s", topic.Topic, err)
+	}
+}
+
 // TruncateTags clears "cached" tags in topic
 func TruncateTags(topic *tat.Topic) error {
 	err := store.Tat().CTopics.Update(
@@ -732,18 +910,25 @@ func setAParam(topic *tat.Topic, key, value string) error {
 			return fmt.Errorf("Error while set param %s with value %s", key, value)
 		}
 		return setParamInDB(topic, key, v)
-	} else if key == "maxreplies" {
+	} else if key == "maxreplies" || key == "retentionMaxMessages" {
 		v, err := strconv.Atoi(value)
 		if err != nil {
 			return fmt.Errorf("Error while set param %s with value %s", key, value)
 		}
 		return setParamInDB(topic, key, v)
+	} else if key == "retentionMaxAge" {
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("Error while set param %s with value %s", key, value)
+		}
+		return setParamInDB(topic, key, v)
 	}
 	return fmt.Errorf("set param %s is an invalid action", key)
 }
 
 func setParamInDB(topic *tat.Topic, key string, value interface{}) error {
-	if key != "maxreplies" && key != "isAutoComputeTags" && key != "isAutoComputeLabels" {
+	if key != "maxreplies" && key != "isAutoComputeTags" && key != "isAutoComputeLabels" &&
+		key != "retentionMaxAge" && key != "retentionMaxMessages" {
 		return fmt.Errorf("set param %s is an invalid action", key)
 	}
 
@@ -805,9 +990,9 @@ func IsTopicExists(topicName string) bool {
 
 // SetParam update param maxLength, maxReplies, canForceDate, canUpdateMsg, canDeleteMsg,
 // canUpdateAllMsg, canDeleteAllMsg, adminCanUpdateAllMsg, adminCanDeleteAllMsg, parameters on topic
-func SetParam(topic *tat.Topic, username string, recursive bool, maxLength, maxReplies int,
+func SetParam(topic *tat.Topic, username string, recursive bool, maxLength, maxReplies int, attachmentsQuota, retentionMaxAge int64, retentionMaxMessages int,
 	canForceDate, canUpdateMsg, canDeleteMsg, canUpdateAllMsg, canDeleteAllMsg, adminCanUpdateAllMsg, adminCanDeleteAllMsg,
-	isAutoComputeTags, isAutoComputeLabels bool, parameters []tat.TopicParameter) error {
+	isAutoComputeTags, isAutoComputeLabels bool, parameters []tat.TopicParameter, indexedFields []string) error {
 
 	var selector bson.M
 
@@ -824,6 +1009,9 @@ func SetParam(topic *tat.Topic, username string, recursive bool, maxLength, maxR
 	update := bson.M{
 		"maxlength":            maxLength,
 		"maxreplies":           maxReplies,
+		"attachmentsQuota":     attachmentsQuota,
+		"retentionMaxAge":      retentionMaxAge,
+		"retentionMaxMessages": retentionMaxMessages,
 		"canForceDate":         canForceDate,
 		"canUpdateMsg":         canUpdateMsg,
 		"canDeleteMsg":         canDeleteMsg,
@@ -838,20 +1026,65 @@ func SetParam(topic *tat.Topic, username string, recursive bool, maxLength, maxR
 	if parameters != nil {
 		update["parameters"] = parameters
 	}
+	if indexedFields != nil {
+		update["indexedFields"] = indexedFields
+	}
 	_, err := store.Tat().CTopics.UpdateAll(selector, bson.M{"$set": update})
 
 	if err != nil {
 		log.Errorf("Error while updateAll parameters : %s", err.Error())
 		return err
 	}
-	h := fmt.Sprintf("update param to maxlength:%d, maxreplies:%d, canForceDate:%t, canUpdateMsg:%t, canDeleteMsg:%t, canUpdateAllMsg:%t, canDeleteAllMsg:%t, adminCanDeleteAllMsg:%t isAutoComputeTags:%t, isAutoComputeLabels:%t",
-		maxLength, maxReplies, canForceDate, canUpdateMsg, canDeleteMsg, canUpdateAllMsg, canDeleteAllMsg, adminCanDeleteAllMsg, isAutoComputeTags, isAutoComputeLabels)
+
+	if len(indexedFields) > 0 {
+		if err := ensureIndexesFields(selector, indexedFields); err != nil {
+			return err
+		}
+	}
+
+	h := fmt.Sprintf("update param to maxlength:%d, maxreplies:%d, attachmentsQuota:%d, retentionMaxAge:%d, retentionMaxMessages:%d, canForceDate:%t, canUpdateMsg:%t, canDeleteMsg:%t, canUpdateAllMsg:%t, canDeleteAllMsg:%t, adminCanDeleteAllMsg:%t isAutoComputeTags:%t, isAutoComputeLabels:%t",
+		maxLength, maxReplies, attachmentsQuota, retentionMaxAge, retentionMaxMessages, canForceDate, canUpdateMsg, canDeleteMsg, canUpdateAllMsg, canDeleteAllMsg, adminCanDeleteAllMsg, isAutoComputeTags, isAutoComputeLabels)
+	if indexedFields != nil {
+		h += fmt.Sprintf(", indexedFields:%s", strings.Join(indexedFields, ","))
+	}
 
 	err = addToHistory(topic, selector, username, h)
 	cache.CleanTopicByName(topic.Topic)
 	return err
 }
 
+// IsOnDefaultCollection returns true if messages of topic, or of one of its
+// sub-topics if recursive, are in the default messages collection, shared by topics
+func IsOnDefaultCollection(topic *tat.Topic, recursive bool) (bool, error) {
+	selector := bson.M{"_id": topic.ID}
+	if recursive {
+		selector = bson.M{"topic": bson.RegEx{Pattern: "^" + topic.Topic + ".*$"}}
+	}
+	selector["collection"] = bson.M{"$in": []string{"", store.CollectionDefaultMessages}}
+	n, err := store.Tat().CTopics.Find(selector).Count()
+	if err != nil {
+		log.Errorf("Error while counting topics on default collection: %s", err)
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// ensureIndexesFields creates indexes on fields of messages, on collections of topics matching selector
+func ensureIndexesFields(selector bson.M, indexedFields []string) error {
+	var collections []string
+	if err := store.Tat().CTopics.Find(selector).Distinct("collection", &collections); err != nil {
+		log.Errorf("Error while getting collections of topics: %s", err)
+		return err
+	}
+	for _, collection := range collections {
+		if err := store.EnsureIndexesFields(collection, indexedFields); err != nil {
+			log.Errorf("Error while creating indexes on fields on collection %s: %s", collection, err)
+			return err
+		}
+	}
+	return nil
+}
+
 func actionOnSetParameter(topic *tat.Topic, operand, set, admin string, newParam tat.TopicParameter, recursive bool, history string) error {
 
 	var selector bson.M
@@ -1025,6 +1258,241 @@ func UpdateFilter(topic *tat.Topic, filter *tat.Filter) error {
 	return err
 }
 
+// SetWorkflow sets workflow of labels on topic, a nil workflow removes it
+func SetWorkflow(topic *tat.Topic, username string, workflow *tat.Workflow) error {
+	update := bson.M{"$unset": bson.M{"workflow": ""}}
+	history := "remove workflow"
+	if workflow != nil {
+		update = bson.M{"$set": bson.M{"workflow": workflow}}
+		history = fmt.Sprintf("set workflow with states %s and %d transitions", strings.Join(workflow.States, ","), len(workflow.Transitions))
+	}
+	if err := store.Tat().CTopics.Update(bson.M{"_id": topic.ID}, update); err != nil {
+		return err
+	}
+	topic.Workflow = workflow
+	return AddToHistory(topic, username, history)
+}
+
+// SetArchived archives or unarchives topic, with its sub-topics if recursive
+func SetArchived(topic *tat.Topic, username string, isArchived, recursive bool) error {
+	selector := bson.M{"_id": topic.ID}
+	if recursive {
+		selector = bson.M{"topic": bson.RegEx{Pattern: "^" + regexp.QuoteMeta(topic.Topic) + "(/.*)?$"}}
+	}
+	if _, err := store.Tat().CTopics.UpdateAll(selector, bson.M{"$set": bson.M{"isArchived": isArchived}}); err != nil {
+		return err
+	}
+	history := "unarchive topic"
+	if isArchived {
+		history = "archive topic"
+	}
+	cache.CleanAllTopicsLists()
+	return addToHistory(topic, selector, username, history)
+}
+
+// CheckClone checks that topic can be cloned to newName, and returns fixed newName
+// with topics to clone: topic, and its sub-topics if recursive, parents first. User must be
+// admin on each topic to clone
+func CheckClone(topic *tat.Topic, newName string, recursive bool, u *tat.User) (string, []tat.Topic, error) {
+	newName, err := tat.CheckAndFixNameTopic(newName)
+	if err != nil {
+		return "", nil, tat.NewError(http.StatusBadRequest, "Invalid new topic name: %s", err)
+	}
+	if recursive && (newName == topic.Topic || strings.HasPrefix(newName, topic.Topic+"/")) {
+		return "", nil, tat.NewError(http.StatusBadRequest, "Invalid new topic name %s, topic can't be cloned to one of its sub-topics", newName)
+	}
+	if err := checkReservedName(newName); err != nil {
+		return "", nil, err
+	}
+
+	selector := bson.M{"_id": topic.ID}
+	if recursive {
+		selector = bson.M{"topic": bson.RegEx{Pattern: "^" + regexp.QuoteMeta(topic.Topic) + "(/.*)?$"}}
+	}
+	var topics []tat.Topic
+	if err := store.Tat().CTopics.Find(selector).Sort("topic").All(&topics); err != nil {
+		log.Errorf("Error while listing topics to clone from %s: %s", topic.Topic, err)
+		return "", nil, fmt.Errorf("Error while listing topics to clone from %s", topic.Topic)
+	}
+
+	// parameters, ACLs and filters of each cloned topic are copied to topics of user
+	if err := checkAdminOnTopics(topics, u); err != nil {
+		return "", nil, err
+	}
+
+	names := []string{}
+	for _, t := range topics {
+		names = append(names, newName+strings.TrimPrefix(t.Topic, topic.Topic))
+	}
+	var existing tat.Topic
+	if err := store.Tat().CTopics.Find(bson.M{"topic": bson.M{"$in": names}}).Select(bson.M{"topic": 1}).One(&existing); err == nil {
+		return "", nil, tat.NewError(http.StatusConflict, "Topic Already Exists : %s", existing.Topic)
+	} else if err != mgo.ErrNotFound {
+		log.Errorf("Error while checking topics %s: %s", strings.Join(names, ","), err)
+		return "", nil, fmt.Errorf("Error while checking new topics")
+	}
+	return newName, topics, nil
+}
+
+// Clone copies settings of source on topic, newly inserted by username. Flags, limits,
+// indexed fields and workflow are always copied, ACLs, parameters, filters and labels only
+// if asked in parts. username stays a read write user of topic if ACLs are copied
+func Clone(source, topic *tat.Topic, username string, parts tat.TopicCloneJSON) error {
+	update := bson.M{
+		"maxlength":            source.MaxLength,
+		"maxreplies":           source.MaxReplies,
+		"attachmentsQuota":     source.AttachmentsQuota,
+		"retentionMaxAge":      source.RetentionMaxAge,
+		"retentionMaxMessages": source.RetentionMaxMessages,
+		"canForceDate":         source.CanForceDate,
+		"canUpdateMsg":         source.CanUpdateMsg,
+		"canDeleteMsg":         source.CanDeleteMsg,
+		"canUpdateAllMsg":      source.CanUpdateAllMsg,
+		"canDeleteAllMsg":      source.CanDeleteAllMsg,
+		"adminCanUpdateAllMsg": source.AdminCanUpdateAllMsg,
+		"adminCanDeleteAllMsg": source.AdminCanDeleteAllMsg,
+		"isAutoComputeTags":    source.IsAutoComputeTags,
+		"isAutoComputeLabels":  source.IsAutoComputeLabels,
+		"indexedFields":        source.IndexedFields,
+	}
+	if source.Workflow != nil {
+		update["workflow"] = source.Workflow
+	}
+	if parts.ACL {
+		rwUsers := source.RWUsers
+		if !tat.ArrayContains(rwUsers, username) {
+			rwUsers = append(append([]string{}, rwUsers...), username)
+		}
+		update["roUsers"] = source.ROUsers
+		update["rwUsers"] = rwUsers
+		update["adminUsers"] = source.AdminUsers
+		update["roGroups"] = source.ROGroups
+		update["rwGroups"] = source.RWGroups
+		update["adminGroups"] = source.AdminGroups
+	}
+	if parts.Parameters {
+		update["parameters"] = source.Parameters
+	}
+	if parts.Filters {
+		// filters are copied only for users who can read the clone, with
+		// copied ACL or ACL inherited from its parent, username is read write on it
+		acl := *topic
+		acl.RWUsers = append(append([]string{}, topic.RWUsers...), username)
+		if parts.ACL {
+			acl.ROUsers = update["roUsers"].([]string)
+			acl.RWUsers = update["rwUsers"].([]string)
+			acl.AdminUsers = update["adminUsers"].([]string)
+			acl.ROGroups = update["roGroups"].([]string)
+			acl.RWGroups = update["rwGroups"].([]string)
+			acl.AdminGroups = update["adminGroups"].([]string)
+		}
+		filters := []tat.Filter{}
+		for _, f := range source.Filters {
+			read, err := canRead(&acl, f.Username)
+			if err != nil {
+				return err
+			}
+			if !read {
+				log.Infof("Clone %s to %s: filter %s of %s not copied, no read access", source.Topic, topic.Topic, f.Title, f.Username)
+				continue
+			}
+			f.ID = bson.NewObjectId().Hex()
+			f.Hooks = append([]tat.Hook{}, f.Hooks...)
+			for i := range f.Hooks {
+				f.Hooks[i].ID = bson.NewObjectId().Hex()
+			}
+			filters = append(filters, f)
+		}
+		update["filters"] = filters
+	}
+	if parts.Labels {
+		update["labels"] = source.Labels
+	}
+
+	selector := bson.M{"_id": topic.ID}
+	if err := store.Tat().CTopics.Update(selector, bson.M{"$set": update}); err != nil {
+		log.Errorf("Error while cloning %s to %s: %s", source.Topic, topic.Topic, err)
+		return fmt.Errorf("Error while cloning %s to %s", source.Topic, topic.Topic)
+	}
+	if len(source.IndexedFields) > 0 {
+		if err := ensureIndexesFields(selector, source.IndexedFields); err != nil {
+			return err
+		}
+	}
+	cache.CleanAllTopicsLists()
+	return addToHistory(topic, selector, username, "clone from "+source.Topic)
+}
+
+// canRead returns true if topic is a private topic of username or if username
+// is in an ACL of topic, directly or by one of his groups
+func canRead(topic *tat.Topic, username string) (bool, error) {
+	if topic.Topic == "/Private/"+username || strings.HasPrefix(topic.Topic, "/Private/"+username+"/") {
+		return true, nil
+	}
+	for _, field := range []string{"roUsers", "rwUsers", "adminUsers"} {
+		if tat.ArrayContains(topicACL(topic, field), username) {
+			return true, nil
+		}
+	}
+	groups, err := group.GetUserGroupsOnlyName(username)
+	if err != nil {
+		return false, fmt.Errorf("Error while fetching groups of user %s", username)
+	}
+	for _, field := range []string{"roGroups", "rwGroups", "adminGroups"} {
+		if tat.ItemInBothArrays(topicACL(topic, field), groups) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Pin adds a message to pinned messages of the topic
+func Pin(topic *tat.Topic, idMessage, username string) error {
+	if tat.ArrayContains(topic.Pinned, idMessage) {
+		return fmt.Errorf("Message %s is already pinned on topic %s", idMessage, topic.Topic)
+	}
+	err := store.Tat().CTopics.Update(
+		bson.M{"_id": topic.ID},
+		bson.M{"$addToSet": bson.M{"pinned": idMessage}},
+	)
+	if err != nil {
+		return err
+	}
+	topic.Pinned = append(topic.Pinned, idMessage)
+	addToHistory(topic, bson.M{"_id": topic.ID}, username, "pin message "+idMessage)
+	cache.CleanTopicByName(topic.Topic)
+	cache.CleanMessagesLists(topic.Topic)
+	return nil
+}
+
+// Unpin removes a message from pinned messages of the topic
+func Unpin(topic *tat.Topic, idMessage, username string) error {
+	if !tat.ArrayContains(topic.Pinned, idMessage) {
+		return fmt.Errorf("Message %s is not pinned on topic %s", idMessage, topic.Topic)
+	}
+	if err := RemovePinned(topic, idMessage); err != nil {
+		return err
+	}
+	addToHistory(topic, bson.M{"_id": topic.ID}, username, "unpin message "+idMessage)
+	cache.CleanTopicByName(topic.Topic)
+	return nil
+}
+
+// RemovePinned removes messages from pinned messages of the topic,
+// used when messages are unpinned, deleted or moved
+func RemovePinned(topic *tat.Topic, idsMessage ...string) error {
+	err := store.Tat().CTopics.Update(
+		bson.M{"topic": topic.Topic},
+		bson.M{"$pullAll": bson.M{"pinned": idsMessage}},
+	)
+	if err != nil {
+		return err
+	}
+	cache.CleanTopicByName(topic.Topic)
+	cache.CleanMessagesLists(topic.Topic)
+	return nil
+}
+
 // AddParameter add a parameter to the topic
 func AddParameter(topic *tat.Topic, admin string, parameterKey string, parameterValue string, recursive bool) error {
 	return actionOnSetParameter(topic, "$addToSet", "parameters", admin, tat.TopicParameter{Key: parameterKey, Value: parameterValue}, recursive, "add to parameter")
@@ -1035,11 +1503,18 @@ func RemoveParameter(topic *tat.Topic, admin string, parameterKey string, parame
 	return actionOnSetParameter(topic, "$pull", "parameters", admin, tat.TopicParameter{Key: parameterKey, Value: ""}, recursive, "remove from parameters")
 }
 
+// AddToHistory adds an entry in history of one topic
+func AddToHistory(topic *tat.Topic, user string, historyToAdd string) error {
+	err := addToHistory(topic, bson.M{"_id": topic.ID}, user, historyToAdd)
+	cache.CleanTopicByName(topic.Topic)
+	return err
+}
+
 func addToHistory(topic *tat.Topic, selector bson.M, user string, historyToAdd string) error {
 	toAdd := strconv.FormatInt(time.Now().Unix(), 10) + " " + user + " " + historyToAdd
 	_, err := store.Tat().CTopics.UpdateAll(
 		selector,
-		bson.M{"$addToSet": bson.M{"history": toAdd}},
+		bson.M{"$push": bson.M{"history": bson.M{"$each": []string{toAdd}, "$slice": -store.MaxHistory}}},
 	)
 	return err
 }
@@ -1101,6 +1576,120 @@ func IsUserAdmin(topic *tat.Topic, user *tat.User) bool {
 	return strings.HasPrefix(topic.Topic, "/Private/"+user.Username)
 }
 
+// Permissions returns effective rights of user on topic, with grants giving each right.
+// Rights follow checks done on topics: read through any list of users or groups (ListTopics),
+// write through rwUsers, rwGroups or adminUsers (GetUserRights), admin through adminUsers,
+// adminGroups, Tat admin or /Private/username (IsUserAdmin)
+func Permissions(topic *tat.Topic, user *tat.User) (*tat.TopicPermissionsJSON, error) {
+	groups, err := group.GetUserGroupsOnlyName(user.Username)
+	if err != nil {
+		return nil, fmt.Errorf("Error while fetching groups of user %s", user.Username)
+	}
+	ancestors := getAncestors(topic)
+
+	out := &tat.TopicPermissionsJSON{Topic: topic.Topic, Username: user.Username, Grants: []tat.TopicGrant{}}
+	add := func(right, source, field, name string) {
+		out.Grants = append(out.Grants, tat.TopicGrant{
+			Right:          right,
+			Source:         source,
+			Field:          field,
+			Name:           name,
+			SameOnAncestor: sameOnAncestor(ancestors, field, name),
+		})
+	}
+	byUser := func(right, field string) {
+		if tat.ArrayContains(topicACL(topic, field), user.Username) {
+			add(right, tat.TopicGrantUser, field, user.Username)
+		}
+	}
+	byGroups := func(right, field string) {
+		for _, g := range groups {
+			if tat.ArrayContains(topicACL(topic, field), g) {
+				add(right, tat.TopicGrantGroup, field, g)
+			}
+		}
+	}
+
+	for _, field := range []string{"roUsers", "rwUsers", "adminUsers"} {
+		byUser(tat.TopicRightRead, field)
+	}
+	for _, field := range []string{"roGroups", "rwGroups", "adminGroups"} {
+		byGroups(tat.TopicRightRead, field)
+	}
+	byUser(tat.TopicRightWrite, "rwUsers")
+	byUser(tat.TopicRightWrite, "adminUsers")
+	byGroups(tat.TopicRightWrite, "rwGroups")
+	if user.IsAdmin {
+		add(tat.TopicRightAdmin, tat.TopicGrantTatAdmin, "", "")
+	}
+	byUser(tat.TopicRightAdmin, "adminUsers")
+	byGroups(tat.TopicRightAdmin, "adminGroups")
+	if strings.HasPrefix(topic.Topic, "/Private/"+user.Username) {
+		add(tat.TopicRightAdmin, tat.TopicGrantPrivate, "", "")
+	}
+
+	for _, g := range out.Grants {
+		switch g.Right {
+		case tat.TopicRightRead:
+			out.Read = true
+		case tat.TopicRightWrite:
+			out.Write = true
+		case tat.TopicRightAdmin:
+			out.Admin = true
+		}
+	}
+	return out, nil
+}
+
+// getAncestors returns existing ancestors of topic, parent first
+func getAncestors(topic *tat.Topic) []tat.Topic {
+	ancestors := []tat.Topic{}
+	for name := topic.Topic; strings.LastIndex(name, "/") > 0; {
+		name = name[:strings.LastIndex(name, "/")]
+		parent, err := FindByTopic(name, true, false, false, nil)
+		if err != nil {
+			break
+		}
+		ancestors = append(ancestors, *parent)
+	}
+	return ancestors
+}
+
+// sameOnAncestor returns the top ancestor having name in field, through an
+// unbroken chain of ancestors, empty if parent does not have it. Tat does not
+// record where an entry comes from, it's a hint on a copy at creation
+func sameOnAncestor(ancestors []tat.Topic, field, name string) string {
+	if field == "" {
+		return ""
+	}
+	from := ""
+	for i := range ancestors {
+		if !tat.ArrayContains(topicACL(&ancestors[i], field), name) {
+			break
+		}
+		from = ancestors[i].Topic
+	}
+	return from
+}
+
+func topicACL(topic *tat.Topic, field string) []string {
+	switch field {
+	case "roUsers":
+		return topic.ROUsers
+	case "rwUsers":
+		return topic.RWUsers
+	case "adminUsers":
+		return topic.AdminUsers
+	case "roGroups":
+		return topic.ROGroups
+	case "rwGroups":
+		return topic.RWGroups
+	case "adminGroups":
+		return topic.AdminGroups
+	}
+	return nil
+}
+
 // CheckAndFixName Add a / to topic name is it is not present
 // return an error if length of name is < 4 or > 100
 func CheckAndFixName(topic *tat.Topic) error {
@@ -1,14 +1,17 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/ovh/tat"
+	auditDB "github.com/ovh/tat/api/audit"
 	groupDB "github.com/ovh/tat/api/group"
 	messageDB "github.com/ovh/tat/api/message"
 	presenceDB "github.com/ovh/tat/api/presence"
@@ -18,6 +21,15 @@ import (
 	"github.com/spf13/viper"
 )
 
+// exportPrefix is the prefix of topic param on GET /topic/export/*topic
+const exportPrefix = "/export/"
+
+// permissionsPrefix is the prefix of topic param on GET /topic/permissions/*topic
+const permissionsPrefix = "/permissions/"
+
+// historyPrefix is the prefix of topic param on GET /topic/history/*topic
+const historyPrefix = "/history/"
+
 // TopicsController contains all methods about topics manipulation
 type TopicsController struct{}
 
@@ -45,6 +57,9 @@ func (*TopicsController) buildCriteria(ctx *gin.Context, user *tat.User) *tat.To
 	c.OnlyFavorites = ctx.Query("onlyFavorites")
 	c.GetForTatAdmin = ctx.Query("getForTatAdmin")
 	c.TopicPath = ctx.Query("topicPath")
+	c.SortBy = ctx.Query("sortBy")
+	c.Cursor = ctx.Query("cursor")
+	c.HideArchived = ctx.Query("hideArchived")
 
 	if c.OnlyFavorites == "true" {
 		c.Topic = strings.Join(user.FavoritesTopics, ",")
@@ -75,6 +90,9 @@ func (t *TopicsController) List(ctx *gin.Context) {
 	}
 
 	out := &tat.TopicsJSON{Topics: topics, Count: count}
+	if len(topics) > 0 && len(topics) >= criteria.Limit {
+		out.NextCursor = topics[len(topics)-1].GetCursor(criteria.SortBy)
+	}
 
 	if criteria.GetNbMsgUnread == "true" {
 		c := &tat.PresenceCriteria{
@@ -89,7 +107,7 @@ func (t *TopicsController) List(ctx *gin.Context) {
 		unread := make(map[string]int)
 		var knownPresence bool
 		for _, topic := range topics {
-			if tat.ArrayContains(user.OffNotificationsTopics, topic.Topic) {
+			if topic.IsArchived || tat.ArrayContains(user.OffNotificationsTopics, topic.Topic) {
 				continue
 			}
 			knownPresence = false
@@ -120,14 +138,54 @@ func (t *TopicsController) OneTopic(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, fmt.Errorf("Error while getting topic in param"))
 		return
 	}
-	out, _, code, err := t.innerOneTopic(ctx, topicRequest)
+	// GET /topic/export/*topic can't be routed next to GET /topic/*topic
+	if strings.HasPrefix(topicRequest, exportPrefix) {
+		t.Export(ctx, "/"+strings.TrimPrefix(topicRequest, exportPrefix))
+		return
+	}
+	if strings.HasPrefix(topicRequest, permissionsPrefix) {
+		t.Permissions(ctx, "/"+strings.TrimPrefix(topicRequest, permissionsPrefix))
+		return
+	}
+	if strings.HasPrefix(topicRequest, historyPrefix) {
+		t.History(ctx, "/"+strings.TrimPrefix(topicRequest, historyPrefix))
+		return
+	}
+	out, user, code, err := t.innerOneTopic(ctx, topicRequest)
 	if err != nil {
 		ctx.JSON(code, gin.H{"error": err.Error()})
 		return
 	}
+	if len(out.Topic.Pinned) > 0 {
+		out.PinnedMessages, err = pinnedMessages(out.Topic, user.Username)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
 	ctx.JSON(code, out)
 }
 
+// pinnedMessages returns pinned messages of a topic, in pin order
+func pinnedMessages(topic *tat.Topic, username string) ([]tat.Message, error) {
+	c := &tat.MessageCriteria{Topic: topic.Topic, Pinned: tat.True, Limit: len(topic.Pinned)}
+	msgs, err := messageDB.ListMessages(c, username, *topic)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]tat.Message, len(msgs))
+	for _, m := range msgs {
+		byID[m.ID] = m
+	}
+	pinned := make([]tat.Message, 0, len(msgs))
+	for _, id := range topic.Pinned {
+		if m, ok := byID[id]; ok {
+			pinned = append(pinned, m)
+		}
+	}
+	return pinned, nil
+}
+
 func (t *TopicsController) innerOneTopic(ctx *gin.Context, topicRequest string) (*tat.TopicJSON, *tat.User, int, error) {
 	var user = tat.User{}
 	found, err := userDB.FindByUsername(&user, getCtxUsername(ctx))
@@ -181,6 +239,7 @@ func (*TopicsController) Create(ctx *gin.Context) {
 		ctx.JSON(tat.Error(err))
 		return
 	}
+	auditTopic(ctx, tat.AuditActionCreate, topic.Topic, false, nil, topic.Description)
 	ctx.JSON(http.StatusCreated, topic)
 }
 
@@ -239,6 +298,7 @@ func (t *TopicsController) Delete(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	auditTopic(ctx, tat.AuditActionDelete, topic.Topic, false, nil, nil)
 	ctx.JSON(http.StatusOK, gin.H{"info": fmt.Sprintf("Topic %s is deleted", topic.Topic)})
 }
 
@@ -258,10 +318,174 @@ func (t *TopicsController) Truncate(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error while truncate topic " + topic.Topic})
 		return
 	}
+	auditTopic(ctx, tat.AuditActionTruncate, topic.Topic, false, nil, nbRemoved)
 	// 201 returns
 	ctx.JSON(http.StatusCreated, gin.H{"info": fmt.Sprintf("%d messages removed", nbRemoved)})
 }
 
+// Export streams root messages of a topic with their replies, one JSON per line
+func (t *TopicsController) Export(ctx *gin.Context, topicRequest string) {
+	user, err := PreCheckUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	topic, err := topicDB.FindByTopic(topicRequest, user.IsAdmin, false, false, &user)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("topic %s does not exist or you have no access on it", topicRequest)})
+		return
+	}
+
+	ctx.Header("Content-Type", "application/x-ndjson")
+	ctx.Status(http.StatusOK)
+	encoder := json.NewEncoder(ctx.Writer)
+	end := &tat.TopicExportEndJSON{ExportEnd: true}
+	err = messageDB.Export(*topic, func(message tat.Message) error {
+		if err := encoder.Encode(message); err != nil {
+			return err
+		}
+		end.Messages++
+		ctx.Writer.Flush()
+		return nil
+	})
+	if err != nil {
+		// status is already sent, last line tells client that export is truncated
+		log.Errorf("Error while exporting topic %s: %s", topic.Topic, err)
+		end.Error = fmt.Sprintf("Error while exporting topic %s", topic.Topic)
+	}
+	encoder.Encode(end)
+	ctx.Writer.Flush()
+}
+
+// Permissions returns effective rights of a user on a topic, with grants giving
+// each right, only if user is Tat admin, or admin on topic. Current user if no
+// username in query
+func (t *TopicsController) Permissions(ctx *gin.Context, topicRequest string) {
+	topic, e := t.preCheckUserAdminOnTopic(ctx, topicRequest)
+	if e != nil {
+		ctx.JSON(tat.Error(e))
+		return
+	}
+
+	username := ctx.Query("username")
+	if username == "" {
+		username = getCtxUsername(ctx)
+	}
+	var user = tat.User{}
+	found, err := userDB.FindByUsername(&user, username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error while fetching user"})
+		return
+	}
+	if !found {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("User %s does not exist", username)})
+		return
+	}
+
+	out, err := topicDB.Permissions(topic, &user)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, out)
+}
+
+// History returns audit events on a topic, last first, only if user is Tat admin,
+// or admin on topic
+func (t *TopicsController) History(ctx *gin.Context, topicRequest string) {
+	topic, e := t.preCheckUserAdminOnTopic(ctx, topicRequest)
+	if e != nil {
+		ctx.JSON(tat.Error(e))
+		return
+	}
+
+	a := &AuditController{}
+	criteria, err := a.buildCriteria(ctx)
+	if err != nil {
+		ctx.JSON(tat.Error(err))
+		return
+	}
+	criteria.TargetType = tat.AuditTargetTopic
+	criteria.Target = topic.Topic
+	a.list(ctx, criteria)
+}
+
+// Import inserts messages of a JSON lines stream returned by Export, only if user
+// is Tat admin, or admin on topic
+func (t *TopicsController) Import(ctx *gin.Context) {
+	topicRequest, err := GetParam(ctx, "topic")
+	if err != nil {
+		return
+	}
+	topic, e := t.preCheckUserAdminOnTopic(ctx, topicRequest)
+	if e != nil {
+		ctx.JSON(tat.Error(e))
+		return
+	}
+	if err := checkNotArchived(*topic); err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	user := tat.User{Username: getCtxUsername(ctx)}
+	out := &tat.TopicImportJSON{}
+	decoder := json.NewDecoder(ctx.Request.Body)
+	for {
+		var line json.RawMessage
+		if err := decoder.Decode(&line); err == io.EOF {
+			break
+		} else if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid JSON line after %d messages imported: %s", out.Messages, err)})
+			return
+		}
+		if end := tat.ParseTopicExportEnd(line); end != nil {
+			if end.Error != "" {
+				out.Failures = append(out.Failures, tat.MessageBulkFailure{Error: "Export is truncated: " + end.Error})
+			}
+			continue
+		}
+		var message tat.Message
+		if err := json.Unmarshal(line, &message); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid JSON line after %d messages imported: %s", out.Messages, err)})
+			return
+		}
+		if message.InReplyOfID != "" {
+			out.Failures = append(out.Failures, tat.MessageBulkFailure{IDMessage: message.ID, Error: "Not a root message"})
+			continue
+		}
+		if err := checkWorkflowImport(*topic, user, message); err != nil {
+			out.Failures = append(out.Failures, tat.MessageBulkFailure{IDMessage: message.ID, Error: err.Error()})
+			continue
+		}
+		if code, err := checkRateLimit(ctx, topic.Topic, 1+len(message.Replies)); err != nil {
+			ctx.JSON(code, gin.H{"error": fmt.Sprintf("Import stopped after %d messages imported: %s", out.Messages, err)})
+			return
+		}
+		nb, err := messageDB.Import(message, *topic)
+		out.Messages += nb
+		if err != nil {
+			out.Failures = append(out.Failures, tat.MessageBulkFailure{IDMessage: message.ID, Error: err.Error()})
+		}
+	}
+	out.Info = fmt.Sprintf("%d messages imported in %s", out.Messages, topic.Topic)
+	auditTopic(ctx, tat.AuditActionImport, topic.Topic, false, nil, out.Messages)
+	ctx.JSON(http.StatusOK, out)
+}
+
+// checkWorkflowImport checks states of an exported message and of its replies,
+// as new messages created by user on topic
+func checkWorkflowImport(topic tat.Topic, user tat.User, message tat.Message) error {
+	if _, err := checkWorkflow(topic, user, nil, message.Labels); err != nil {
+		return err
+	}
+	for _, r := range message.Replies {
+		if _, err := checkWorkflow(topic, user, nil, r.Labels); err != nil {
+			return fmt.Errorf("Reply %s: %s", r.ID, err)
+		}
+	}
+	return nil
+}
+
 // ComputeTags computes tags on one topic
 func (t *TopicsController) ComputeTags(ctx *gin.Context) {
 	var paramJSON tat.TopicNameJSON
@@ -315,6 +539,7 @@ func (t *TopicsController) TruncateTags(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error while clear tags on topic " + topic.Topic})
 		return
 	}
+	auditTopic(ctx, tat.AuditActionTruncateTags, topic.Topic, false, topic.Tags, nil)
 	ctx.JSON(http.StatusOK, gin.H{"info": fmt.Sprintf("%d tags cleared", len(topic.Tags))})
 }
 
@@ -333,6 +558,7 @@ func (t *TopicsController) TruncateLabels(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error while clear labels on topic " + topic.Topic})
 		return
 	}
+	auditTopic(ctx, tat.AuditActionTruncateLabels, topic.Topic, false, topic.Labels, nil)
 	ctx.JSON(http.StatusOK, gin.H{"info": fmt.Sprintf("%d labels cleared", len(topic.Labels))})
 }
 
@@ -400,6 +626,7 @@ func (t *TopicsController) AddRoUser(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	auditTopic(ctx, tat.AuditActionAddRoUser, topic.Topic, paramJSON.Recursive, nil, paramJSON.Username)
 	ctx.JSON(http.StatusCreated, "")
 }
 
@@ -419,6 +646,7 @@ func (t *TopicsController) AddRwUser(ctx *gin.Context) {
 		return
 	}
 
+	auditTopic(ctx, tat.AuditActionAddRwUser, topic.Topic, paramJSON.Recursive, nil, paramJSON.Username)
 	ctx.JSON(http.StatusCreated, "")
 }
 
@@ -437,6 +665,7 @@ func (t *TopicsController) AddAdminUser(ctx *gin.Context) {
 		return
 	}
 
+	auditTopic(ctx, tat.AuditActionAddAdminUser, topic.Topic, paramJSON.Recursive, nil, paramJSON.Username)
 	ctx.JSON(http.StatusCreated, "")
 }
 
@@ -455,6 +684,7 @@ func (t *TopicsController) RemoveRoUser(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	auditTopic(ctx, tat.AuditActionRemoveRoUser, topic.Topic, paramJSON.Recursive, paramJSON.Username, nil)
 	ctx.JSON(http.StatusOK, "")
 }
 
@@ -472,6 +702,7 @@ func (t *TopicsController) RemoveRwUser(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	auditTopic(ctx, tat.AuditActionRemoveRwUser, topic.Topic, paramJSON.Recursive, paramJSON.Username, nil)
 	ctx.JSON(http.StatusOK, "")
 }
 
@@ -489,6 +720,7 @@ func (t *TopicsController) RemoveAdminUser(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	auditTopic(ctx, tat.AuditActionRemoveAdminUser, topic.Topic, paramJSON.Recursive, paramJSON.Username, nil)
 	ctx.JSON(http.StatusOK, "")
 }
 
@@ -506,6 +738,7 @@ func (t *TopicsController) AddRoGroup(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	auditTopic(ctx, tat.AuditActionAddRoGroup, topic.Topic, paramJSON.Recursive, nil, paramJSON.Groupname)
 	ctx.JSON(http.StatusCreated, "")
 }
 
@@ -525,6 +758,7 @@ func (t *TopicsController) AddRwGroup(ctx *gin.Context) {
 		return
 	}
 
+	auditTopic(ctx, tat.AuditActionAddRwGroup, topic.Topic, paramJSON.Recursive, nil, paramJSON.Groupname)
 	ctx.JSON(http.StatusCreated, "")
 }
 
@@ -544,6 +778,7 @@ func (t *TopicsController) AddAdminGroup(ctx *gin.Context) {
 		return
 	}
 
+	auditTopic(ctx, tat.AuditActionAddAdminGroup, topic.Topic, paramJSON.Recursive, nil, paramJSON.Groupname)
 	ctx.JSON(http.StatusCreated, "")
 }
 
@@ -564,6 +799,7 @@ func (t *TopicsController) AddParameter(ctx *gin.Context) {
 		return
 	}
 
+	auditTopic(ctx, tat.AuditActionAddParameter, topic.Topic, topicParameterBind.Recursive, nil, tat.TopicParameter{Key: topicParameterBind.Key, Value: topicParameterBind.Value})
 	ctx.JSON(http.StatusCreated, "")
 }
 
@@ -585,6 +821,7 @@ func (t *TopicsController) RemoveParameter(ctx *gin.Context) {
 		return
 	}
 
+	auditTopic(ctx, tat.AuditActionRemoveParameter, topic.Topic, topicParameterBind.Recursive, tat.TopicParameter{Key: topicParameterBind.Key, Value: topicParameterBind.Value}, nil)
 	ctx.JSON(http.StatusCreated, "")
 }
 
@@ -614,6 +851,7 @@ func (t *TopicsController) AddFilter(ctx *gin.Context) {
 		return
 	}
 
+	auditTopic(ctx, tat.AuditActionAddFilter, out.Topic.Topic, false, nil, topicFilterBind)
 	ctx.JSON(http.StatusCreated, gin.H{"info": "filter added on topic", "filter": topicFilterBind})
 }
 
@@ -658,10 +896,13 @@ func (t *TopicsController) RemoveFilter(ctx *gin.Context) {
 		return
 	}
 
-	for _, f := range out.Topic.Filters {
+	var old *tat.Filter
+	for i, f := range out.Topic.Filters {
 		if f.ID == topicFilterBind.ID && f.UserID != user.ID {
 			ctx.JSON(http.StatusForbidden, gin.H{"error": "You can not remove a filter which not belong to you"})
 			return
+		} else if f.ID == topicFilterBind.ID {
+			old = &out.Topic.Filters[i]
 		}
 	}
 
@@ -670,6 +911,7 @@ func (t *TopicsController) RemoveFilter(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	auditTopic(ctx, tat.AuditActionRemoveFilter, out.Topic.Topic, false, old, nil)
 
 	ctx.JSON(http.StatusCreated, gin.H{"info": "filter removed from topic", "filter": topicFilterBind})
 }
@@ -695,10 +937,13 @@ func (t *TopicsController) UpdateFilter(ctx *gin.Context) {
 		return
 	}
 
-	for _, f := range out.Topic.Filters {
+	var old *tat.Filter
+	for i, f := range out.Topic.Filters {
 		if f.ID == topicFilterBind.ID && f.UserID != user.ID {
 			ctx.JSON(http.StatusForbidden, gin.H{"error": "You can not update a filter which not belong to you"})
 			return
+		} else if f.ID == topicFilterBind.ID {
+			old = &out.Topic.Filters[i]
 		}
 	}
 
@@ -710,6 +955,7 @@ func (t *TopicsController) UpdateFilter(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	auditTopic(ctx, tat.AuditActionUpdateFilter, out.Topic.Topic, false, old, topicFilterBind)
 
 	ctx.JSON(http.StatusCreated, gin.H{"info": "filter updated on topic", "filter": topicFilterBind})
 }
@@ -730,6 +976,7 @@ func (t *TopicsController) RemoveRoGroup(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	auditTopic(ctx, tat.AuditActionRemoveRoGroup, topic.Topic, paramJSON.Recursive, paramJSON.Groupname, nil)
 	ctx.JSON(http.StatusOK, "")
 }
 
@@ -749,6 +996,7 @@ func (t *TopicsController) RemoveRwGroup(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	auditTopic(ctx, tat.AuditActionRemoveRwGroup, topic.Topic, paramJSON.Recursive, paramJSON.Groupname, nil)
 	ctx.JSON(http.StatusOK, "")
 }
 
@@ -768,6 +1016,7 @@ func (t *TopicsController) RemoveAdminGroup(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	auditTopic(ctx, tat.AuditActionRemoveAdminGroup, topic.Topic, paramJSON.Recursive, paramJSON.Groupname, nil)
 	ctx.JSON(http.StatusOK, "")
 }
 
@@ -775,6 +1024,9 @@ type paramsJSON struct {
 	Topic                string               `json:"topic"`
 	MaxLength            int                  `json:"maxlength"`
 	MaxReplies           int                  `json:"maxreplies"`
+	AttachmentsQuota     int64                `json:"attachmentsQuota"`
+	RetentionMaxAge      int64                `json:"retentionMaxAge"`
+	RetentionMaxMessages int                  `json:"retentionMaxMessages"`
 	CanForceDate         bool                 `json:"canForceDate"`
 	CanUpdateMsg         bool                 `json:"canUpdateMsg"`
 	CanDeleteMsg         bool                 `json:"canDeleteMsg"`
@@ -786,9 +1038,58 @@ type paramsJSON struct {
 	IsAutoComputeLabels  bool                 `json:"isAutoComputeLabels"`
 	Recursive            bool                 `json:"recursive"`
 	Parameters           []tat.TopicParameter `json:"parameters"`
+	IndexedFields        []string             `json:"indexedFields"`
+}
+
+// topicParams returns params of topic, as set by SetParam
+func topicParams(topic *tat.Topic) paramsJSON {
+	return paramsJSON{
+		Topic:                topic.Topic,
+		MaxLength:            topic.MaxLength,
+		MaxReplies:           topic.MaxReplies,
+		AttachmentsQuota:     topic.AttachmentsQuota,
+		RetentionMaxAge:      topic.RetentionMaxAge,
+		RetentionMaxMessages: topic.RetentionMaxMessages,
+		CanForceDate:         topic.CanForceDate,
+		CanUpdateMsg:         topic.CanUpdateMsg,
+		CanDeleteMsg:         topic.CanDeleteMsg,
+		CanUpdateAllMsg:      topic.CanUpdateAllMsg,
+		CanDeleteAllMsg:      topic.CanDeleteAllMsg,
+		AdminCanUpdateAllMsg: topic.AdminCanUpdateAllMsg,
+		AdminCanDeleteAllMsg: topic.AdminCanDeleteAllMsg,
+		IsAutoComputeTags:    topic.IsAutoComputeTags,
+		IsAutoComputeLabels:  topic.IsAutoComputeLabels,
+		Parameters:           topic.Parameters,
+		IndexedFields:        topic.IndexedFields,
+	}
 }
 
-// SetParam update Topic Parameters : MaxLength, MaxReplies, CanForeceDate, CanUpdateMsg, CanDeleteMsg, CanUpdateAllMsg, CanDeleteAllMsg, AdminCanDeleteAllMsg
+// checkIndexedFields checks that new indexes on fields are not created on the default
+// messages collection, shared by all topics without dedicated collection, except by tat admin
+func checkIndexedFields(ctx *gin.Context, topic *tat.Topic, indexedFields []string, recursive bool) (int, error) {
+	if isTatAdmin(ctx) {
+		return 0, nil
+	}
+	newFields := recursive && len(indexedFields) > 0
+	for _, f := range indexedFields {
+		if !tat.ArrayContains(topic.IndexedFields, f) {
+			newFields = true
+		}
+	}
+	if !newFields {
+		return 0, nil
+	}
+	onDefault, err := topicDB.IsOnDefaultCollection(topic, recursive)
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("Error while checking collection of topic %s", topic.Topic)
+	}
+	if onDefault {
+		return http.StatusForbidden, fmt.Errorf("Only Tat Admin can index fields of topics without dedicated collection")
+	}
+	return 0, nil
+}
+
+// SetParam update Topic Parameters : MaxLength, MaxReplies, AttachmentsQuota, RetentionMaxAge, RetentionMaxMessages, CanForeceDate, CanUpdateMsg, CanDeleteMsg, CanUpdateAllMsg, CanDeleteAllMsg, AdminCanDeleteAllMsg, IndexedFields
 // admin only, except on Private topic
 func (t *TopicsController) SetParam(ctx *gin.Context) {
 	var paramsBind paramsJSON
@@ -817,10 +1118,28 @@ func (t *TopicsController) SetParam(ctx *gin.Context) {
 		}
 	}
 
+	if len(paramsBind.IndexedFields) > tat.MaxTopicIndexedFields {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Too many indexed fields, %d max", tat.MaxTopicIndexedFields)})
+		return
+	}
+	for _, f := range paramsBind.IndexedFields {
+		if err := tat.CheckFieldName(f); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if code, err := checkIndexedFields(ctx, topic, paramsBind.IndexedFields, paramsBind.Recursive); err != nil {
+		ctx.JSON(code, gin.H{"error": err.Error()})
+		return
+	}
+
 	err = topicDB.SetParam(topic, getCtxUsername(ctx),
 		paramsBind.Recursive,
 		paramsBind.MaxLength,
 		paramsBind.MaxReplies,
+		paramsBind.AttachmentsQuota,
+		paramsBind.RetentionMaxAge,
+		paramsBind.RetentionMaxMessages,
 		paramsBind.CanForceDate,
 		paramsBind.CanUpdateMsg,
 		paramsBind.CanDeleteMsg,
@@ -830,7 +1149,8 @@ func (t *TopicsController) SetParam(ctx *gin.Context) {
 		paramsBind.AdminCanDeleteAllMsg,
 		paramsBind.IsAutoComputeTags,
 		paramsBind.IsAutoComputeLabels,
-		paramsBind.Parameters)
+		paramsBind.Parameters,
+		paramsBind.IndexedFields)
 
 	// add tat2xmpp_username RO or RW on this topic if a key is xmpp
 	for _, p := range paramsBind.Parameters {
@@ -875,6 +1195,7 @@ func (t *TopicsController) SetParam(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	auditTopic(ctx, tat.AuditActionSetParam, topic.Topic, paramsBind.Recursive, topicParams(topic), paramsBind)
 	ctx.JSON(http.StatusCreated, gin.H{"info": fmt.Sprintf("Topic %s updated", topic.Topic)})
 }
 
@@ -900,6 +1221,185 @@ func (t *TopicsController) AllComputeLabels(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{"info": info})
 }
 
+// SetWorkflow sets workflow of labels on a topic: states and allowed transitions
+// between them. A null workflow removes it. Admin only
+func (t *TopicsController) SetWorkflow(ctx *gin.Context) {
+	var in tat.TopicWorkflowJSON
+	ctx.Bind(&in)
+
+	topic, e := t.preCheckUserAdminOnTopic(ctx, in.Topic)
+	if e != nil {
+		ctx.JSON(tat.Error(e))
+		return
+	}
+
+	info := fmt.Sprintf("workflow removed from topic %s", topic.Topic)
+	if in.Workflow != nil {
+		if err := in.Workflow.Check(); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		for _, tr := range in.Workflow.Transitions {
+			for _, g := range tr.Groups {
+				if !groupDB.IsGroupnameExists(g) {
+					ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Group %s does not exist", g)})
+					return
+				}
+			}
+		}
+		info = fmt.Sprintf("workflow set on topic %s", topic.Topic)
+	}
+
+	old := topic.Workflow
+	if err := topicDB.SetWorkflow(topic, getCtxUsername(ctx), in.Workflow); err != nil {
+		log.Errorf("Error while setting workflow on topic %s: %s", topic.Topic, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error while setting workflow on topic"})
+		return
+	}
+	auditTopic(ctx, tat.AuditActionSetWorkflow, topic.Topic, false, old, in.Workflow)
+	ctx.JSON(http.StatusCreated, gin.H{"info": info})
+}
+
+// SetArchived archives or unarchives a topic, admin on topic.
+// Only tat admin can archive sub-topics with recursive
+func (t *TopicsController) SetArchived(ctx *gin.Context) {
+	var in tat.TopicArchiveJSON
+	ctx.Bind(&in)
+
+	topic, e := t.preCheckUserAdminOnTopic(ctx, in.Topic)
+	if e != nil {
+		ctx.JSON(tat.Error(e))
+		return
+	}
+
+	if in.Recursive && !isTatAdmin(ctx) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Only tat admin can archive a topic recursively"})
+		return
+	}
+
+	if err := topicDB.SetArchived(topic, getCtxUsername(ctx), in.IsArchived, in.Recursive); err != nil {
+		log.Errorf("Error while archiving topic %s: %s", topic.Topic, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error while archiving topic"})
+		return
+	}
+
+	info := fmt.Sprintf("topic %s archived", topic.Topic)
+	action := tat.AuditActionArchive
+	if !in.IsArchived {
+		info = fmt.Sprintf("topic %s unarchived", topic.Topic)
+		action = tat.AuditActionUnarchive
+	}
+	auditTopic(ctx, action, topic.Topic, in.Recursive, nil, nil)
+	ctx.JSON(http.StatusCreated, gin.H{"info": info})
+}
+
+// Rename renames a topic, with its sub-topics if recursive
+func (t *TopicsController) Rename(ctx *gin.Context) {
+	var in tat.TopicRenameJSON
+	ctx.Bind(&in)
+
+	topic, e := t.preCheckUserAdminOnTopic(ctx, in.Topic)
+	if e != nil {
+		ctx.JSON(tat.Error(e))
+		return
+	}
+
+	user, e := PreCheckUser(ctx)
+	if e != nil {
+		return
+	}
+
+	newName, topics, err := topicDB.CheckRename(topic, in.NewTopic, in.Recursive, &user)
+	if err != nil {
+		ctx.JSON(tat.Error(err))
+		return
+	}
+
+	// sub-topics first, and for each topic, its name is changed last: if renaming
+	// fails, same request renames topics not yet renamed
+	oldName := topic.Topic
+	for i := len(topics) - 1; i >= 0; i-- {
+		tr := topics[i]
+		name := tr.Topic
+		renamed := tr
+		renamed.Topic = newName + strings.TrimPrefix(name, oldName)
+		if err := messageDB.ChangeTopicOnMessages(renamed, name); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Error while renaming topic %s on messages", name)})
+			return
+		}
+		if err := userDB.ChangeTopicOnUsers(name, renamed.Topic); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Error while renaming topic %s on users", name)})
+			return
+		}
+		presenceDB.ChangeTopicOnPresences(name, renamed.Topic)
+		auditDB.ChangeTopicOnEvents(name, renamed.Topic)
+		if err := topicDB.Rename(&tr, user.Username, renamed.Topic); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		auditTopic(ctx, tat.AuditActionRename, tr.Topic, false, name, tr.Topic)
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"info": fmt.Sprintf("topic %s renamed to %s, %d topics renamed", oldName, newName, len(topics))})
+}
+
+// Clone creates a new topic with settings of a topic, with its sub-topics if
+// recursive, only if user is Tat admin, or admin on topic. Messages are not copied
+func (t *TopicsController) Clone(ctx *gin.Context) {
+	var in tat.TopicCloneJSON
+	ctx.Bind(&in)
+
+	topic, e := t.preCheckUserAdminOnTopic(ctx, in.Topic)
+	if e != nil {
+		ctx.JSON(tat.Error(e))
+		return
+	}
+
+	user, e := PreCheckUser(ctx)
+	if e != nil {
+		return
+	}
+
+	newName, topics, err := topicDB.CheckClone(topic, in.NewTopic, in.Recursive, &user)
+	if err != nil {
+		ctx.JSON(tat.Error(err))
+		return
+	}
+
+	// on failure, topics already created are removed, sub-topics first
+	clones := []*tat.Topic{}
+	rollback := func() {
+		for i := len(clones) - 1; i >= 0; i-- {
+			if err := topicDB.Delete(clones[i], &user); err != nil {
+				log.Errorf("Error while removing topic %s after a failed clone: %s", clones[i].Topic, err)
+			}
+		}
+	}
+
+	for _, source := range topics {
+		name := newName + strings.TrimPrefix(source.Topic, topic.Topic)
+		clone := &tat.Topic{Topic: name, Description: source.Description}
+		if err := topicDB.Insert(clone, &user); err != nil {
+			log.Errorf("Error while inserting topic %s, clone of %s: %s", name, source.Topic, err)
+			rollback()
+			ctx.JSON(tat.Error(err))
+			return
+		}
+		clones = append(clones, clone)
+		if err := topicDB.Clone(&source, clone, user.Username, in); err != nil {
+			rollback()
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	for i, clone := range clones {
+		auditTopic(ctx, tat.AuditActionClone, clone.Topic, false, nil, topics[i].Topic)
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"info": fmt.Sprintf("topic %s cloned to %s, %d topics created", topic.Topic, newName, len(topics))})
+}
+
 // AllSetParam set a param on all topics
 func (t *TopicsController) AllSetParam(ctx *gin.Context) {
 	// It's only for admin, admin already checked in route
@@ -911,6 +1411,8 @@ func (t *TopicsController) AllSetParam(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	// target is empty: param is set on all topics
+	auditTopic(ctx, tat.AuditActionSetParam, "", false, nil, param)
 	ctx.JSON(http.StatusOK, gin.H{"info": info})
 }
 
@@ -946,6 +1448,7 @@ func (t *TopicsController) MigrateToDedicatedTopic(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": errMigrate.Error()})
 		return
 	}
+	auditTopic(ctx, tat.AuditActionMigrateDedicated, topic.Topic, false, nil, topic.Collection)
 
 	ctx.JSON(http.StatusOK, gin.H{"info": fmt.Sprintf("%s is now dedicated", topicRequest)})
 }
@@ -977,6 +1480,9 @@ func (t *TopicsController) MigrateMessagesForDedicatedTopic(ctx *gin.Context) {
 	}
 
 	nMigrate, err := messageDB.MigrateMessagesToDedicatedTopic(topic, limit)
+	if nMigrate > 0 {
+		auditTopic(ctx, tat.AuditActionMigrateMessages, topic.Topic, false, nil, nMigrate)
+	}
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Error after %d migrate, err:%s", nMigrate, err.Error())})
 		return
@@ -49,6 +49,19 @@ func TestTopicCreateListAndDelete(t *testing.T) {
 
 }
 
+func TestTopicReservedName(t *testing.T) {
+	tests.Init(t)
+	router := tests.Router(t)
+	client := tests.TATClient(t, tests.AdminUser)
+
+	initRoutesTopics(router, tests.FakeAuthHandler(t, tests.AdminUser, "X-TAT-TEST", true, false))
+
+	for _, name := range []string{"/aggregate", "/aggregate/" + tests.RandomString(t, 10), "/export/" + tests.RandomString(t, 10), "/permissions/" + tests.RandomString(t, 10), "/history/" + tests.RandomString(t, 10)} {
+		_, err := client.TopicCreate(tat.TopicCreateJSON{Topic: name, Description: "this is a test"})
+		assert.Error(t, err, "topic %s should not be created", name)
+	}
+}
+
 func TestTruncateAndDeleteAllTopics(t *testing.T) {
 	tests.Init(t)
 	router := tests.Router(t)
@@ -199,3 +212,219 @@ func TestTopicsFilterManage(t *testing.T) {
 	_, err = client.TopicDelete(tat.TopicNameJSON{Topic: created.Topic.Topic})
 	assert.NoError(t, err)
 }
+
+func TestTopicRename(t *testing.T) {
+	tests.Init(t)
+	router := tests.Router(t)
+	client := tests.TATClient(t, tests.AdminUser)
+
+	initRoutesMessages(router, tests.FakeAuthHandler(t, tests.AdminUser, "X-TAT-TEST", true, false))
+	initRoutesTopics(router, tests.FakeAuthHandler(t, tests.AdminUser, "X-TAT-TEST", true, false))
+	initRoutesUsers(router, tests.FakeAuthHandler(t, tests.AdminUser, "X-TAT-TEST", true, false))
+
+	name := "/" + tests.RandomString(t, 10)
+	topic, err := client.TopicCreate(tat.TopicCreateJSON{Topic: name, Description: "this is a test"})
+	assert.NoError(t, err)
+	_, err = client.TopicCreate(tat.TopicCreateJSON{Topic: name + "/sub", Description: "this is a test"})
+	assert.NoError(t, err)
+	_, err = client.MessageAdd(tat.MessageJSON{Topic: name + "/sub", Text: "a message"})
+	assert.NoError(t, err)
+	_, err = client.TopicAddFilter(tat.Filter{Topic: name + "/sub", Title: "myFilter", Criteria: tat.FilterCriteria{Label: "fooLabel"}})
+	assert.NoError(t, err)
+
+	newName := "/" + tests.RandomString(t, 10)
+	_, err = client.TopicRename(tat.TopicRenameJSON{Topic: name, NewTopic: newName})
+	assert.Error(t, err, "topic has a sub-topic")
+
+	_, err = client.TopicRename(tat.TopicRenameJSON{Topic: name, NewTopic: newName, Recursive: true})
+	assert.NoError(t, err)
+
+	out, err := client.TopicOne(newName)
+	assert.NoError(t, err)
+	assert.Equal(t, topic.ID, out.Topic.ID)
+
+	msgs, err := client.MessageList(newName+"/sub", &tat.MessageCriteria{Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, msgs.Messages, 1)
+	if len(msgs.Messages) == 1 {
+		assert.Equal(t, newName+"/sub", msgs.Messages[0].Topic)
+	}
+
+	_, err = client.TopicOne(name)
+	assert.Error(t, err)
+
+	tasks := "/Private/" + tests.AdminUser + "/Tasks"
+	client.TopicCreate(tat.TopicCreateJSON{Topic: tasks, Description: "this is a test"})
+	_, err = client.TopicRename(tat.TopicRenameJSON{Topic: tasks, NewTopic: "/Private/" + tests.AdminUser + "/" + tests.RandomString(t, 10)})
+	assert.Error(t, err, "Tasks topic is used by its name")
+}
+
+func TestTopicArchived(t *testing.T) {
+	tests.Init(t)
+	router := tests.Router(t)
+	client := tests.TATClient(t, tests.AdminUser)
+
+	initRoutesMessages(router, tests.FakeAuthHandler(t, tests.AdminUser, "X-TAT-TEST", true, false))
+	initRoutesTopics(router, tests.FakeAuthHandler(t, tests.AdminUser, "X-TAT-TEST", true, false))
+
+	name := "/" + tests.RandomString(t, 10)
+	_, err := client.TopicCreate(tat.TopicCreateJSON{Topic: name, Description: "this is a test"})
+	assert.NoError(t, err)
+	defer client.TopicDelete(tat.TopicNameJSON{Topic: name})
+	defer client.TopicTruncate(tat.TopicNameJSON{Topic: name})
+	defer client.TopicArchive(name, false, false)
+
+	_, err = client.TopicParameter(tat.TopicParameters{Topic: name, CanUpdateMsg: true, CanDeleteMsg: true})
+	assert.NoError(t, err)
+
+	message, err := client.MessageAdd(tat.MessageJSON{Topic: name, Text: "a message"})
+	assert.NoError(t, err)
+	if message == nil {
+		t.Fail()
+		return
+	}
+	deleted, err := client.MessageAdd(tat.MessageJSON{Topic: name, Text: "a deleted message"})
+	assert.NoError(t, err)
+	if deleted == nil {
+		t.Fail()
+		return
+	}
+	_, err = client.MessageDelete(deleted.Message.ID, name, false, false)
+	assert.NoError(t, err)
+
+	_, err = client.TopicArchive(name, true, false)
+	assert.NoError(t, err)
+
+	_, err = client.MessageAdd(tat.MessageJSON{Topic: name, Text: "a new message"})
+	assertArchivedError(t, err, "create")
+	_, err = client.MessageUpdate(name, message.Message.ID, "an update")
+	assertArchivedError(t, err, "update")
+	_, err = client.MessageReply(name, message.Message.ID, "a reply")
+	assertArchivedError(t, err, "reply")
+	_, err = client.MessageDelete(message.Message.ID, name, false, false)
+	assertArchivedError(t, err, "delete")
+	_, err = client.MessageUndelete(name, deleted.Message.ID)
+	assertArchivedError(t, err, "undelete")
+
+	msgs, err := client.MessageList(name, &tat.MessageCriteria{Limit: 10})
+	assert.NoError(t, err, "messages of an archived topic can be read")
+	assert.Len(t, msgs.Messages, 1)
+
+	topics, err := client.TopicList(&tat.TopicCriteria{Topic: name, Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, topics.Count)
+
+	topics, err = client.TopicList(&tat.TopicCriteria{Topic: name, Limit: 10, HideArchived: tat.True})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, topics.Count, "archived topic should be hidden")
+}
+
+func assertArchivedError(t *testing.T, err error, action string) {
+	assert.Error(t, err, "%s should be refused on an archived topic", action)
+	if err != nil {
+		assert.Contains(t, err.Error(), "archived", "%s should be refused on an archived topic", action)
+	}
+}
+
+func TestTopicPermissions(t *testing.T) {
+	tests.Init(t)
+	router := tests.Router(t)
+	client := tests.TATClient(t, tests.AdminUser)
+
+	initRoutesTopics(router, tests.FakeAuthHandler(t, tests.AdminUser, "X-TAT-TEST", true, false))
+	initRoutesUsers(router, tests.FakeAuthHandler(t, tests.AdminUser, "X-TAT-TEST", true, false))
+
+	name := "/" + tests.RandomString(t, 10)
+	_, err := client.TopicCreate(tat.TopicCreateJSON{Topic: name, Description: "this is a test"})
+	assert.NoError(t, err)
+	_, err = client.TopicCreate(tat.TopicCreateJSON{Topic: name + "/sub", Description: "this is a test"})
+	assert.NoError(t, err)
+
+	out, err := client.TopicPermissions(name+"/sub", "")
+	assert.NoError(t, err)
+	assert.Equal(t, name+"/sub", out.Topic)
+	assert.True(t, out.Read)
+	assert.True(t, out.Write)
+	assert.True(t, out.Admin)
+	assert.Contains(t, out.Grants, tat.TopicGrant{Right: tat.TopicRightWrite, Source: tat.TopicGrantUser, Field: "rwUsers", Name: tests.AdminUser, SameOnAncestor: name})
+	assert.Contains(t, out.Grants, tat.TopicGrant{Right: tat.TopicRightAdmin, Source: tat.TopicGrantTatAdmin})
+
+	_, err = client.TopicPermissions(name, tests.RandomString(t, 10))
+	assert.Error(t, err, "user does not exist")
+}
+
+func TestTopicClone(t *testing.T) {
+	tests.Init(t)
+	router := tests.Router(t)
+	client := tests.TATClient(t, tests.AdminUser)
+
+	initRoutesMessages(router, tests.FakeAuthHandler(t, tests.AdminUser, "X-TAT-TEST", true, false))
+	initRoutesTopics(router, tests.FakeAuthHandler(t, tests.AdminUser, "X-TAT-TEST", true, false))
+	initRoutesUsers(router, tests.FakeAuthHandler(t, tests.AdminUser, "X-TAT-TEST", true, false))
+
+	name := "/" + tests.RandomString(t, 10)
+	_, err := client.TopicCreate(tat.TopicCreateJSON{Topic: name, Description: "this is a test"})
+	assert.NoError(t, err)
+	_, err = client.TopicCreate(tat.TopicCreateJSON{Topic: name + "/sub", Description: "this is a sub-topic"})
+	assert.NoError(t, err)
+	_, err = client.TopicAddParameter(name+"/sub", "hook", "http://localhost", false)
+	assert.NoError(t, err)
+	_, err = client.MessageAdd(tat.MessageJSON{Topic: name + "/sub", Text: "a message"})
+	assert.NoError(t, err)
+	_, err = client.TopicAddFilter(tat.Filter{Topic: name + "/sub", Title: "myFilter", Criteria: tat.FilterCriteria{Label: "fooLabel"}})
+	assert.NoError(t, err)
+
+	newName := "/" + tests.RandomString(t, 10)
+	_, err = client.TopicClone(tat.TopicCloneJSON{Topic: name, NewTopic: name + "/sub/clone", Recursive: true})
+	assert.Error(t, err, "topic can't be cloned to one of its sub-topics")
+
+	_, err = client.TopicClone(tat.TopicCloneJSON{Topic: name, NewTopic: newName, Recursive: true, ACL: true, Parameters: true, Filters: true})
+	assert.NoError(t, err)
+
+	out, err := client.TopicOne(newName + "/sub")
+	assert.NoError(t, err)
+	assert.Equal(t, "this is a sub-topic", out.Topic.Description)
+	assert.Contains(t, out.Topic.RWUsers, tests.AdminUser)
+	assert.Equal(t, []tat.TopicParameter{{Key: "hook", Value: "http://localhost"}}, out.Topic.Parameters)
+	if assert.Len(t, out.Topic.Filters, 1) {
+		assert.Equal(t, "myFilter", out.Topic.Filters[0].Title)
+		assert.Equal(t, tests.AdminUser, out.Topic.Filters[0].Username)
+	}
+
+	msgs, err := client.MessageList(newName+"/sub", &tat.MessageCriteria{Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, msgs.Messages, 0)
+
+	_, err = client.TopicClone(tat.TopicCloneJSON{Topic: name, NewTopic: newName})
+	assert.Error(t, err, "topic already exists")
+}
+
+func TestTopicHistory(t *testing.T) {
+	tests.Init(t)
+	router := tests.Router(t)
+	client := tests.TATClient(t, tests.AdminUser)
+
+	initRoutesAudit(router, tests.FakeAuthHandler(t, tests.AdminUser, "X-TAT-TEST", true, false))
+	initRoutesTopics(router, tests.FakeAuthHandler(t, tests.AdminUser, "X-TAT-TEST", true, false))
+	initRoutesUsers(router, tests.FakeAuthHandler(t, tests.AdminUser, "X-TAT-TEST", true, false))
+
+	name := "/" + tests.RandomString(t, 10)
+	_, err := client.TopicCreate(tat.TopicCreateJSON{Topic: name, Description: "this is a test"})
+	assert.NoError(t, err)
+	_, err = client.TopicAddParameter(name, "key", "value", false)
+	assert.NoError(t, err)
+
+	out, err := client.TopicHistory(name, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, out.Count)
+	if len(out.Events) == 2 {
+		assert.Equal(t, tat.AuditActionAddParameter, out.Events[0].Action)
+		assert.Equal(t, tat.AuditActionCreate, out.Events[1].Action)
+		assert.Equal(t, tests.AdminUser, out.Events[0].Actor)
+		assert.Equal(t, "X-TAT-TEST", out.Events[0].Referer)
+	}
+
+	all, err := client.AuditList(&tat.AuditCriteria{Limit: 10, Actor: tests.AdminUser, TargetType: tat.AuditTargetTopic, Target: name, Action: tat.AuditActionCreate})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, all.Count)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ovh/tat"
+	"github.com/ovh/tat/api/hook"
+	messageDB "github.com/ovh/tat/api/message"
+	topicDB "github.com/ovh/tat/api/topic"
+)
+
+// preCheckTrash returns topic if user is Tat admin or admin on topic
+func (m *MessagesController) preCheckTrash(ctx *gin.Context) (*tat.Topic, int, error) {
+	topicIn, err := GetParam(ctx, "topic")
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	user, err := PreCheckUser(ctx)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	topic, err := topicDB.FindByTopic(topicIn, true, false, false, &user)
+	if err != nil {
+		return nil, http.StatusNotFound, fmt.Errorf("Topic '%s' does not exist", topicIn)
+	}
+
+	if !topicDB.IsUserAdmin(topic, &user) {
+		return nil, http.StatusForbidden, fmt.Errorf("Only admins of topic %s can access its trash", topic.Topic)
+	}
+	return topic, http.StatusOK, nil
+}
+
+// ListTrash returns messages deleted from a topic, only for Tat admin and admins on topic
+func (m *MessagesController) ListTrash(ctx *gin.Context) {
+	topic, code, err := m.preCheckTrash(ctx)
+	if err != nil {
+		ctx.JSON(code, gin.H{"error": err.Error()})
+		return
+	}
+
+	skip, _ := strconv.Atoi(ctx.DefaultQuery("skip", "0"))
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "100"))
+	if limit <= 0 || limit > 1000 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Please put a limit between 1 and 1000"})
+		return
+	}
+
+	messages, err := messageDB.ListTrash(*topic, skip, limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error while listing trash"})
+		return
+	}
+	ctx.JSON(http.StatusOK, &tat.TrashedMessagesJSON{Messages: messages})
+}
+
+// Undelete restores a message from trash, with replies deleted with it,
+// only for Tat admin and admins on topic
+func (m *MessagesController) Undelete(ctx *gin.Context) {
+	idMessageIn, err := GetParam(ctx, "idMessage")
+	if err != nil {
+		return
+	}
+
+	topic, code, err := m.preCheckTrash(ctx)
+	if err != nil {
+		ctx.JSON(code, gin.H{"error": err.Error()})
+		return
+	}
+	if err := checkNotArchived(*topic); err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	messages, err := messageDB.Undelete(idMessageIn, *topic)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, message := range messages {
+		hook.SendHook(&tat.HookJSON{HookMessage: &tat.HookMessageJSON{MessageJSONOut: &tat.MessageJSONOut{Message: message}, Action: tat.MessageActionUndelete}}, *topic)
+	}
+	ctx.JSON(http.StatusOK, gin.H{"info": fmt.Sprintf("%d messages restored in %s", len(messages), topic.Topic)})
+}
@@ -775,6 +775,31 @@ func Rename(user *tat.User, newUsername string) error {
 	return nil
 }
 
+// ChangeTopicOnUsers sets new name of a renamed topic in favorites topics
+// and off notifications topics of users
+func ChangeTopicOnUsers(oldName, newName string) error {
+	var users []tat.User
+	if err := store.Tat().CUsers.Find(bson.M{"$or": []bson.M{{"favoritesTopics": oldName}, {"offNotificationsTopics": oldName}}}).
+		Select(bson.M{"username": 1}).
+		All(&users); err != nil {
+		log.Errorf("Error while getting users with topic %s: %s", oldName, err)
+		return err
+	}
+
+	for _, set := range []string{"favoritesTopics", "offNotificationsTopics"} {
+		if _, err := store.Tat().CUsers.UpdateAll(
+			bson.M{set: oldName},
+			bson.M{"$set": bson.M{set + ".$": newName}}); err != nil {
+			log.Errorf("Error while update topic from %s to %s on users (%s): %s", oldName, newName, set, err)
+			return err
+		}
+	}
+	for _, u := range users {
+		cache.CleanUsernames(u.Username)
+	}
+	return nil
+}
+
 // Update changes fullname and email of user
 func Update(user *tat.User, newFullname, newEmail string) error {
 
@@ -119,6 +119,7 @@ func (u *UsersController) Create(ctx *gin.Context) {
 	}
 
 	go userDB.SendVerifyEmail(userIn.Username, userIn.Email, tokenVerify, callback)
+	auditUser(ctx, tat.AuditActionCreate, userIn.Username, nil, gin.H{"fullname": userIn.Fullname, "email": userIn.Email})
 
 	info := ""
 	if viper.GetBool("username_from_email") {
@@ -170,6 +171,7 @@ func (u *UsersController) Verify(ctx *gin.Context) {
 			log.Errorf("%s %s", e, err.Error())
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": e})
 		} else {
+			auditUser(ctx, tat.AuditActionVerify, username, nil, nil)
 			ctx.JSON(http.StatusOK, gin.H{
 				"message":  "Verification successful",
 				"username": username,
@@ -235,6 +237,133 @@ func (*UsersController) Me(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, out)
 }
 
+// readableTopics returns all topics readable by user
+func readableTopics(user *tat.User) ([]tat.Topic, error) {
+	_, topics, err := topicDB.ListTopics(&tat.TopicCriteria{}, user, false, false, false)
+	return topics, err
+}
+
+// Mentions returns messages mentioning current user, on all topics readable by user
+func (*UsersController) Mentions(ctx *gin.Context) {
+	user, err := PreCheckUser(ctx)
+	if err != nil {
+		return
+	}
+
+	criteria := (&MessagesController{}).buildCriteria(ctx)
+	if criteria.Limit > 1000 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Please put a limit <= 1000 for fetching mentions"})
+		return
+	}
+	// skip is applied after merging messages of all topics, skip+limit messages are read
+	if criteria.Skip < 0 || criteria.Skip > 1000 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Please put a skip <= 1000 for fetching mentions, or use dateMaxCreation"})
+		return
+	}
+	// sort and topics are fixed on mentions
+	criteria.Topic = ""
+	criteria.Pinned = ""
+	criteria.Cursor = ""
+
+	topics, err := readableTopics(&user)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error while fetching topics"})
+		return
+	}
+
+	messages, nbUnread, err := messageDB.ListMentions(criteria, user.Username, topics, ctx.Query("onlyUnread") == tat.True)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error while fetching mentions"})
+		return
+	}
+
+	out := &tat.MentionsJSON{Messages: messages, NbUnread: nbUnread}
+	for _, m := range messages {
+		if !m.IsMentionRead(user.Username) {
+			out.Unread = append(out.Unread, m.ID)
+		}
+	}
+	ctx.JSON(http.StatusOK, out)
+}
+
+// Tasks returns messages assigned to current user, directly or through a group, on all
+// topics readable by user, ordered by due date. Filter on states with state=todo,doing
+func (*UsersController) Tasks(ctx *gin.Context) {
+	user, err := PreCheckUser(ctx)
+	if err != nil {
+		return
+	}
+
+	criteria := (&MessagesController{}).buildCriteria(ctx)
+	if criteria.Limit > 1000 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Please put a limit <= 1000 for fetching tasks"})
+		return
+	}
+	// skip is applied after merging messages of all topics, skip+limit messages are read
+	if criteria.Skip < 0 || criteria.Skip > 1000 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Please put a skip <= 1000 for fetching tasks"})
+		return
+	}
+	// sort and topics are fixed on tasks
+	criteria.Topic = ""
+	criteria.Pinned = ""
+	criteria.Cursor = ""
+
+	states := []string{tat.TaskStateTodo, tat.TaskStateDoing, tat.TaskStateBlocked}
+	if ctx.Query("state") != "" {
+		states = strings.Split(ctx.Query("state"), ",")
+		for _, s := range states {
+			if err := tat.CheckTaskState(s); err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+	}
+
+	groups, err := groupDB.GetUserGroupsOnlyName(user.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error while fetching groups"})
+		return
+	}
+
+	topics, err := readableTopics(&user)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error while fetching topics"})
+		return
+	}
+
+	messages, err := messageDB.ListTasks(criteria, user.Username, groups, topics, states)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error while fetching tasks"})
+		return
+	}
+	ctx.JSON(http.StatusOK, &tat.TasksJSON{Messages: messages})
+}
+
+// MarkMentionsRead marks mentions of current user as read, all mentions if no idMessages given
+func (*UsersController) MarkMentionsRead(ctx *gin.Context) {
+	var in tat.MentionsReadJSON
+	ctx.Bind(&in)
+
+	user, err := PreCheckUser(ctx)
+	if err != nil {
+		return
+	}
+
+	topics, err := readableTopics(&user)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error while fetching topics"})
+		return
+	}
+
+	nb, err := messageDB.MarkMentionsRead(user.Username, topics, in.IDMessages)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error while marking mentions as read"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"info": fmt.Sprintf("%d mentions marked as read", nb)})
+}
+
 // Contacts retrieves contacts presences since n seconds
 func (*UsersController) Contacts(ctx *gin.Context) {
 	sinceSeconds, err := GetParam(ctx, "sinceSeconds")
@@ -500,6 +629,7 @@ func (*UsersController) Convert(ctx *gin.Context) {
 		AbortWithReturnError(ctx, http.StatusBadRequest, fmt.Errorf("Convert %s to system user failed", convertJSON.Username))
 		return
 	}
+	auditUser(ctx, tat.AuditActionConvert, userToConvert.Username, nil, gin.H{"canListUsersAsAdmin": convertJSON.CanListUsersAsAdmin})
 
 	ctx.JSON(http.StatusOK, gin.H{
 		"message":  "Verification successful",
@@ -538,11 +668,13 @@ func (*UsersController) UpdateSystemUser(ctx *gin.Context) {
 		return
 	}
 
+	old := gin.H{"canListUsersAsAdmin": userToConvert.CanListUsersAsAdmin}
 	err2 := userDB.UpdateSystemUser(&userToConvert, convertJSON.CanListUsersAsAdmin)
 	if err2 != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Error while update system user %s", convertJSON.Username)})
 		return
 	}
+	auditUser(ctx, tat.AuditActionUpdate, userToConvert.Username, old, gin.H{"canListUsersAsAdmin": convertJSON.CanListUsersAsAdmin})
 
 	ctx.JSON(http.StatusCreated, gin.H{"message": "Update successful"})
 }
@@ -577,6 +709,7 @@ func (*UsersController) ResetSystemUser(ctx *gin.Context) {
 		AbortWithReturnError(ctx, http.StatusBadRequest, fmt.Errorf("Reset password for %s (system user) failed", systemUserJSON.Username))
 		return
 	}
+	auditUser(ctx, tat.AuditActionReset, systemUserToReset.Username, nil, nil)
 
 	ctx.JSON(http.StatusOK, gin.H{
 		"message":  "Reset password successful",
@@ -610,6 +743,7 @@ func (*UsersController) SetAdmin(ctx *gin.Context) {
 		AbortWithReturnError(ctx, http.StatusBadRequest, fmt.Errorf("Convert %s to admin user failed", convertJSON.Username))
 		return
 	}
+	auditUser(ctx, tat.AuditActionSetAdmin, userToGrant.Username, false, true)
 
 	ctx.JSON(http.StatusCreated, "")
 }
@@ -638,6 +772,7 @@ func (*UsersController) Archive(ctx *gin.Context) {
 		AbortWithReturnError(ctx, http.StatusBadRequest, fmt.Errorf("archive user %s failed", archiveJSON.Username))
 		return
 	}
+	auditUser(ctx, tat.AuditActionArchive, userToArchive.Username, nil, nil)
 
 	ctx.JSON(http.StatusCreated, "")
 }
@@ -666,6 +801,7 @@ func (*UsersController) Rename(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Errorf("Rename %s user to %s failed", renameJSON.Username, renameJSON.NewUsername)})
 		return
 	}
+	auditUser(ctx, tat.AuditActionRename, renameJSON.NewUsername, renameJSON.Username, renameJSON.NewUsername)
 	ctx.JSON(http.StatusCreated, gin.H{"info": "user is renamed"})
 }
 
@@ -689,11 +825,13 @@ func (*UsersController) Update(ctx *gin.Context) {
 		return
 	}
 
+	old := gin.H{"fullname": userToUpdate.Fullname, "email": userToUpdate.Email}
 	err2 := userDB.Update(&userToUpdate, strings.TrimSpace(updateJSON.NewFullname), strings.TrimSpace(updateJSON.NewEmail))
 	if err2 != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Update %s user to fullname %s and email %s failed : %s", updateJSON.Username, updateJSON.NewFullname, updateJSON.NewEmail, err2.Error())})
 		return
 	}
+	auditUser(ctx, tat.AuditActionUpdate, userToUpdate.Username, old, gin.H{"fullname": strings.TrimSpace(updateJSON.NewFullname), "email": strings.TrimSpace(updateJSON.NewEmail)})
 
 	ctx.JSON(http.StatusCreated, gin.H{"info": "user updated"})
 }
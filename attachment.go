@@ -0,0 +1,97 @@
+package tat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"path/filepath"
+)
+
+// MessageAddWithAttachments posts a tat message with files as attachments.
+// Size of all attachments on a topic is limited by attachmentsQuota of topic
+//
+//	msg := tat.MessageJSON{Text: "build #42 failed", Topic: "/Internal/YourTopic"}
+//	out, err := getClient().MessageAddWithAttachments(msg, "build.log")
+func (c *Client) MessageAddWithAttachments(message MessageJSON, files ...string) (*MessageJSONOut, error) {
+	if c == nil {
+		return nil, ErrClientNotInitiliazed
+	}
+
+	if message.Topic == "" {
+		return nil, fmt.Errorf("A message must have a Topic")
+	}
+
+	m, err := json.Marshal(message)
+	if err != nil {
+		ErrorLogFunc("Error while marshal message: %s", err)
+		return nil, err
+	}
+
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	if err := w.WriteField("message", string(m)); err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if err := addAttachmentPart(w, f); err != nil {
+			ErrorLogFunc("Error while reading attachment %s: %s", f, err)
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	out, err := c.reqWantContentType("POST", 201, "/message"+message.Topic, w.FormDataContentType(), body.Bytes())
+	if err != nil {
+		ErrorLogFunc("Error while posting message with attachments: %s", err)
+		return nil, err
+	}
+
+	msg := &MessageJSONOut{}
+	if err := json.Unmarshal(out, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func addAttachmentPart(w *multipart.Writer, file string) error {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	contentType := mime.TypeByExtension(filepath.Ext(file))
+	if contentType == "" {
+		contentType = http.DetectContentType(content)
+	}
+
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="attachments"; filename=%q`, filepath.Base(file)))
+	h.Set("Content-Type", contentType)
+	part, err := w.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	_, err = part.Write(content)
+	return err
+}
+
+// MessageAttachment returns content of an attachment of a message
+func (c *Client) MessageAttachment(topic, idMessage, idAttachment string) ([]byte, error) {
+	if c == nil {
+		return nil, ErrClientNotInitiliazed
+	}
+
+	out, err := c.simpleGetAndGetBytes(fmt.Sprintf("/message/%s/attachments/%s%s", idMessage, idAttachment, topic))
+	if err != nil {
+		ErrorLogFunc("Error getting attachment %s of message %s: %s", idAttachment, idMessage, err)
+		return nil, err
+	}
+	return out, nil
+}
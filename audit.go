@@ -0,0 +1,154 @@
+package tat
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+)
+
+// Types of targets of audit events
+const (
+	AuditTargetTopic = "topic"
+	AuditTargetGroup = "group"
+	AuditTargetUser  = "user"
+)
+
+// Actions of audit events
+const (
+	AuditActionCreate           = "create"
+	AuditActionUpdate           = "update"
+	AuditActionDelete           = "delete"
+	AuditActionRename           = "rename"
+	AuditActionTruncate         = "truncate"
+	AuditActionTruncateTags     = "truncateTags"
+	AuditActionTruncateLabels   = "truncateLabels"
+	AuditActionMigrateDedicated = "migrateToDedicatedTopic"
+	AuditActionMigrateMessages  = "migrateMessages"
+	AuditActionImport           = "import"
+	AuditActionClone            = "clone"
+	AuditActionArchive          = "archive"
+	AuditActionUnarchive        = "unarchive"
+	AuditActionSetParam         = "setParam"
+	AuditActionSetWorkflow      = "setWorkflow"
+	AuditActionAddParameter     = "addParameter"
+	AuditActionRemoveParameter  = "removeParameter"
+	AuditActionAddFilter        = "addFilter"
+	AuditActionUpdateFilter     = "updateFilter"
+	AuditActionRemoveFilter     = "removeFilter"
+	AuditActionAddRoUser        = "addRoUser"
+	AuditActionRemoveRoUser     = "removeRoUser"
+	AuditActionAddRwUser        = "addRwUser"
+	AuditActionRemoveRwUser     = "removeRwUser"
+	AuditActionAddAdminUser     = "addAdminUser"
+	AuditActionRemoveAdminUser  = "removeAdminUser"
+	AuditActionAddRoGroup       = "addRoGroup"
+	AuditActionRemoveRoGroup    = "removeRoGroup"
+	AuditActionAddRwGroup       = "addRwGroup"
+	AuditActionRemoveRwGroup    = "removeRwGroup"
+	AuditActionAddAdminGroup    = "addAdminGroup"
+	AuditActionRemoveAdminGroup = "removeAdminGroup"
+	AuditActionPin              = "pin"
+	AuditActionUnpin            = "unpin"
+	AuditActionAddUser          = "addUser"
+	AuditActionRemoveUser       = "removeUser"
+	AuditActionVerify           = "verify"
+	AuditActionReset            = "reset"
+	AuditActionConvert          = "convert"
+	AuditActionSetAdmin         = "setAdmin"
+	AuditActionHistory          = "history" // entry of history of a topic or a group, before audit events
+)
+
+// AuditEvent is a change made by a user on a topic, a group or a user
+type AuditEvent struct {
+	ID         string      `bson:"_id"        json:"_id"`
+	Actor      string      `bson:"actor"      json:"actor"`
+	Referer    string      `bson:"referer"    json:"referer,omitempty"` // X-Tat-Referer of request
+	Action     string      `bson:"action"     json:"action"`
+	TargetType string      `bson:"targetType" json:"targetType"`
+	Target     string      `bson:"target"     json:"target"`
+	Recursive  bool        `bson:"recursive"  json:"recursive,omitempty"` // change made on sub-topics too
+	Old        interface{} `bson:"old"        json:"old,omitempty"`
+	New        interface{} `bson:"new"        json:"new,omitempty"`
+	Date       float64     `bson:"date"       json:"date"`
+}
+
+// AuditCriteria is used to list audit events, last first
+type AuditCriteria struct {
+	Skip       int
+	Limit      int
+	Actor      string
+	Action     string
+	TargetType string
+	Target     string
+	DateMin    string // timestamp
+	DateMax    string // timestamp
+}
+
+// AuditEventsJSON is used by GET /audit and GET /topic/history/*topic
+type AuditEventsJSON struct {
+	Count  int          `json:"count"`
+	Events []AuditEvent `json:"events"`
+}
+
+// GetURL returns query parameters of criteria
+func (c *AuditCriteria) GetURL() string {
+	v := url.Values{}
+	v.Set("skip", strconv.Itoa(c.Skip))
+	v.Set("limit", strconv.Itoa(c.Limit))
+	if c.Actor != "" {
+		v.Set("actor", c.Actor)
+	}
+	if c.Action != "" {
+		v.Set("action", c.Action)
+	}
+	if c.TargetType != "" {
+		v.Set("targetType", c.TargetType)
+	}
+	if c.Target != "" {
+		v.Set("target", c.Target)
+	}
+	if c.DateMin != "" {
+		v.Set("dateMin", c.DateMin)
+	}
+	if c.DateMax != "" {
+		v.Set("dateMax", c.DateMax)
+	}
+	return v.Encode()
+}
+
+// AuditList returns audit events on topics, groups and users, only for Tat admin
+func (c *Client) AuditList(criteria *AuditCriteria) (*AuditEventsJSON, error) {
+	if c == nil {
+		return nil, ErrClientNotInitiliazed
+	}
+	if criteria == nil {
+		criteria = &AuditCriteria{Skip: 0, Limit: 100}
+	}
+	return c.auditList("/audit?" + criteria.GetURL())
+}
+
+// TopicHistory returns audit events on a topic, only for Tat admin and admins on topic.
+// TargetType and Target of criteria are ignored
+func (c *Client) TopicHistory(topic string, criteria *AuditCriteria) (*AuditEventsJSON, error) {
+	if c == nil {
+		return nil, ErrClientNotInitiliazed
+	}
+	if criteria == nil {
+		criteria = &AuditCriteria{Skip: 0, Limit: 100}
+	}
+	return c.auditList("/topic/history" + topic + "?" + criteria.GetURL())
+}
+
+func (c *Client) auditList(path string) (*AuditEventsJSON, error) {
+	body, err := c.simpleGetAndGetBytes(path)
+	if err != nil {
+		ErrorLogFunc("Error getting audit events: %s", err)
+		return nil, err
+	}
+
+	out := &AuditEventsJSON{}
+	if err := json.Unmarshal(body, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
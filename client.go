@@ -8,6 +8,7 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -116,20 +117,16 @@ func (c *Client) IsHTTPS() bool {
 }
 
 func (c *Client) reqWant(method string, wantCode int, path string, jsonStr []byte) ([]byte, error) {
+	return c.reqWantContentType(method, wantCode, path, "", jsonStr)
+}
+
+// reqWantContentType is as reqWant, with a body which is not JSON
+func (c *Client) reqWantContentType(method string, wantCode int, path, contentType string, jsonStr []byte) ([]byte, error) {
 	if c == nil {
 		return nil, ErrClientNotInitiliazed
 	}
 
 	requestPath := c.url + path
-	var req *http.Request
-	if jsonStr != nil {
-		req, _ = http.NewRequest(method, requestPath, bytes.NewReader(jsonStr))
-	} else {
-		req, _ = http.NewRequest(method, requestPath, nil)
-	}
-
-	c.initHeaders(req)
-
 	if HTTPClient == nil {
 		HTTPClient = &http.Client{
 			Transport: &httpcontrol.Transport{
@@ -141,7 +138,31 @@ func (c *Client) reqWant(method string, wantCode int, path string, jsonStr []byt
 			},
 		}
 	}
-	resp, err := HTTPClient.Do(req)
+
+	var resp *http.Response
+	var err error
+	for try := uint(1); ; try++ {
+		var req *http.Request
+		if jsonStr != nil {
+			req, _ = http.NewRequest(method, requestPath, bytes.NewReader(jsonStr))
+		} else {
+			req, _ = http.NewRequest(method, requestPath, nil)
+		}
+
+		c.initHeaders(req)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+
+		resp, err = HTTPClient.Do(req)
+		wait, limited := retryAfter(resp)
+		if !limited || try >= c.maxTries {
+			break
+		}
+		resp.Body.Close()
+		DebugLogFunc("Rate limit exceeded on %s %s, retry %d in %s", method, requestPath, try, wait)
+		time.Sleep(wait)
+	}
 
 	defer func() {
 		if resp != nil && resp.Body != nil {
@@ -165,6 +186,11 @@ func (c *Client) reqWant(method string, wantCode int, path string, jsonStr []byt
 				return []byte{}, fmt.Errorf("Response code:%d (want:%d) err on readAll Body:%s", resp.StatusCode, wantCode, errc)
 			}
 			ErrorLogFunc("Response Body:%s", string(body))
+			if resp.StatusCode == http.StatusConflict {
+				if conflict := newConflictError(body); conflict != nil {
+					return []byte{}, conflict
+				}
+			}
 			return []byte{}, fmt.Errorf("Response code:%d (want:%d) with Body:%s", resp.StatusCode, wantCode, string(body))
 		}
 	}
@@ -181,6 +207,19 @@ func (c *Client) reqWant(method string, wantCode int, path string, jsonStr []byt
 	return body, nil
 }
 
+// retryAfter returns delay asked by Retry-After header of a 429 Too Many Requests
+// response, 1s if header is missing. false if response is not a 429
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil || resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds < 1 {
+		seconds = 1
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
 func (c *Client) simpleGetAndGetBytes(url string) ([]byte, error) {
 	out, err := c.reqWant("GET", 200, url, nil)
 	if err != nil {
@@ -0,0 +1,234 @@
+package tat
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// cursorPosition is the position after the last element of a page:
+// value of sort field and ID of this element
+type cursorPosition struct {
+	SortBy string      `json:"s"`
+	Value  interface{} `json:"v"`
+	ID     string      `json:"i"`
+}
+
+// EncodeCursor returns an opaque cursor, pointing after element with given
+// ID and value of sortBy field
+func EncodeCursor(sortBy string, value interface{}, id string) string {
+	b, _ := json.Marshal(cursorPosition{SortBy: sortBy, Value: value, ID: id})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor returns sortBy, value of sort field and ID of a cursor
+func DecodeCursor(cursor string) (string, interface{}, string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", nil, "", fmt.Errorf("Invalid cursor")
+	}
+	p := cursorPosition{}
+	if err := json.Unmarshal(b, &p); err != nil || p.ID == "" {
+		return "", nil, "", fmt.Errorf("Invalid cursor")
+	}
+	return p.SortBy, p.Value, p.ID, nil
+}
+
+// CursorSortFields returns fields to use on sort: sortBy, then _id in same
+// direction, so that order is stable. Returns only sortBy if it can't be used
+// with a cursor
+func CursorSortFields(sortBy string, allowed []string) []string {
+	if !ArrayContains(allowed, strings.TrimPrefix(sortBy, "-")) {
+		return []string{sortBy}
+	}
+	if strings.HasPrefix(sortBy, "-") {
+		return []string{sortBy, "-_id"}
+	}
+	return []string{sortBy, "_id"}
+}
+
+// MessageCursorSortFields are the fields of a message usable with a cursor
+var MessageCursorSortFields = []string{"dateCreation", "dateUpdate", "nbLikes", "nbVotesUP", "nbVotesDown", "nbReplies", "text", "topic", "inReplyOfID", "inReplyOfIDRoot"}
+
+// TopicCursorSortFields are the fields of a topic usable with a cursor
+var TopicCursorSortFields = []string{"topic", "description", "dateCreation", "dateLastMessage"}
+
+// GetCursor returns cursor pointing after this message, for sortBy.
+// Returns empty string if sortBy can't be used with a cursor
+func (m *Message) GetCursor(sortBy string) string {
+	var v interface{}
+	switch strings.TrimPrefix(sortBy, "-") {
+	case "dateCreation":
+		v = m.DateCreation
+	case "dateUpdate":
+		v = m.DateUpdate
+	case "nbLikes":
+		v = m.NbLikes
+	case "nbVotesUP":
+		v = m.NbVotesUP
+	case "nbVotesDown":
+		v = m.NbVotesDown
+	case "nbReplies":
+		v = m.NbReplies
+	case "text":
+		v = m.Text
+	case "topic":
+		v = m.Topic
+	case "inReplyOfID":
+		v = m.InReplyOfID
+	case "inReplyOfIDRoot":
+		v = m.InReplyOfIDRoot
+	default:
+		return ""
+	}
+	return EncodeCursor(sortBy, v, m.ID)
+}
+
+// GetCursor returns cursor pointing after this topic, for sortBy.
+// Returns empty string if sortBy can't be used with a cursor
+func (t *Topic) GetCursor(sortBy string) string {
+	var v interface{}
+	switch strings.TrimPrefix(sortBy, "-") {
+	case "topic":
+		v = t.Topic
+	case "description":
+		v = t.Description
+	case "dateCreation":
+		v = t.DateCreation
+	case "dateLastMessage":
+		v = t.DateLastMessage
+	default:
+		return ""
+	}
+	return EncodeCursor(sortBy, v, t.ID)
+}
+
+// MessageIterator walks all messages of a topic matching criteria, page by
+// page, using cursors. New messages created during the walk don't shift pages.
+//
+//	it := client.MessageIterator("/Internal/YourTopic", &tat.MessageCriteria{Limit: 100})
+//	for it.Next() {
+//		m := it.Message()
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type MessageIterator struct {
+	client   *Client
+	topic    string
+	criteria MessageCriteria
+	page     []Message
+	idx      int
+	last     bool
+	err      error
+}
+
+// MessageIterator returns an iterator on messages of topic matching criteria.
+// Skip is ignored, Limit is the size of each page
+func (c *Client) MessageIterator(topic string, criteria *MessageCriteria) *MessageIterator {
+	it := &MessageIterator{client: c, topic: topic}
+	if criteria != nil {
+		it.criteria = *criteria
+	}
+	if it.criteria.Limit <= 0 {
+		it.criteria.Limit = 100
+	}
+	it.criteria.Skip = 0
+	if it.criteria.SortBy == "" {
+		it.criteria.SortBy = "-dateCreation"
+	}
+	return it
+}
+
+// Next fetches next message, returns false at the end or on error
+func (it *MessageIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if it.idx+1 < len(it.page) {
+		it.idx++
+		return true
+	}
+	if it.last {
+		return false
+	}
+
+	out, err := it.client.MessageList(it.topic, &it.criteria)
+	if err != nil {
+		it.err = err
+		return false
+	}
+	it.page, it.idx = out.Messages, 0
+	it.criteria.Cursor = out.NextCursor
+	it.last = out.NextCursor == ""
+	return len(it.page) > 0
+}
+
+// Message returns current message
+func (it *MessageIterator) Message() Message {
+	return it.page[it.idx]
+}
+
+// Err returns error which stopped iteration, if any
+func (it *MessageIterator) Err() error {
+	return it.err
+}
+
+// TopicIterator walks all topics matching criteria, page by page, using cursors
+type TopicIterator struct {
+	client   *Client
+	criteria TopicCriteria
+	page     []Topic
+	idx      int
+	last     bool
+	err      error
+}
+
+// TopicIterator returns an iterator on topics matching criteria.
+// Skip is ignored, Limit is the size of each page
+func (c *Client) TopicIterator(criteria *TopicCriteria) *TopicIterator {
+	it := &TopicIterator{client: c}
+	if criteria != nil {
+		it.criteria = *criteria
+	}
+	if it.criteria.Limit <= 0 {
+		it.criteria.Limit = 100
+	}
+	it.criteria.Skip = 0
+	return it
+}
+
+// Next fetches next topic, returns false at the end or on error
+func (it *TopicIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if it.idx+1 < len(it.page) {
+		it.idx++
+		return true
+	}
+	if it.last {
+		return false
+	}
+
+	out, err := it.client.TopicList(&it.criteria)
+	if err != nil {
+		it.err = err
+		return false
+	}
+	it.page, it.idx = out.Topics, 0
+	it.criteria.Cursor = out.NextCursor
+	it.last = out.NextCursor == ""
+	return len(it.page) > 0
+}
+
+// Topic returns current topic
+func (it *TopicIterator) Topic() Topic {
+	return it.page[it.idx]
+}
+
+// Err returns error which stopped iteration, if any
+func (it *TopicIterator) Err() error {
+	return it.err
+}
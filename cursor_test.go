@@ -0,0 +1,30 @@
+package tat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCursor(t *testing.T) {
+	m := Message{ID: "abc", DateCreation: 1489418584.123456}
+	cursor := m.GetCursor("-dateCreation")
+	assert.NotEqual(t, "", cursor)
+
+	sortBy, value, id, err := DecodeCursor(cursor)
+	assert.Nil(t, err)
+	assert.Equal(t, "-dateCreation", sortBy)
+	assert.Equal(t, 1489418584.123456, value)
+	assert.Equal(t, "abc", id)
+
+	assert.Equal(t, "", m.GetCursor("labels"), "labels can't be used with a cursor")
+
+	_, _, _, err = DecodeCursor("not a cursor")
+	assert.NotNil(t, err)
+}
+
+func TestCursorSortFields(t *testing.T) {
+	assert.Equal(t, []string{"-dateCreation", "-_id"}, CursorSortFields("-dateCreation", MessageCursorSortFields))
+	assert.Equal(t, []string{"topic", "_id"}, CursorSortFields("topic", TopicCursorSortFields))
+	assert.Equal(t, []string{"labels"}, CursorSortFields("labels", MessageCursorSortFields))
+}
@@ -1,6 +1,7 @@
 package tat
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 )
@@ -35,3 +36,33 @@ func Error(err error) (int, interface{}) {
 		return http.StatusInternalServerError, e
 	}
 }
+
+// ConflictError is returned by client when engine answers 409 Conflict: message
+// was updated since the ifDateUpdate given on update. Message is its current version,
+// caller can apply its update again on it
+type ConflictError struct {
+	Cause   string
+	Message Message
+}
+
+func (e *ConflictError) Error() string {
+	return e.Cause
+}
+
+// IsConflict returns the ConflictError if err is one
+//  if conflict, ok := tat.IsConflict(err); ok {
+//    // retry with conflict.Message.DateUpdate as ifDateUpdate
+//  }
+func IsConflict(err error) (*ConflictError, bool) {
+	e, ok := err.(*ConflictError)
+	return e, ok
+}
+
+// newConflictError returns a ConflictError from body of a 409 response, nil if body is not a MessageConflictJSON
+func newConflictError(body []byte) *ConflictError {
+	out := &MessageConflictJSON{}
+	if err := json.Unmarshal(body, out); err != nil || out.Message.ID == "" {
+		return nil
+	}
+	return &ConflictError{Cause: out.Error, Message: out.Message}
+}
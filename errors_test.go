@@ -0,0 +1,28 @@
+package tat
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConflictError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`{"error":"Message abc was updated since ifDateUpdate","message":{"_id":"abc","dateUpdate":1484555263.5}}`))
+	}))
+	defer ts.Close()
+
+	c, err := NewClient(Options{URL: ts.URL, Username: "foo", Password: "bar", MaxTries: 1})
+	assert.Nil(t, err)
+
+	_, err = c.MessageRelabelIf("/Internal/foo", "abc", []Label{{Text: "ok"}}, nil, 1484555000)
+	conflict, ok := IsConflict(err)
+	assert.True(t, ok)
+	assert.Equal(t, "abc", conflict.Message.ID)
+	assert.Equal(t, 1484555263.5, conflict.Message.DateUpdate)
+
+	assert.Nil(t, newConflictError([]byte(`{"error":"no message"}`)))
+}
@@ -0,0 +1,309 @@
+package tat
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Operators usable in conditions on fields of messages, ex: build>=1200
+const (
+	FieldOperatorEqual          = "="
+	FieldOperatorNotEqual       = "!="
+	FieldOperatorGreater        = ">"
+	FieldOperatorGreaterOrEqual = ">="
+	FieldOperatorLower          = "<"
+	FieldOperatorLowerOrEqual   = "<="
+)
+
+const (
+	// MaxMessageFields is max number of fields on one message
+	MaxMessageFields = 32
+	// MaxFieldValueLength is max length of a string value of a field
+	MaxFieldValueLength = 256
+	// MaxTopicIndexedFields is max number of indexed fields on one topic
+	MaxTopicIndexedFields = 10
+
+	// fieldURLPrefix prefixes each condition on fields in URL, ex: field.build>=1200
+	fieldURLPrefix = "field."
+)
+
+// longest operators first, ">=" must be tested before ">"
+var fieldOperators = []string{FieldOperatorNotEqual, FieldOperatorGreaterOrEqual, FieldOperatorLowerOrEqual,
+	FieldOperatorEqual, FieldOperatorGreater, FieldOperatorLower}
+
+var fieldNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_\-]{1,64}$`)
+
+// FieldCondition is a condition on a field of messages, ex: build>=1200
+type FieldCondition struct {
+	Name     string
+	Operator string
+	// Text is the value as written in condition, see Value() for typed value
+	Text string
+}
+
+// CheckFieldName returns an error if name can't be used as a field name:
+// only letters, digits, - and _ are allowed
+func CheckFieldName(name string) error {
+	if !fieldNameRegexp.MatchString(name) {
+		return fmt.Errorf("Invalid field name %s, only a-z, A-Z, 0-9, - and _ are allowed, 64 characters max", name)
+	}
+	return nil
+}
+
+// CheckMessageFields checks names and values of fields, and returns fields with
+// numbers converted to float64. A value can only be a string, a number or a bool
+func CheckMessageFields(fields map[string]interface{}) (map[string]interface{}, error) {
+	if len(fields) > MaxMessageFields {
+		return nil, fmt.Errorf("Too many fields on message, %d max", MaxMessageFields)
+	}
+	out := make(map[string]interface{}, len(fields))
+	for name, value := range fields {
+		if err := CheckFieldName(name); err != nil {
+			return nil, err
+		}
+		switch v := value.(type) {
+		case string:
+			if len(v) > MaxFieldValueLength {
+				return nil, fmt.Errorf("Invalid value of field %s, %d characters max", name, MaxFieldValueLength)
+			}
+			out[name] = v
+		case bool:
+			out[name] = v
+		case float64:
+			out[name] = v
+		case float32:
+			out[name] = float64(v)
+		case int:
+			out[name] = float64(v)
+		case int32:
+			out[name] = float64(v)
+		case int64:
+			out[name] = float64(v)
+		default:
+			return nil, fmt.Errorf("Invalid value of field %s, only string, number or bool are allowed", name)
+		}
+	}
+	return out, nil
+}
+
+// ParseFieldValue returns a bool for true or false, a number if s is a number,
+// s otherwise
+func ParseFieldValue(s string) interface{} {
+	if s == True || s == False {
+		return s == True
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+	return s
+}
+
+// FormatFieldValue returns value of a field as text
+func FormatFieldValue(value interface{}) string {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case string:
+		return v
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// ParseFieldCondition parses a condition as name, operator and value, ex: build>=1200, env=prod
+func ParseFieldCondition(expr string) (FieldCondition, error) {
+	i := strings.IndexAny(expr, "!<>=")
+	if i <= 0 {
+		return FieldCondition{}, fmt.Errorf("Invalid condition on field %s, ex: build>=1200", expr)
+	}
+	c := FieldCondition{Name: expr[:i]}
+	if err := CheckFieldName(c.Name); err != nil {
+		return FieldCondition{}, err
+	}
+	for _, op := range fieldOperators {
+		if strings.HasPrefix(expr[i:], op) {
+			c.Operator = op
+			c.Text = expr[i+len(op):]
+			return c, nil
+		}
+	}
+	return FieldCondition{}, fmt.Errorf("Invalid operator in condition on field %s, possible values: =, !=, >, >=, <, <=", expr)
+}
+
+// ParseFieldConditions parses conditions delimited by comma, ex: build>=1200,env=prod
+func ParseFieldConditions(s string) ([]FieldCondition, error) {
+	conditions := []FieldCondition{}
+	for _, expr := range strings.Split(s, ",") {
+		if expr == "" {
+			continue
+		}
+		c, err := ParseFieldCondition(expr)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, c)
+	}
+	return conditions, nil
+}
+
+// String returns condition as name, operator and value
+func (c FieldCondition) String() string {
+	return c.Name + c.Operator + c.Text
+}
+
+// Value returns typed value of condition, see ParseFieldValue
+func (c FieldCondition) Value() interface{} {
+	return ParseFieldValue(c.Text)
+}
+
+// Values returns values equal to condition: typed value and text,
+// a field stored as string "1200" is equal to condition build=1200
+func (c FieldCondition) Values() []interface{} {
+	v := c.Value()
+	if _, ok := v.(string); ok {
+		return []interface{}{v}
+	}
+	return []interface{}{v, c.Text}
+}
+
+// Match returns true if fields match condition. As on engine, only
+// values of same type are compared with >, >=, < and <=
+func (c FieldCondition) Match(fields map[string]interface{}) bool {
+	value, ok := fields[c.Name]
+	switch c.Operator {
+	case FieldOperatorEqual:
+		return ok && c.isEqual(value)
+	case FieldOperatorNotEqual:
+		return !ok || !c.isEqual(value)
+	}
+	if !ok {
+		return false
+	}
+	cmp, comparable := compareFieldValues(value, c.Value())
+	if !comparable {
+		return false
+	}
+	switch c.Operator {
+	case FieldOperatorGreater:
+		return cmp > 0
+	case FieldOperatorGreaterOrEqual:
+		return cmp >= 0
+	case FieldOperatorLower:
+		return cmp < 0
+	case FieldOperatorLowerOrEqual:
+		return cmp <= 0
+	}
+	return false
+}
+
+func (c FieldCondition) isEqual(value interface{}) bool {
+	for _, v := range c.Values() {
+		if cmp, comparable := compareFieldValues(value, v); comparable && cmp == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// compareFieldValues returns -1, 0 or 1 if a and b have same type, false otherwise
+func compareFieldValues(a, b interface{}) (int, bool) {
+	switch va := a.(type) {
+	case float64:
+		if vb, ok := b.(float64); ok {
+			return compareFloats(va, vb), true
+		}
+	case string:
+		if vb, ok := b.(string); ok {
+			return strings.Compare(va, vb), true
+		}
+	case bool:
+		if vb, ok := b.(bool); ok {
+			if va == vb {
+				return 0, true
+			} else if vb {
+				return -1, true
+			}
+			return 1, true
+		}
+	}
+	return 0, false
+}
+
+func compareFloats(a, b float64) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+// MatchFields returns true if message matches all conditions, delimited by comma
+func (m *Message) MatchFields(conditions string) bool {
+	cs, err := ParseFieldConditions(conditions)
+	if err != nil {
+		return false
+	}
+	for _, c := range cs {
+		if !c.Match(m.Fields) {
+			return false
+		}
+	}
+	return true
+}
+
+// FormatFields returns fields of message as name=value, sorted by name and delimited by comma
+func (m *Message) FormatFields() string {
+	names := make([]string, 0, len(m.Fields))
+	for name := range m.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := make([]string, len(names))
+	for i, name := range names {
+		out[i] = name + "=" + FormatFieldValue(m.Fields[name])
+	}
+	return strings.Join(out, ",")
+}
+
+// addFieldConditionsToURL adds one URL value per condition, so that
+// build>=1200 is sent as field.build>=1200
+func addFieldConditionsToURL(v url.Values, conditions string) {
+	for _, expr := range strings.Split(conditions, ",") {
+		if expr == "" {
+			continue
+		}
+		if i := strings.Index(expr, "="); i >= 0 {
+			v.Add(fieldURLPrefix+expr[:i], expr[i+1:])
+		} else {
+			v.Add(fieldURLPrefix+expr, "")
+		}
+	}
+}
+
+// GetFieldConditionsFromURLValues returns conditions given in URL as field.<name><operator><value>,
+// ex: field.build>=1200&field.env=prod returns build>=1200,env=prod
+func GetFieldConditionsFromURLValues(values url.Values) string {
+	conditions := []string{}
+	for k, vs := range values {
+		if !strings.HasPrefix(k, fieldURLPrefix) {
+			continue
+		}
+		name := strings.TrimPrefix(k, fieldURLPrefix)
+		for _, v := range vs {
+			// field.build>1200 has no value, operator and value are in key
+			if v == "" && strings.ContainsAny(name, "<>") {
+				conditions = append(conditions, name)
+			} else {
+				conditions = append(conditions, name+"="+v)
+			}
+		}
+	}
+	sort.Strings(conditions)
+	return strings.Join(conditions, ",")
+}
@@ -0,0 +1,78 @@
+package tat
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFieldCondition(t *testing.T) {
+	c, err := ParseFieldCondition("build>=1200")
+	assert.Nil(t, err)
+	assert.Equal(t, FieldCondition{Name: "build", Operator: FieldOperatorGreaterOrEqual, Text: "1200"}, c)
+	assert.Equal(t, float64(1200), c.Value())
+
+	c, err = ParseFieldCondition("env!=prod")
+	assert.Nil(t, err)
+	assert.Equal(t, FieldOperatorNotEqual, c.Operator)
+	assert.Equal(t, "prod", c.Value())
+
+	c, err = ParseFieldCondition("sha=abc=def")
+	assert.Nil(t, err)
+	assert.Equal(t, "abc=def", c.Text)
+
+	_, err = ParseFieldCondition(">=1200")
+	assert.NotNil(t, err, "name is missing")
+
+	_, err = ParseFieldCondition("build")
+	assert.NotNil(t, err, "operator is missing")
+
+	_, err = ParseFieldCondition("bu.ild=1")
+	assert.NotNil(t, err, "invalid name")
+}
+
+func TestFieldConditionMatch(t *testing.T) {
+	fields := map[string]interface{}{"build": float64(1234), "env": "prod", "sha": "1234", "ok": true}
+
+	cs, err := ParseFieldConditions("build>1200,build<=1234,env=prod,ok=true,sha=1234,missing!=x")
+	assert.Nil(t, err)
+	for _, c := range cs {
+		assert.True(t, c.Match(fields), c.String())
+	}
+
+	cs, err = ParseFieldConditions("build>=1235,env!=prod,sha>1000,missing=x,ok=false")
+	assert.Nil(t, err)
+	for _, c := range cs {
+		assert.False(t, c.Match(fields), c.String())
+	}
+}
+
+func TestCheckMessageFields(t *testing.T) {
+	fields, err := CheckMessageFields(map[string]interface{}{"build": 1234, "env": "prod", "ok": true})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"build": float64(1234), "env": "prod", "ok": true}, fields)
+
+	_, err = CheckMessageFields(map[string]interface{}{"list": []string{"a"}})
+	assert.NotNil(t, err)
+
+	_, err = CheckMessageFields(map[string]interface{}{"$set": "a"})
+	assert.NotNil(t, err)
+}
+
+func TestFieldConditionsURL(t *testing.T) {
+	c := MessageCriteria{Field: "build<1300,build>=1200,env=prod"}
+	values, err := url.ParseQuery(c.GetURL())
+	assert.Nil(t, err)
+	assert.Equal(t, "1200", values.Get("field.build>"))
+
+	c2, err := GetMessageCriteriaFromURLValues(values)
+	assert.Nil(t, err)
+	assert.Equal(t, c.Field, c2.Field)
+
+	m := Message{Fields: map[string]interface{}{"env": "prod", "build": float64(1234)}}
+	assert.Equal(t, "build=1234,env=prod", m.FormatFields())
+	out, err := m.Format("field.build,fields", "")
+	assert.Nil(t, err)
+	assert.Equal(t, "1234 fields:build=1234,env=prod ", out)
+}
@@ -10,6 +10,8 @@ var HookTat2XMPPHeaderKey = "Tat2xmppkey"
 type HookMessageJSON struct {
 	Action         string          `json:"action"`
 	MessageJSONOut *MessageJSONOut `json:"message"`
+	// Transition contains from and to states, if message changed of state of topic workflow
+	Transition *WorkflowTransition `json:"transition,omitempty"`
 }
 
 // HookJSON represents a json sent to an external system
@@ -0,0 +1,69 @@
+package tat
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// MentionsJSON is used by GET /user/me/mentions
+type MentionsJSON struct {
+	Messages []Message `json:"messages"`
+	// Unread contains IDs of returned messages not yet marked as read
+	Unread []string `json:"unread,omitempty"`
+	// NbUnread is the number of unread mentions, on all topics
+	NbUnread int `json:"nbUnread"`
+}
+
+// MentionsReadJSON is used by PUT /user/me/mentions/read
+type MentionsReadJSON struct {
+	// IDMessages to mark as read, all mentions if empty
+	IDMessages []string `json:"idMessages"`
+}
+
+// IsMentionRead returns true if username marked mention as read on message.
+// MentionsRead is not sent by tat engine, use MentionsJSON.Unread on client side
+func (m *Message) IsMentionRead(username string) bool {
+	return ArrayContains(m.MentionsRead, username)
+}
+
+// UserMentions returns messages mentioning current user, on all topics readable by user,
+// last created first. Only unread mentions if onlyUnread
+func (c *Client) UserMentions(criteria *MessageCriteria, onlyUnread bool) (*MentionsJSON, error) {
+	if c == nil {
+		return nil, ErrClientNotInitiliazed
+	}
+	if criteria == nil {
+		criteria = &MessageCriteria{Skip: 0, Limit: 100}
+	}
+
+	path := fmt.Sprintf("/user/me/mentions?%s", criteria.GetURL())
+	if onlyUnread {
+		path += "&onlyUnread=true"
+	}
+	body, err := c.simpleGetAndGetBytes(path)
+	if err != nil {
+		ErrorLogFunc("Error getting mentions: %s", err)
+		return nil, err
+	}
+
+	out := &MentionsJSON{}
+	if err := json.Unmarshal(body, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UserMentionsRead marks mentions of current user as read, all mentions if no idMessages
+func (c *Client) UserMentionsRead(idMessages ...string) ([]byte, error) {
+	if c == nil {
+		return nil, ErrClientNotInitiliazed
+	}
+
+	out, err := c.simplePutAndGetBytes("/user/me/mentions/read", http.StatusOK, MentionsReadJSON{IDMessages: idMessages})
+	if err != nil {
+		ErrorLogFunc("Error marking mentions as read: %s", err)
+		return nil, err
+	}
+	return out, nil
+}
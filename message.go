@@ -1,10 +1,13 @@
 package tat
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -26,6 +29,8 @@ const (
 	TreeViewOneTree = "onetree"
 	// TreeViewFullTree is fulltree value for treeView
 	TreeViewFullTree = "fulltree"
+	// SortByRelevance sorts messages by text score, textSearch is required
+	SortByRelevance = "relevance"
 
 	// MessageActionCreate for create a message
 	MessageActionCreate = "create"
@@ -61,6 +66,30 @@ const (
 	MessageActionTask = "task"
 	// MessageActionUntask for untask action on a message
 	MessageActionUntask = "untask"
+	// MessageActionRestore for restore a previous revision of a message
+	MessageActionRestore = "restore"
+	// MessageActionDelete is used in hooks and streams when a message is deleted
+	MessageActionDelete = "delete"
+	// MessageActionReact for react action on a message
+	MessageActionReact = "react"
+	// MessageActionUnreact for unreact action on a message
+	MessageActionUnreact = "unreact"
+	// MessageActionPin for pin action on a message, reserved to topic admins
+	MessageActionPin = "pin"
+	// MessageActionUnpin for unpin action on a message, reserved to topic admins
+	MessageActionUnpin = "unpin"
+	// MessageActionUndelete is used in hooks and streams when a message is restored from trash
+	MessageActionUndelete = "undelete"
+	// MessageActionFields for set or remove fields on a message
+	MessageActionFields = "fields"
+	// MessageActionAssign for assign a message to users or groups, with a due date and a state
+	MessageActionAssign = "assign"
+	// MessageActionUnassign for remove task from a message
+	MessageActionUnassign = "unassign"
+	// MessageActionTaskState for change state of the task of a message
+	MessageActionTaskState = "taskstate"
+	// MessageActionTaskOverdue is used in hooks and streams when due date of a task is exceeded
+	MessageActionTaskOverdue = "taskoverdue"
 )
 
 // Author struct
@@ -75,28 +104,42 @@ type Label struct {
 	Color string `bson:"color" json:"color"`
 }
 
+// Attachment is a file attached to a message, content is stored in GridFS
+type Attachment struct {
+	ID          string `bson:"_id" json:"_id"`
+	Filename    string `bson:"filename" json:"filename"`
+	ContentType string `bson:"contentType" json:"contentType"`
+	Size        int64  `bson:"size" json:"size"`
+}
+
 // Message struc
 type Message struct {
-	ID              string    `bson:"_id"             json:"_id"`
-	Text            string    `bson:"text"            json:"text"`
-	Topic           string    `bson:"topic"           json:"topic"`
-	InReplyOfID     string    `bson:"inReplyOfID"     json:"inReplyOfID"`
-	InReplyOfIDRoot string    `bson:"inReplyOfIDRoot" json:"inReplyOfIDRoot"`
-	NbLikes         int64     `bson:"nbLikes"         json:"nbLikes"`
-	Labels          []Label   `bson:"labels"          json:"labels,omitempty"`
-	Likers          []string  `bson:"likers"          json:"likers,omitempty"`
-	VotersUP        []string  `bson:"votersUP"        json:"votersUP,omitempty"`
-	VotersDown      []string  `bson:"votersDown"      json:"votersDown,omitempty"`
-	NbVotesUP       int64     `bson:"nbVotesUP"       json:"nbVotesUP"`
-	NbVotesDown     int64     `bson:"nbVotesDown"     json:"nbVotesDown"`
-	UserMentions    []string  `bson:"userMentions"    json:"userMentions,omitempty"`
-	Urls            []string  `bson:"urls"            json:"urls,omitempty"`
-	Tags            []string  `bson:"tags"            json:"tags,omitempty"`
-	DateCreation    float64   `bson:"dateCreation"    json:"dateCreation"`
-	DateUpdate      float64   `bson:"dateUpdate"      json:"dateUpdate"`
-	Author          Author    `bson:"author"          json:"author"`
-	Replies         []Message `bson:"-"               json:"replies,omitempty"`
-	NbReplies       int64     `bson:"nbReplies"       json:"nbReplies"`
+	ID              string                 `bson:"_id"             json:"_id"`
+	Text            string                 `bson:"text"            json:"text"`
+	Topic           string                 `bson:"topic"           json:"topic"`
+	InReplyOfID     string                 `bson:"inReplyOfID"     json:"inReplyOfID"`
+	InReplyOfIDRoot string                 `bson:"inReplyOfIDRoot" json:"inReplyOfIDRoot"`
+	NbLikes         int64                  `bson:"nbLikes"         json:"nbLikes"`
+	Labels          []Label                `bson:"labels"          json:"labels,omitempty"`
+	Likers          []string               `bson:"likers"          json:"likers,omitempty"`
+	VotersUP        []string               `bson:"votersUP"        json:"votersUP,omitempty"`
+	VotersDown      []string               `bson:"votersDown"      json:"votersDown,omitempty"`
+	NbVotesUP       int64                  `bson:"nbVotesUP"       json:"nbVotesUP"`
+	NbVotesDown     int64                  `bson:"nbVotesDown"     json:"nbVotesDown"`
+	Reactions       map[string][]string    `bson:"reactions"       json:"reactions,omitempty"`
+	UserMentions    []string               `bson:"userMentions"    json:"userMentions,omitempty"`
+	MentionsRead    []string               `bson:"mentionsRead"    json:"-"` // read state is only returned to each user, in MentionsJSON.Unread
+	Urls            []string               `bson:"urls"            json:"urls,omitempty"`
+	Attachments     []Attachment           `bson:"attachments"     json:"attachments,omitempty"`
+	Tags            []string               `bson:"tags"            json:"tags,omitempty"`
+	Fields          map[string]interface{} `bson:"fields,omitempty" json:"fields,omitempty"`
+	Task            *Task                  `bson:"task,omitempty"   json:"task,omitempty"`
+	DateCreation    float64                `bson:"dateCreation"    json:"dateCreation"`
+	DateUpdate      float64                `bson:"dateUpdate"      json:"dateUpdate"`
+	Author          Author                 `bson:"author"          json:"author"`
+	Replies         []Message              `bson:"-"               json:"replies,omitempty"`
+	NbReplies       int64                  `bson:"nbReplies"       json:"nbReplies"`
+	Score           float64                `bson:"score,omitempty" json:"score,omitempty"`
 }
 
 // MessageCriteria are used to list messages
@@ -147,6 +190,11 @@ type MessageCriteria struct {
 	OnlyMsgReply            string `bson:"onlyMsgReply" json:"onlyMsgReply,omitempty"`
 	OnlyCount               string
 	SortBy                  string `bson:"sortBy" json:"sortBy"`
+	TextSearch              string `bson:"textSearch" json:"textSearch,omitempty"`
+	Cursor                  string `bson:"cursor" json:"cursor,omitempty"`
+	Reaction                string `bson:"reaction" json:"reaction,omitempty"`
+	Pinned                  string `bson:"pinned" json:"pinned,omitempty"`
+	Field                   string `bson:"field" json:"field,omitempty"` // conditions on fields, ex: build>=1200,env=prod
 }
 
 // CacheKey returns cache key value
@@ -182,6 +230,21 @@ func (m *MessageCriteria) CacheKey() []string {
 	if m.Text != "" {
 		s = append(s, "Text="+m.Text)
 	}
+	if m.TextSearch != "" {
+		s = append(s, "TextSearch="+m.TextSearch)
+	}
+	if m.Cursor != "" {
+		s = append(s, "Cursor="+m.Cursor)
+	}
+	if m.Reaction != "" {
+		s = append(s, "Reaction="+m.Reaction)
+	}
+	if m.Pinned != "" {
+		s = append(s, "Pinned="+m.Pinned)
+	}
+	if m.Field != "" {
+		s = append(s, "Field="+m.Field)
+	}
 	if m.Label != "" {
 		s = append(s, "Label="+m.Label)
 	}
@@ -307,9 +370,11 @@ type MessageReferenceJSON struct {
 
 // MessagesJSON represents a message and information if current topic is RW
 type MessagesJSON struct {
-	Messages     []Message `json:"messages"`
-	IsTopicRw    bool      `json:"isTopicRw"`
-	IsTopicAdmin bool      `json:"isTopicAdmin"`
+	Messages     []Message           `json:"messages"`
+	IsTopicRw    bool                `json:"isTopicRw"`
+	IsTopicAdmin bool                `json:"isTopicAdmin"`
+	Highlights   map[string][]string `json:"highlights,omitempty"` // snippets by message ID, with textSearch only
+	NextCursor   string              `json:"nextCursor,omitempty"` // cursor of next page, empty on last page
 }
 
 // MessagesCountJSON represents count of messages
@@ -319,32 +384,77 @@ type MessagesCountJSON struct {
 
 // MessageJSONOut represents a message and an additional info
 type MessageJSONOut struct {
+	Message   Message           `json:"message"`
+	Info      string            `json:"info"`
+	Scheduled *ScheduledMessage `json:"scheduled,omitempty"`
+}
+
+// MessageConflictJSON is returned with code 409 when a message was updated
+// since the ifDateUpdate given on update
+type MessageConflictJSON struct {
+	Error   string  `json:"error"`
 	Message Message `json:"message"`
-	Info    string  `json:"info"`
 }
 
 type MessagesJSONIn struct {
 	Messages []*MessageJSON `json:"messages"`
 }
 
+// MessagesBulkJSONOut is the summary of an action run on many messages
+type MessagesBulkJSONOut struct {
+	Info     string               `json:"info"`
+	Updated  []string             `json:"updated"`
+	Failures []MessageBulkFailure `json:"failures,omitempty"`
+}
+
+// MessageBulkFailure is a message not updated by a bulk action, with the reason
+type MessageBulkFailure struct {
+	IDMessage string `json:"idMessage"`
+	Error     string `json:"error"`
+}
+
 // MessageJSON represents a message with action on it
 type MessageJSON struct {
 	ID                  string `json:"_id"`
 	Text                string `json:"text"`
 	Option              string `json:"option"`
 	Topic               string
-	IDReference         string        `json:"idReference"`
-	StartTagReference   string        `json:"startTagReference"`
-	StartLabelReference string        `json:"startLabelReference"`
-	TagReference        string        `json:"tagReference"`
-	LabelReference      string        `json:"labelReference"`
-	OnlyRootReference   string        `json:"onlyRootReference"`
-	Action              string        `json:"action"`
-	DateCreation        float64       `json:"dateCreation"`
-	Labels              []Label       `json:"labels"`
-	Options             []string      `json:"options"`
-	Replies             []string      `json:"replies"`
-	Messages            []MessageJSON `json:"messages"` // same as replies, but with Labels...
+	IDReference         string                 `json:"idReference"`
+	StartTagReference   string                 `json:"startTagReference"`
+	StartLabelReference string                 `json:"startLabelReference"`
+	TagReference        string                 `json:"tagReference"`
+	LabelReference      string                 `json:"labelReference"`
+	OnlyRootReference   string                 `json:"onlyRootReference"`
+	Action              string                 `json:"action"`
+	DateCreation        float64                `json:"dateCreation"`
+	Labels              []Label                `json:"labels"`
+	Options             []string               `json:"options"`
+	Replies             []string               `json:"replies"`
+	Messages            []MessageJSON          `json:"messages"`                 // same as replies, but with Labels...
+	IDRevision          string                 `json:"idRevision,omitempty"`     // with action restore
+	DateScheduled       float64                `json:"dateScheduled,omitempty"`  // post message later, at this date
+	IdempotencyKey      string                 `json:"idempotencyKey,omitempty"` // replays with same key return first result
+	Fields              map[string]interface{} `json:"fields,omitempty"`         // typed fields: string, number or bool. With action fields, a null value removes field
+	IfDateUpdate        float64                `json:"ifDateUpdate,omitempty"`   // update only if dateUpdate of message is still this one, 409 otherwise
+	Task                *Task                  `json:"task,omitempty"`           // with actions assign and taskstate
+}
+
+// MessageRevision is a previous version of a message, saved before an update
+type MessageRevision struct {
+	ID              string  `bson:"_id"             json:"_id"`
+	IDMessage       string  `bson:"idMessage"       json:"idMessage"`
+	InReplyOfIDRoot string  `bson:"inReplyOfIDRoot" json:"inReplyOfIDRoot"`
+	Topic           string  `bson:"topic"           json:"topic"`
+	Text            string  `bson:"text"            json:"text"`
+	Labels          []Label `bson:"labels"          json:"labels,omitempty"`
+	DateUpdate      float64 `bson:"dateUpdate"      json:"dateUpdate"`   // date of this version
+	DateRevision    float64 `bson:"dateRevision"    json:"dateRevision"` // date of replacement of this version
+	UpdatedBy       Author  `bson:"updatedBy"       json:"updatedBy"`    // user who replaced this version
+}
+
+// MessageRevisionsJSON represents revisions of a message, last first
+type MessageRevisionsJSON struct {
+	Revisions []MessageRevision `json:"revisions"`
 }
 
 // MessageAdd post a tat message
@@ -356,10 +466,19 @@ func (c *Client) MessageAdd(message MessageJSON) (*MessageJSONOut, error) {
 	if message.Topic == "" {
 		return nil, fmt.Errorf("A message must have a Topic")
 	}
-
 	return c.processForMessageJSONOut("POST", "/message"+message.Topic, 201, message)
 }
 
+// NewIdempotencyKey returns a random key, to use as MessageJSON.IdempotencyKey.
+// Keep the key to send a request again without creating the message twice
+func NewIdempotencyKey() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
 // MessageAddBulk post many tat message (root msg or replies)
 func (c *Client) MessageAddBulk(messages []MessageJSON) ([]MessageJSONOut, error) {
 	if c == nil {
@@ -371,7 +490,6 @@ func (c *Client) MessageAddBulk(messages []MessageJSON) ([]MessageJSONOut, error
 		if message.Topic == "" {
 			return nil, fmt.Errorf("A message must have a Topic")
 		}
-
 		m, err := c.processForMessageJSONOut("POST", "/message"+message.Topic, 201, message)
 		if err != nil {
 			return msgs, err
@@ -439,17 +557,89 @@ func (c *Client) MessagesDeleteBulk(topic string, cascade bool, cascadeForce boo
 	return out, nil
 }
 
+// MessagesUpdateBulk runs an action on all messages matching criteria:
+// label, unlabel, relabel, like, unlike, task, untask, move or fields.
+//  _, err := c.MessagesUpdateBulk("/Alerts", tat.MessageCriteria{Label: "AL", Limit: 500}, tat.MessageJSON{
+//    Action: tat.MessageActionRelabel,
+//    Labels: []tat.Label{{Text: "UP", Color: "#14892c"}},
+//  })
+func (c *Client) MessagesUpdateBulk(topic string, criteria MessageCriteria, message MessageJSON) (*MessagesBulkJSONOut, error) {
+	if c == nil {
+		return nil, ErrClientNotInitiliazed
+	}
+
+	b, err := json.Marshal(message)
+	if err != nil {
+		ErrorLogFunc("Error while marshal message: %s", err)
+		return nil, err
+	}
+
+	body, err := c.reqWant(http.MethodPut, 200, fmt.Sprintf("/messages%s?%s", topic, criteria.GetURL()), b)
+	if err != nil {
+		ErrorLogFunc("Error updating messages: %s", err)
+		return nil, err
+	}
+	out := &MessagesBulkJSONOut{}
+	if err := json.Unmarshal(body, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MessageUpdate updates a message
 func (c *Client) MessageUpdate(topic, idMessage string, newText string) (*MessageJSONOut, error) {
+	return c.MessageUpdateIf(topic, idMessage, newText, 0)
+}
+
+// MessageUpdateIf updates a message only if it was not updated since ifDateUpdate,
+// the dateUpdate of message known by caller. Otherwise, err is a *ConflictError
+// with current message, see IsConflict
+func (c *Client) MessageUpdateIf(topic, idMessage string, newText string, ifDateUpdate float64) (*MessageJSONOut, error) {
 	if c == nil {
 		return nil, ErrClientNotInitiliazed
 	}
 
 	message := MessageJSON{
-		Action:      MessageActionUpdate,
+		Action:       MessageActionUpdate,
+		Topic:        topic,
+		IDReference:  idMessage,
+		Text:         newText,
+		IfDateUpdate: ifDateUpdate,
+	}
+	return c.processForMessageJSONOut("PUT", "/message"+message.Topic, 200, message)
+}
+
+// MessageRevisions returns previous versions of a message, last first
+func (c *Client) MessageRevisions(topic, idMessage string) (*MessageRevisionsJSON, error) {
+	if c == nil {
+		return nil, ErrClientNotInitiliazed
+	}
+
+	body, err := c.simpleGetAndGetBytes(fmt.Sprintf("/message/%s/revisions%s", idMessage, topic))
+	if err != nil {
+		ErrorLogFunc("Error getting revisions of message %s: %s", idMessage, err)
+		return nil, err
+	}
+
+	out := &MessageRevisionsJSON{}
+	if err := json.Unmarshal(body, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MessageRestoreRevision restores text and labels of a previous version of a message.
+// Current version is saved as a new revision
+func (c *Client) MessageRestoreRevision(topic, idMessage, idRevision string) (*MessageJSONOut, error) {
+	if c == nil {
+		return nil, ErrClientNotInitiliazed
+	}
+
+	message := MessageJSON{
+		Action:      MessageActionRestore,
 		Topic:       topic,
 		IDReference: idMessage,
-		Text:        newText,
+		IDRevision:  idRevision,
 	}
 	return c.processForMessageJSONOut("PUT", "/message"+message.Topic, 200, message)
 }
@@ -465,15 +655,22 @@ curl -XPUT \
 ```
 */
 func (c *Client) MessageConcat(topic, idMessage string, addText string) (*MessageJSONOut, error) {
+	return c.MessageConcatIf(topic, idMessage, addText, 0)
+}
+
+// MessageConcatIf is as MessageConcat, only if message was not updated since ifDateUpdate.
+// Otherwise, err is a *ConflictError with current message, see IsConflict
+func (c *Client) MessageConcatIf(topic, idMessage string, addText string, ifDateUpdate float64) (*MessageJSONOut, error) {
 	if c == nil {
 		return nil, ErrClientNotInitiliazed
 	}
 
 	message := MessageJSON{
-		Action:      MessageActionConcat,
-		Topic:       topic,
-		IDReference: idMessage,
-		Text:        addText,
+		Action:       MessageActionConcat,
+		Topic:        topic,
+		IDReference:  idMessage,
+		Text:         addText,
+		IfDateUpdate: ifDateUpdate,
 	}
 	return c.processForMessageJSONOut("PUT", "/message"+message.Topic, 200, message)
 }
@@ -521,6 +718,22 @@ func (c *Client) MessageUntask(topic, idMessage string) (*MessageJSONOut, error)
 	return c.processForMessageJSONOut("PUT", "/message"+message.Topic, 201, message)
 }
 
+// MessageSetFields sets fields on a message, other fields are kept. A nil value removes field
+//  c.MessageSetFields("/Builds", idMessage, map[string]interface{}{"build": 1234, "env": "prod", "sha": nil})
+func (c *Client) MessageSetFields(topic, idMessage string, fields map[string]interface{}) (*MessageJSONOut, error) {
+	if c == nil {
+		return nil, ErrClientNotInitiliazed
+	}
+
+	message := MessageJSON{
+		Topic:       topic,
+		IDReference: idMessage,
+		Action:      MessageActionFields,
+		Fields:      fields,
+	}
+	return c.processForMessageJSONOut("PUT", "/message"+message.Topic, 201, message)
+}
+
 // MessageLike add a like to a message
 func (c *Client) MessageLike(topic, idMessage string) (*MessageJSONOut, error) {
 	if c == nil {
@@ -535,6 +748,64 @@ func (c *Client) MessageLike(topic, idMessage string) (*MessageJSONOut, error) {
 	return c.processForMessageJSONOut("PUT", "/message"+message.Topic, 201, message)
 }
 
+// MessageReact adds a reaction on a message, ex: "+1", "ack", "👀"
+func (c *Client) MessageReact(topic, idMessage, reaction string) (*MessageJSONOut, error) {
+	if c == nil {
+		return nil, ErrClientNotInitiliazed
+	}
+
+	message := MessageJSON{
+		Topic:       topic,
+		IDReference: idMessage,
+		Action:      MessageActionReact,
+		Text:        reaction,
+	}
+	return c.processForMessageJSONOut("PUT", "/message"+message.Topic, 201, message)
+}
+
+// MessageUnreact removes a reaction of current user from a message
+func (c *Client) MessageUnreact(topic, idMessage, reaction string) (*MessageJSONOut, error) {
+	if c == nil {
+		return nil, ErrClientNotInitiliazed
+	}
+
+	message := MessageJSON{
+		Topic:       topic,
+		IDReference: idMessage,
+		Action:      MessageActionUnreact,
+		Text:        reaction,
+	}
+	return c.processForMessageJSONOut("PUT", "/message"+message.Topic, 201, message)
+}
+
+// MessagePin pins a message on top of its topic, reserved to topic admins
+func (c *Client) MessagePin(topic, idMessage string) (*MessageJSONOut, error) {
+	if c == nil {
+		return nil, ErrClientNotInitiliazed
+	}
+
+	message := MessageJSON{
+		Topic:       topic,
+		IDReference: idMessage,
+		Action:      MessageActionPin,
+	}
+	return c.processForMessageJSONOut("PUT", "/message"+message.Topic, 201, message)
+}
+
+// MessageUnpin removes a message from pinned messages of its topic
+func (c *Client) MessageUnpin(topic, idMessage string) (*MessageJSONOut, error) {
+	if c == nil {
+		return nil, ErrClientNotInitiliazed
+	}
+
+	message := MessageJSON{
+		Topic:       topic,
+		IDReference: idMessage,
+		Action:      MessageActionUnpin,
+	}
+	return c.processForMessageJSONOut("PUT", "/message"+message.Topic, 201, message)
+}
+
 // MessageUnlike removes a like from a message
 func (c *Client) MessageUnlike(topic, idMessage string) (*MessageJSONOut, error) {
 	if c == nil {
@@ -607,47 +878,73 @@ func (c *Client) MessageUnVoteDown(topic, idMessage string) (*MessageJSONOut, er
 
 // MessageLabel add a label to a message
 func (c *Client) MessageLabel(topic, idMessage string, label Label) (*MessageJSONOut, error) {
+	return c.MessageLabelIf(topic, idMessage, label, 0)
+}
+
+// MessageLabelIf is as MessageLabel, only if message was not updated since ifDateUpdate.
+// Otherwise, err is a *ConflictError with current message, see IsConflict
+func (c *Client) MessageLabelIf(topic, idMessage string, label Label, ifDateUpdate float64) (*MessageJSONOut, error) {
 	if c == nil {
 		return nil, ErrClientNotInitiliazed
 	}
 
 	message := MessageJSON{
-		Topic:       topic,
-		IDReference: idMessage,
-		Text:        label.Text,
-		Option:      label.Color,
-		Action:      MessageActionLabel,
+		Topic:        topic,
+		IDReference:  idMessage,
+		Text:         label.Text,
+		Option:       label.Color,
+		Action:       MessageActionLabel,
+		IfDateUpdate: ifDateUpdate,
 	}
 	return c.processForMessageJSONOut("PUT", "/message"+message.Topic, 201, message)
 }
 
 // MessageUnlabel removes a label from one message
 func (c *Client) MessageUnlabel(topic, idMessage, label string) (*MessageJSONOut, error) {
+	return c.MessageUnlabelIf(topic, idMessage, label, 0)
+}
+
+// MessageUnlabelIf is as MessageUnlabel, only if message was not updated since ifDateUpdate.
+// Otherwise, err is a *ConflictError with current message, see IsConflict
+func (c *Client) MessageUnlabelIf(topic, idMessage, label string, ifDateUpdate float64) (*MessageJSONOut, error) {
 	if c == nil {
 		return nil, ErrClientNotInitiliazed
 	}
 
 	message := MessageJSON{
-		Topic:       topic,
-		IDReference: idMessage,
-		Text:        label,
-		Action:      MessageActionUnlabel,
+		Topic:        topic,
+		IDReference:  idMessage,
+		Text:         label,
+		Action:       MessageActionUnlabel,
+		IfDateUpdate: ifDateUpdate,
 	}
 	return c.processForMessageJSONOut("PUT", "/message"+message.Topic, 201, message)
 }
 
 // MessageRelabel removes all labels and add new ones to a message
 func (c *Client) MessageRelabel(topic, idMessage string, labels []Label, options []string) (*MessageJSONOut, error) {
+	return c.MessageRelabelIf(topic, idMessage, labels, options, 0)
+}
+
+// MessageRelabelIf is as MessageRelabel, only if message was not updated since ifDateUpdate,
+// so that two relabels on same message don't overwrite each other.
+// Otherwise, err is a *ConflictError with current message, see IsConflict
+//  out, err := client.MessageRelabelIf(topic, msg.ID, labels, nil, msg.DateUpdate)
+//  if conflict, ok := tat.IsConflict(err); ok {
+//    // compute labels again from conflict.Message, then retry with conflict.Message.DateUpdate
+//  }
+func (c *Client) MessageRelabelIf(topic, idMessage string, labels []Label, options []string, ifDateUpdate float64) (*MessageJSONOut, error) {
 	if c == nil {
 		return nil, ErrClientNotInitiliazed
 	}
 
 	message := MessageJSON{
-		Topic:       topic,
-		IDReference: idMessage,
-		Labels:      labels,
-		Options:     options,
-		Action:      MessageActionRelabel,
+		Topic:        topic,
+		IDReference:  idMessage,
+		Labels:       labels,
+		Options:      options,
+		Action:       MessageActionRelabel,
+		IfDateUpdate: ifDateUpdate,
 	}
 
 	return c.processForMessageJSONOut("PUT", "/message"+message.Topic, 201, message)
@@ -728,6 +1025,21 @@ func (m *MessageCriteria) GetURL() string {
 	if m.Text != "" {
 		v.Set("text", m.Text)
 	}
+	if m.TextSearch != "" {
+		v.Set("textSearch", m.TextSearch)
+	}
+	if m.Cursor != "" {
+		v.Set("cursor", m.Cursor)
+	}
+	if m.Reaction != "" {
+		v.Set("reaction", m.Reaction)
+	}
+	if m.Pinned != "" {
+		v.Set("pinned", m.Pinned)
+	}
+	if m.Field != "" {
+		addFieldConditionsToURL(v, m.Field)
+	}
 	if m.Topic != "" {
 		v.Set("topic", m.Topic)
 	}
@@ -876,6 +1188,14 @@ func GetMessageCriteriaFromURLValues(values url.Values) (*MessageCriteria, error
 			c.AllIDMessage = v[0]
 		case "text":
 			c.Text = v[0]
+		case "textSearch":
+			c.TextSearch = v[0]
+		case "cursor":
+			c.Cursor = v[0]
+		case "reaction":
+			c.Reaction = v[0]
+		case "pinned":
+			c.Pinned = v[0]
 		case "topic":
 			c.Topic = v[0]
 		case "label":
@@ -954,6 +1274,7 @@ func GetMessageCriteriaFromURLValues(values url.Values) (*MessageCriteria, error
 			c.SortBy = v[0]
 		}
 	}
+	c.Field = GetFieldConditionsFromURLValues(values)
 
 	return c, nil
 }
@@ -1032,8 +1353,12 @@ func (m *Message) ContainsLabel(label string) bool {
 	return err == nil
 }
 
-// IsDoing returns true if message contains label doing or starts with doing:
+// IsDoing returns true if message is a task in state doing, or if message
+// contains label doing or starts with doing:
 func (m *Message) IsDoing() bool {
+	if m.Task != nil && m.Task.State == TaskStateDoing {
+		return true
+	}
 	for _, label := range m.Labels {
 		if label.Text == "doing" || strings.HasPrefix(label.Text, "doing:") {
 			return true
@@ -1042,6 +1367,23 @@ func (m *Message) IsDoing() bool {
 	return false
 }
 
+// FormatReactions returns reactions on message with number of users,
+// sorted by reaction: "+1:2 ack:1"
+func (m *Message) FormatReactions() string {
+	reactions := []string{}
+	for r := range m.Reactions {
+		reactions = append(reactions, r)
+	}
+	sort.Strings(reactions)
+	out := []string{}
+	for _, r := range reactions {
+		if len(m.Reactions[r]) > 0 {
+			out = append(out, fmt.Sprintf("%s:%d", r, len(m.Reactions[r])))
+		}
+	}
+	return strings.Join(out, " ")
+}
+
 // GetTag returns position, tag is message contains tag
 func (m *Message) GetTag(tag string) (int, string, error) {
 	for idx, cur := range m.Tags {
@@ -1085,7 +1427,8 @@ func GetDateRef(pattern string) (time.Time, error) {
 // Avalable fields:
 // id,text,topic,inReplyOfID,inReplyOfIDRoot,nbLikes,labels,
 // votersUP,votersDown,nbVotesUP,nbVotesDown,userMentions,
-// urls,tags,dateCreation,dateUpdate,username,fullname,nbReplies,tatwebuiURL
+// urls,tags,dateCreation,dateUpdate,username,fullname,nbReplies,tatwebuiURL,
+// reactions,fields,field.<name>,task
 func (m *Message) Format(format string, tatwebuiBaseURL string) (string, error) {
 
 	if format == "" {
@@ -1146,8 +1489,22 @@ func (m *Message) Format(format string, tatwebuiBaseURL string) (string, error)
 			out += fmt.Sprintf("%s ", m.Author.Fullname)
 		case "nbReplies":
 			out += fmt.Sprintf("nbReplies:%d ", m.NbReplies)
+		case "reactions":
+			out += fmt.Sprintf("reactions:%s ", m.FormatReactions())
+		case "fields":
+			out += fmt.Sprintf("fields:%s ", m.FormatFields())
+		case "task":
+			if m.Task != nil {
+				out += fmt.Sprintf("task:%s ", m.FormatTask())
+			}
 		case "tatwebuiURL":
 			out += fmt.Sprintf("tatwebui:%s%s?idMessage=%s", tatwebuiBaseURL, m.Topic, m.ID)
+		default:
+			if strings.HasPrefix(t, fieldURLPrefix) {
+				if v, ok := m.Fields[strings.TrimPrefix(t, fieldURLPrefix)]; ok {
+					out += fmt.Sprintf("%s ", FormatFieldValue(v))
+				}
+			}
 		}
 	}
 
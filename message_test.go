@@ -0,0 +1,20 @@
+package tat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatReactions(t *testing.T) {
+	m := Message{Reactions: map[string][]string{
+		"ack": {"foo"},
+		"+1":  {"foo", "bar"},
+		"👀":   {},
+	}}
+	assert.Equal(t, "+1:2 ack:1", m.FormatReactions())
+
+	out, err := m.Format("reactions", "")
+	assert.Nil(t, err)
+	assert.Equal(t, "reactions:+1:2 ack:1 ", out)
+}
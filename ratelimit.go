@@ -0,0 +1,45 @@
+package tat
+
+import "encoding/json"
+
+// RateLimits are max numbers of messages created per minute, per user, per topic
+// and on all topics. 0 if there is no limit
+type RateLimits struct {
+	User   int `json:"user"`
+	Topic  int `json:"topic"`
+	Global int `json:"global"`
+}
+
+// RateLimitThrottled is a user, a topic or all topics on which messages were
+// rejected because of a rate limit
+type RateLimitThrottled struct {
+	Kind              string  `json:"kind"` // user, topic or global
+	Name              string  `json:"name"` // username or topic name, empty for global
+	Rejected          int64   `json:"rejected"`
+	DateLastThrottled float64 `json:"dateLastThrottled"`
+}
+
+// RateLimitJSON is returned by GET /system/ratelimit
+type RateLimitJSON struct {
+	Limits    RateLimits           `json:"limits"`
+	Throttled []RateLimitThrottled `json:"throttled"`
+}
+
+// SystemRateLimit returns rate limits on messages creation, and users and
+// topics throttled during last hour, only for tat admin
+func (c *Client) SystemRateLimit() (*RateLimitJSON, error) {
+	if c == nil {
+		return nil, ErrClientNotInitiliazed
+	}
+	body, err := c.simpleGetAndGetBytes("/system/ratelimit")
+	if err != nil {
+		ErrorLogFunc("Error getting rate limits: %s", err)
+		return nil, err
+	}
+
+	out := &RateLimitJSON{}
+	if err := json.Unmarshal(body, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,39 @@
+package tat
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientRetryAfter(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error":"Rate limit of 10 messages per minute exceeded on user foo, retry in 1s"}`))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"message":{"_id":"abc","text":"a text"}}`))
+	}))
+	defer ts.Close()
+
+	c, err := NewClient(Options{URL: ts.URL, Username: "foo", Password: "bar", MaxTries: 2})
+	assert.Nil(t, err)
+
+	out, err := c.MessageAdd(MessageJSON{Topic: "/Internal/foo", Text: "a text"})
+	assert.Nil(t, err)
+	assert.Equal(t, "abc", out.Message.ID)
+	assert.Equal(t, 2, calls)
+
+	calls = 0
+	c, _ = NewClient(Options{URL: ts.URL, Username: "foo", Password: "bar", MaxTries: 1})
+	_, err = c.MessageAdd(MessageJSON{Topic: "/Internal/foo", Text: "a text"})
+	assert.NotNil(t, err, "no retry with MaxTries 1")
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,62 @@
+package tat
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ScheduledMessage is a message waiting to be posted on its topic at DateScheduled
+type ScheduledMessage struct {
+	ID            string                 `bson:"_id"           json:"_id"`
+	Topic         string                 `bson:"topic"         json:"topic"`
+	InReplyOfID   string                 `bson:"inReplyOfID"   json:"inReplyOfID,omitempty"`
+	Text          string                 `bson:"text"          json:"text"`
+	Labels        []Label                `bson:"labels"        json:"labels,omitempty"`
+	Replies       []string               `bson:"replies"       json:"replies,omitempty"`
+	Messages      []MessageJSON          `bson:"messages"      json:"messages,omitempty"`
+	Fields        map[string]interface{} `bson:"fields,omitempty" json:"fields,omitempty"`
+	Author        Author                 `bson:"author"        json:"author"`
+	DateCreation  float64                `bson:"dateCreation"  json:"dateCreation"`
+	DateScheduled float64                `bson:"dateScheduled" json:"dateScheduled"`
+	// Error is set if message could not be posted at DateScheduled, it will not be retried
+	Error     string  `bson:"error,omitempty"     json:"error,omitempty"`
+	DateLease float64 `bson:"dateLease,omitempty" json:"-"`
+}
+
+// ScheduledMessagesJSON is used by GET /scheduled
+type ScheduledMessagesJSON struct {
+	Messages []ScheduledMessage `json:"messages"`
+}
+
+// MessageScheduledList returns messages scheduled by current user, not yet posted
+func (c *Client) MessageScheduledList() (*ScheduledMessagesJSON, error) {
+	if c == nil {
+		return nil, ErrClientNotInitiliazed
+	}
+
+	body, err := c.simpleGetAndGetBytes("/scheduled")
+	if err != nil {
+		ErrorLogFunc("Error getting scheduled messages: %s", err)
+		return nil, err
+	}
+
+	out := &ScheduledMessagesJSON{}
+	if err := json.Unmarshal(body, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MessageScheduledCancel cancels a scheduled message, before it's posted
+func (c *Client) MessageScheduledCancel(idScheduled string) ([]byte, error) {
+	if c == nil {
+		return nil, ErrClientNotInitiliazed
+	}
+
+	out, err := c.simpleDeleteAndGetBytes(fmt.Sprintf("/scheduled/%s", idScheduled), 200, nil)
+	if err != nil {
+		ErrorLogFunc("Error cancelling scheduled message %s: %s", idScheduled, err)
+		return nil, err
+	}
+	return out, nil
+}
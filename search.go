@@ -0,0 +1,70 @@
+package tat
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// highlightSnippetLength is the number of characters kept around a term in a snippet
+const highlightSnippetLength = 40
+
+// GetTextSearchTerms returns terms and phrases of a textSearch, as used by
+// MongoDB: "a phrase" is one term, -word excludes word and is not returned
+func GetTextSearchTerms(textSearch string) []string {
+	terms := []string{}
+	for i, part := range strings.Split(textSearch, "\"") {
+		if i%2 == 1 { // inside a phrase
+			if p := strings.TrimSpace(part); p != "" {
+				terms = append(terms, p)
+			}
+			continue
+		}
+		for _, w := range strings.Fields(part) {
+			if strings.HasPrefix(w, "-") {
+				continue
+			}
+			terms = append(terms, w)
+		}
+	}
+	return terms
+}
+
+// HighlightText returns snippets of text containing terms of textSearch,
+// each term found is surrounded by **
+func HighlightText(text, textSearch string) []string {
+	snippets := []string{}
+	lower := strings.ToLower(text)
+	for _, term := range GetTextSearchTerms(textSearch) {
+		idx := strings.Index(text, term)
+		if len(lower) == len(text) {
+			idx = strings.Index(lower, strings.ToLower(term))
+		}
+		if idx < 0 {
+			continue
+		}
+		// extends term to the end of the word, as search uses stemming
+		end := idx + len(term)
+		for end < len(text) && !unicode.IsSpace(rune(text[end])) && !unicode.IsPunct(rune(text[end])) {
+			end++
+		}
+		start := idx - highlightSnippetLength
+		prefix := "..."
+		if start <= 0 {
+			start, prefix = 0, ""
+		}
+		for start > 0 && !utf8.RuneStart(text[start]) {
+			start--
+		}
+		stop := end + highlightSnippetLength
+		suffix := "..."
+		if stop >= len(text) {
+			stop, suffix = len(text), ""
+		}
+		for stop < len(text) && !utf8.RuneStart(text[stop]) {
+			stop++
+		}
+		snippets = append(snippets, prefix+text[start:idx]+"**"+text[idx:end]+"**"+text[end:stop]+suffix)
+	}
+	return snippets
+}
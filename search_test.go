@@ -0,0 +1,23 @@
+package tat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetTextSearchTerms(t *testing.T) {
+	assert.Equal(t, []string{"build", "failed"}, GetTextSearchTerms("build failed"))
+	assert.Equal(t, []string{"deploy", "prod eu"}, GetTextSearchTerms(`deploy "prod eu" -staging`))
+	assert.Equal(t, []string{}, GetTextSearchTerms("  "))
+}
+
+func TestHighlightText(t *testing.T) {
+	assert.Equal(t, []string{"the **Building** of #1234 is ok"}, HighlightText("the Building of #1234 is ok", "build"))
+	assert.Equal(t, []string{}, HighlightText("nothing here", "build"))
+
+	text := "a very long text to check that snippet is cut before and after the word, we are searching here, and after that nothing"
+	snippets := HighlightText(text, "word")
+	assert.Equal(t, 1, len(snippets))
+	assert.Equal(t, "...hat snippet is cut before and after the **word**, we are searching here, and after that ...", snippets[0])
+}
@@ -0,0 +1,78 @@
+package tat
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// MessageStream subscribes to events on messages of topics, matching criteria.
+// handler is called on each event. MessageStream returns when tat closes the
+// stream (client should call it again) or when handler returns an error.
+func (c *Client) MessageStream(topics []string, criteria *MessageCriteria, handler func(*HookMessageJSON) error) error {
+	if c == nil {
+		return ErrClientNotInitiliazed
+	}
+	if len(topics) == 0 {
+		return fmt.Errorf("Invalid topics")
+	}
+	if criteria == nil {
+		criteria = &MessageCriteria{}
+	}
+
+	path := fmt.Sprintf("%s/stream%s?%s", c.url, topics[0], criteria.GetURL())
+	if len(topics) > 1 {
+		path += "&topics=" + url.QueryEscape(strings.Join(topics[1:], ","))
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	c.initHeaders(req)
+	req.Header.Set("Accept", "text/event-stream")
+
+	// no request timeout here, stream is closed by tat
+	streamClient := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: c.sslInsecureSkipVerify},
+		},
+	}
+	resp, err := streamClient.Do(req)
+	if err != nil {
+		ErrorLogFunc("MessageStream: error while requesting tat: %s", err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("Response code:%d (want:%d) with Body:%s", resp.StatusCode, http.StatusOK, string(body))
+	}
+	DebugLogFunc("MessageStream: stream opened on %s", path)
+
+	var event string
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "event:") {
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+			continue
+		}
+		if !strings.HasPrefix(line, "data:") || event == "heartbeat" {
+			continue
+		}
+		h := &HookMessageJSON{}
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data:")), h); err != nil {
+			ErrorLogFunc("MessageStream: error while reading event %s: %s", event, err)
+			continue
+		}
+		if err := handler(h); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,147 @@
+package tat
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// States of a task
+const (
+	TaskStateTodo    = "todo"
+	TaskStateDoing   = "doing"
+	TaskStateDone    = "done"
+	TaskStateBlocked = "blocked"
+)
+
+// TaskStates contains all possible states of a task
+var TaskStates = []string{TaskStateTodo, TaskStateDoing, TaskStateDone, TaskStateBlocked}
+
+// Task is the assignment of a message to users or groups
+type Task struct {
+	Assignees       []string `bson:"assignees"       json:"assignees,omitempty"` // usernames
+	Groups          []string `bson:"groups"          json:"groups,omitempty"`    // groupnames, all members of groups are assignees
+	State           string   `bson:"state"           json:"state"`
+	DateDue         float64  `bson:"dateDue"         json:"dateDue,omitempty"` // 0 if no due date
+	AssignedBy      string   `bson:"assignedBy"      json:"assignedBy,omitempty"`
+	DateAssign      float64  `bson:"dateAssign"      json:"dateAssign,omitempty"`
+	OverdueNotified bool     `bson:"overdueNotified" json:"overdueNotified,omitempty"` // overdue hook already sent
+}
+
+// TasksJSON is used by GET /user/me/tasks
+type TasksJSON struct {
+	Messages []Message `json:"messages"`
+}
+
+// CheckTaskState returns an error if state is not one of TaskStates
+func CheckTaskState(state string) error {
+	if !ArrayContains(TaskStates, state) {
+		return fmt.Errorf("Invalid task state %s, possible values: %s", state, strings.Join(TaskStates, ", "))
+	}
+	return nil
+}
+
+// IsOverdue returns true if task has a due date before now and is not done
+func (t *Task) IsOverdue(now float64) bool {
+	return t != nil && t.DateDue > 0 && t.DateDue < now && t.State != TaskStateDone
+}
+
+// IsAssignedTo returns true if message is a task assigned to username,
+// directly or through one of groups
+func (m *Message) IsAssignedTo(username string, groups []string) bool {
+	if m.Task == nil {
+		return false
+	}
+	return ArrayContains(m.Task.Assignees, username) || ItemInBothArrays(m.Task.Groups, groups)
+}
+
+// FormatTask returns state, assignees and due date of task of message,
+// ex: doing to:yesnault,team-ops due:Oct 20 18:00:00
+func (m *Message) FormatTask() string {
+	if m.Task == nil {
+		return ""
+	}
+	out := m.Task.State
+	if to := append(append([]string{}, m.Task.Assignees...), m.Task.Groups...); len(to) > 0 {
+		out += " to:" + strings.Join(to, ",")
+	}
+	if m.Task.DateDue > 0 {
+		out += " due:" + time.Unix(int64(m.Task.DateDue), 0).Format(time.Stamp)
+	}
+	return out
+}
+
+// MessageAssign assigns a message to users or groups, with an optional due date and state.
+// Message is assigned to current user if task has no assignee and no group
+func (c *Client) MessageAssign(topic, idMessage string, task Task) (*MessageJSONOut, error) {
+	if c == nil {
+		return nil, ErrClientNotInitiliazed
+	}
+
+	message := MessageJSON{
+		Action:      MessageActionAssign,
+		Topic:       topic,
+		IDReference: idMessage,
+		Task:        &task,
+	}
+	return c.processForMessageJSONOut("PUT", "/message"+message.Topic, 201, message)
+}
+
+// MessageUnassign removes task from a message
+func (c *Client) MessageUnassign(topic, idMessage string) (*MessageJSONOut, error) {
+	if c == nil {
+		return nil, ErrClientNotInitiliazed
+	}
+
+	message := MessageJSON{
+		Action:      MessageActionUnassign,
+		Topic:       topic,
+		IDReference: idMessage,
+	}
+	return c.processForMessageJSONOut("PUT", "/message"+message.Topic, 201, message)
+}
+
+// MessageTaskState changes state of the task of a message: todo, doing, done or blocked
+func (c *Client) MessageTaskState(topic, idMessage, state string) (*MessageJSONOut, error) {
+	if c == nil {
+		return nil, ErrClientNotInitiliazed
+	}
+
+	message := MessageJSON{
+		Action:      MessageActionTaskState,
+		Topic:       topic,
+		IDReference: idMessage,
+		Task:        &Task{State: state},
+	}
+	return c.processForMessageJSONOut("PUT", "/message"+message.Topic, 201, message)
+}
+
+// UserTasks returns tasks assigned to current user, directly or through a group,
+// on all topics readable by user, ordered by due date. Tasks without due date are
+// returned last. states filters on states of tasks, all tasks not done if empty
+func (c *Client) UserTasks(criteria *MessageCriteria, states ...string) (*TasksJSON, error) {
+	if c == nil {
+		return nil, ErrClientNotInitiliazed
+	}
+	if criteria == nil {
+		criteria = &MessageCriteria{Skip: 0, Limit: 100}
+	}
+
+	path := fmt.Sprintf("/user/me/tasks?%s", criteria.GetURL())
+	if len(states) > 0 {
+		path += "&state=" + url.QueryEscape(strings.Join(states, ","))
+	}
+	body, err := c.simpleGetAndGetBytes(path)
+	if err != nil {
+		ErrorLogFunc("Error getting tasks: %s", err)
+		return nil, err
+	}
+
+	out := &TasksJSON{}
+	if err := json.Unmarshal(body, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
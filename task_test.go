@@ -0,0 +1,41 @@
+package tat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskIsOverdue(t *testing.T) {
+	now := float64(1484555263)
+	var task *Task
+	assert.False(t, task.IsOverdue(now), "not a task")
+
+	task = &Task{State: TaskStateTodo}
+	assert.False(t, task.IsOverdue(now), "no due date")
+
+	task.DateDue = now - 60
+	assert.True(t, task.IsOverdue(now))
+
+	task.State = TaskStateDone
+	assert.False(t, task.IsOverdue(now), "done")
+
+	task = &Task{State: TaskStateBlocked, DateDue: now + 60}
+	assert.False(t, task.IsOverdue(now), "due later")
+}
+
+func TestMessageIsAssignedTo(t *testing.T) {
+	m := Message{}
+	assert.False(t, m.IsAssignedTo("foo", nil))
+	assert.Equal(t, "", m.FormatTask())
+
+	m.Task = &Task{Assignees: []string{"foo"}, Groups: []string{"ops"}, State: TaskStateDoing}
+	assert.True(t, m.IsAssignedTo("foo", nil))
+	assert.True(t, m.IsAssignedTo("bar", []string{"dev", "ops"}))
+	assert.False(t, m.IsAssignedTo("bar", []string{"dev"}))
+	assert.True(t, m.IsDoing())
+	assert.Equal(t, "doing to:foo,ops", m.FormatTask())
+
+	assert.Nil(t, CheckTaskState(TaskStateBlocked))
+	assert.NotNil(t, CheckTaskState("wip"))
+}
@@ -3,6 +3,7 @@ package message
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/ovh/tat"
 	"github.com/ovh/tat/tatcli/internal"
@@ -11,21 +12,34 @@ import (
 
 var cmdLabel []string
 
+var cmdAttach []string
+
+var cmdFields []string
+
 var (
 	dateCreation int
+	at           string
+	in           string
 )
 
 func init() {
 	cmdMessageAdd.Flags().IntVarP(&dateCreation, "dateCreation", "", -1, "Force date creation, only for system user")
 	cmdMessageAdd.Flags().StringSliceVar(&cmdLabel, "label", nil, "add labels : --label=\"#EEEE;myLabel1,#EEEE;myLabel2\"")
+	cmdMessageAdd.Flags().StringVarP(&at, "at", "", "", "Post message later, at this date: --at=\"2017-03-21 14:00\" or RFC3339 date")
+	cmdMessageAdd.Flags().StringVarP(&in, "in", "", "", "Post message later, after this duration: --in=1h30m")
+	cmdMessageAdd.Flags().StringSliceVar(&cmdAttach, "attach", nil, "attach files : --attach=build.log,screenshot.png")
+	cmdMessageAdd.Flags().StringSliceVar(&cmdFields, "field", nil, "add fields : --field=build=1234,env=prod")
 }
 
 var cmdMessageAdd = &cobra.Command{
 	Use:     "add",
 	Aliases: []string{"a"},
-	Short:   "tatcli message add [--dateCreation=timestamp] <topic> <my message>",
+	Short:   "tatcli message add [--dateCreation=timestamp] [--attach=file] [--field=name=value] [--at=date|--in=duration] <topic> <my message>",
 	Long: `Add a message to a Topic:
 		tatcli message add /Private/firstname.lastname my new messsage
+		tatcli message add --attach=build.log /Private/firstname.lastname build failed
+		tatcli message add --in=1h /Internal/Release freeze starts in 1h
+		tatcli message add --field=build=1234,env=prod /Builds build ok
 		`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) >= 2 {
@@ -48,6 +62,13 @@ func Create(topic, message string) (*tat.MessageJSONOut, error) {
 	if dateCreation > 0 {
 		m.DateCreation = float64(dateCreation)
 	}
+	if at != "" || in != "" {
+		d, err := dateScheduled(at, in)
+		if err != nil {
+			return nil, err
+		}
+		m.DateScheduled = tat.TSFromDate(d)
+	}
 	for _, label := range cmdLabel {
 		s := strings.Split(label, ";")
 		if len(s) == 2 {
@@ -57,5 +78,38 @@ func Create(topic, message string) (*tat.MessageJSONOut, error) {
 		}
 	}
 
+	if len(cmdFields) > 0 {
+		fields, err := parseFields(cmdFields)
+		if err != nil {
+			return nil, err
+		}
+		m.Fields = fields
+	}
+
+	if len(cmdAttach) > 0 {
+		return internal.Client().MessageAddWithAttachments(m, cmdAttach...)
+	}
 	return internal.Client().MessageAdd(m)
 }
+
+// dateScheduled returns date to post message, from --at or --in flags
+func dateScheduled(at, in string) (time.Time, error) {
+	if at != "" && in != "" {
+		return time.Time{}, fmt.Errorf("Invalid arguments, --at and --in can't be used together")
+	}
+	if in != "" {
+		d, err := time.ParseDuration(in)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("Invalid argument --in=%s: %s", in, err)
+		}
+		return time.Now().Add(d), nil
+	}
+	if d, err := time.ParseInLocation("2006-01-02 15:04", at, time.Local); err == nil {
+		return d, nil
+	}
+	d, err := time.Parse(time.RFC3339, at)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid argument --at=%s, use \"2006-01-02 15:04\" or RFC3339 date", at)
+	}
+	return d, nil
+}
@@ -0,0 +1,35 @@
+package message
+
+import (
+	"github.com/ovh/tat"
+	"github.com/ovh/tat/tatcli/internal"
+	"github.com/spf13/cobra"
+)
+
+// addBulkFlags adds flags selecting messages updated by a bulk command
+func addBulkFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVarP(&criteria.Skip, "skip", "", 0, "Skip first messages matching criteria")
+	cmd.Flags().IntVarP(&criteria.Limit, "limit", "", 100, "Max number of messages to update, 1000 max")
+	cmd.Flags().StringVarP(&criteria.IDMessage, "idMessage", "", "", "Search by IDMessage")
+	cmd.Flags().StringVarP(&criteria.Text, "text", "", "", "Search by text")
+	cmd.Flags().StringVarP(&criteria.Label, "label", "", "", "Search by label: could be labelA,labelB")
+	cmd.Flags().StringVarP(&criteria.StartLabel, "startLabel", "", "", "Search by a label prefix: --startLabel='mykey:,myKey2:'")
+	cmd.Flags().StringVarP(&criteria.NotLabel, "notLabel", "", "", "Search by label (exclude): could be labelA,labelB")
+	cmd.Flags().StringVarP(&criteria.AndLabel, "andLabel", "", "", "Search by label (and) : could be labelA,labelB")
+	cmd.Flags().StringVarP(&criteria.Tag, "tag", "", "", "Search by tag : could be tagA,tagB")
+	cmd.Flags().StringVarP(&criteria.NotTag, "notTag", "", "", "Search by tag (exclude) : could be tagA,tagB")
+	cmd.Flags().StringVarP(&criteria.AndTag, "andTag", "", "", "Search by tag (and) : could be tagA,tagB")
+	cmd.Flags().StringVarP(&criteria.Username, "username", "", "", "Search by username : could be usernameA,usernameB")
+	cmd.Flags().StringVarP(&criteria.DateMinCreation, "dateMinCreation", "", "", "Search by dateCreation (timestamp), select messages where dateCreation >= dateMinCreation")
+	cmd.Flags().StringVarP(&criteria.DateMaxCreation, "dateMaxCreation", "", "", "Search by dateCreation (timestamp), select messages where dateCreation <= dateMaxCreation")
+	cmd.Flags().StringVarP(&criteria.DateMinUpdate, "dateMinUpdate", "", "", "Search by dateUpdate (timestamp), select messages where dateUpdate >= dateMinUpdate")
+	cmd.Flags().StringVarP(&criteria.DateMaxUpdate, "dateMaxUpdate", "", "", "Search by dateUpdate (timestamp), select messages where dateUpdate <= dateMaxUpdate")
+	cmd.Flags().StringVarP(&criteria.OnlyMsgRoot, "onlyMsgRoot", "", "", "--onlyMsgRoot=true: restricts to root message only (inReplyOfIDRoot empty)")
+}
+
+// runBulk runs action on messages matching criteria and prints summary
+func runBulk(topic string, message tat.MessageJSON) {
+	out, err := internal.Client().MessagesUpdateBulk(topic, criteria, message)
+	internal.Check(err)
+	internal.Print(out)
+}
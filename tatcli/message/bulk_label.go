@@ -0,0 +1,37 @@
+package message
+
+import (
+	"strings"
+
+	"github.com/ovh/tat"
+	"github.com/ovh/tat/tatcli/internal"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	addBulkFlags(cmdMessageBulkLabel)
+}
+
+var cmdMessageBulkLabel = &cobra.Command{
+	Use:   "bulk-label",
+	Short: "Add a label to all messages matching criteria: tatcli message bulk-label <topic> <colorInHexa> <my Label> [--label=AL] [--limit=100]",
+	Long: `Add a label to all messages matching criteria:
+	tatcli message bulk-label <topic> <colorInHexa> <my Label> [criteria flags]
+	Example in bash:
+	tatcli message bulk-label /MyTopic \#EEEEEE release-1.2 --tag=release-1.2 --limit=500
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 3 {
+			internal.Exit("Invalid argument to add a label on messages: tatcli message bulk-label --help\n")
+		}
+		color := args[1]
+		if !strings.HasPrefix(color, "#") {
+			color = "#" + color
+		}
+		runBulk(args[0], tat.MessageJSON{
+			Action: tat.MessageActionLabel,
+			Text:   strings.Join(args[2:], " "),
+			Option: color,
+		})
+	},
+}
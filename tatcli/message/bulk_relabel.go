@@ -0,0 +1,45 @@
+package message
+
+import (
+	"strings"
+
+	"github.com/ovh/tat"
+	"github.com/ovh/tat/tatcli/internal"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	addBulkFlags(cmdMessageBulkRelabel)
+	cmdMessageBulkRelabel.Flags().StringSliceVar(&cmdOptions, "options", nil, "remove only theses Labels on relabel : --options=\"myLabelToRemove1,myLabelToRemove2\"")
+}
+
+var cmdMessageBulkRelabel = &cobra.Command{
+	Use:   "bulk-relabel",
+	Short: "Remove labels and add new ones to all messages matching criteria: tatcli message bulk-relabel <topic> \"#EEEE;myLabel1,#EEEE;myLabel2\" [--options=\"myLabelToRemove1\"] [--label=AL] [--limit=100]",
+	Long: `Remove all labels and add new ones to all messages matching criteria:
+	tatcli message bulk-relabel <topic> "#EEEE;myLabel1,#EEEE;myLabel2" [criteria flags]
+	Example in bash, close all AL alerts:
+	tatcli message bulk-relabel /MyTopic "#14892c;UP" --options="AL" --label=AL --limit=500
+
+	Without --options, all labels of messages are removed before adding new ones.
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 2 {
+			internal.Exit("Invalid argument to 'bulk-relabel': tatcli message bulk-relabel --help\n")
+		}
+		labels := []tat.Label{}
+		for _, label := range strings.Split(args[1], ",") {
+			s := strings.Split(label, ";")
+			if len(s) == 2 {
+				labels = append(labels, tat.Label{Text: s[1], Color: s[0]})
+			} else {
+				internal.Exit("Invalid argument label %s to 'bulk-relabel': tatcli msg bulk-relabel --help\n", label)
+			}
+		}
+		runBulk(args[0], tat.MessageJSON{
+			Action:  tat.MessageActionRelabel,
+			Labels:  labels,
+			Options: cmdOptions,
+		})
+	},
+}
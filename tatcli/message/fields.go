@@ -0,0 +1,60 @@
+package message
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ovh/tat"
+	"github.com/ovh/tat/tatcli/internal"
+	"github.com/spf13/cobra"
+)
+
+var cmdFieldsRemove []string
+
+func init() {
+	cmdMessageFields.Flags().StringSliceVar(&cmdFieldsRemove, "remove", nil, "remove fields: --remove=sha,env")
+}
+
+var cmdMessageFields = &cobra.Command{
+	Use:   "fields",
+	Short: "Set fields on a message: tatcli message fields [--remove=<name>,...] <topic> <idMessage> [<name>=<value>]...",
+	Long: `Set fields on a message, other fields are kept. A value is a number, true, false
+or a string. Use double quotes to force a string:
+		tatcli message fields /Builds idMessage build=1234 env=prod sha='"1234"'
+		tatcli message fields --remove=sha /Builds idMessage
+		`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 2 || (len(args) == 2 && len(cmdFieldsRemove) == 0) {
+			internal.Exit("Invalid argument to set fields on a message: tatcli message fields --help\n")
+		}
+		fields, err := parseFields(args[2:])
+		internal.Check(err)
+		for _, name := range cmdFieldsRemove {
+			fields[name] = nil
+		}
+		out, err := internal.Client().MessageSetFields(args[0], args[1], fields)
+		internal.Check(err)
+		if internal.Verbose {
+			internal.Print(out)
+		}
+	},
+}
+
+// parseFields returns fields from name=value arguments, see tat.ParseFieldValue.
+// A value between double quotes is always a string
+func parseFields(args []string) (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	for _, arg := range args {
+		i := strings.Index(arg, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("Invalid field %s, use name=value", arg)
+		}
+		name, value := arg[:i], arg[i+1:]
+		if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+			fields[name] = value[1 : len(value)-1]
+		} else {
+			fields[name] = tat.ParseFieldValue(value)
+		}
+	}
+	return fields, nil
+}
@@ -28,6 +28,11 @@ func init() {
 	cmdMessageList.Flags().StringVarP(&criteria.InReplyOfIDRoot, "inReplyOfIDRoot", "", "", "Search by IDMessage IdRoot")
 	cmdMessageList.Flags().StringVarP(&criteria.AllIDMessage, "allIDMessage", "", "", "Search in All ID Message (idMessage, idReply, idRoot)")
 	cmdMessageList.Flags().StringVarP(&criteria.Text, "text", "", "", "Search by text")
+	cmdMessageList.Flags().StringVarP(&criteria.TextSearch, "textSearch", "", "", `Full-text search, with stemming: --textSearch='build failed'. Use "a phrase" for phrase search, -word to exclude a word. Use with --sortBy=relevance to sort by score`)
+	cmdMessageList.Flags().StringVarP(&criteria.Cursor, "cursor", "", "", "nextCursor returned by previous page, to get next page")
+	cmdMessageList.Flags().StringVarP(&criteria.Reaction, "reaction", "", "", "Search messages with one of these reactions: --reaction=+1,ack")
+	cmdMessageList.Flags().StringVarP(&criteria.Pinned, "pinned", "", "", "Search only pinned messages of topic: --pinned=true")
+	cmdMessageList.Flags().StringVarP(&criteria.Field, "field", "", "", "Search by fields, with =, !=, >, >=, <, <=: --field='build>=1200,build<1300,env=prod'")
 	cmdMessageList.Flags().StringVarP(&criteria.Topic, "topic", "", "", "Search by topic")
 	cmdMessageList.Flags().StringVarP(&criteria.Label, "label", "", "", "Search by label: could be labelA,labelB")
 	cmdMessageList.Flags().StringVarP(&criteria.StartLabel, "startLabel", "", "", "Search by a label prefix: --startLabel='mykey:,myKey2:'")
@@ -65,7 +70,7 @@ func init() {
 	cmdMessageList.Flags().StringVarP(&criteria.OnlyMsgRoot, "onlyMsgRoot", "", "", "--onlyMsgRoot=true: restricts to root message only (inReplyOfIDRoot empty). If treeView is used, limit search criteria to root message, replies are still given, independently of search criteria.")
 	cmdMessageList.Flags().StringVarP(&criteria.OnlyMsgReply, "onlyMsgReply", "", "", "--onlyMsgReply=true: restricts to reply message only (inReplyOfIDRoot not empty). If treeView is used, limit search criteria to reply, messages root are still given, independently of search criteria.")
 	cmdMessageList.Flags().StringVarP(&criteria.OnlyCount, "onlyCount", "", "", "--onlyCount=true: only count messages, without retrieve msg. limit, skip, treeview criterias are ignored.")
-	cmdMessageList.Flags().StringVarP(&criteria.SortBy, "sortBy", "", "", "--sortBy=-dateCreation: sort message. Use '-' to reverse sort. Default is --sortBy=-dateCreation. You can use: text, topic, inReplyOfID, inReplyOfIDRoot, nbLikes, labels, likers, votersUP, votersDown, nbVotesUP, nbVotesDown, userMentions, urls, tags, dateCreation, dateUpdate, author, nbReplies")
+	cmdMessageList.Flags().StringVarP(&criteria.SortBy, "sortBy", "", "", "--sortBy=-dateCreation: sort message. Use '-' to reverse sort. Default is --sortBy=-dateCreation. You can use: relevance (with --textSearch), text, topic, inReplyOfID, inReplyOfIDRoot, nbLikes, labels, likers, votersUP, votersDown, nbVotesUP, nbVotesDown, userMentions, urls, tags, dateCreation, dateUpdate, author, nbReplies")
 	cmdMessageList.Flags().BoolVarP(&stream, "stream", "s", false, "stream messages --stream. Request tat each 10s, default sort: dateUpdate")
 	cmdMessageList.Flags().BoolVarP(&streamQuiet, "streamQuiet", "", false, "stream messages --stream --streamQuiet. Do not display error, but exec --execErr if necessary")
 	cmdMessageList.Flags().StringSliceVarP(&execMsg, "exec", "", nil, `--stream required. Exec a cmd on each new message: --stream --exec 'myLights --pulse blue --duration=1000' With only --onlyMsgCount=true : --exec min:max:cmda --exec min:max:cmdb, example: --exec 0:4:'cmdA' --exec 5::'cmdb'`)
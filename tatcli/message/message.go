@@ -9,6 +9,8 @@ func init() {
 	Cmd.AddCommand(cmdMessageReply)
 	Cmd.AddCommand(cmdMessageDelete)
 	Cmd.AddCommand(cmdMessageDeleteBulk)
+	Cmd.AddCommand(cmdMessageTrash)
+	Cmd.AddCommand(cmdMessageUndelete)
 	Cmd.AddCommand(cmdMessageUpdate)
 	Cmd.AddCommand(cmdMessageConcat)
 	Cmd.AddCommand(cmdMessageMove)
@@ -16,6 +18,11 @@ func init() {
 	Cmd.AddCommand(cmdMessageUntask)
 	Cmd.AddCommand(cmdMessageLike)
 	Cmd.AddCommand(cmdMessageUnlike)
+	Cmd.AddCommand(cmdMessageReact)
+	Cmd.AddCommand(cmdMessageUnreact)
+	Cmd.AddCommand(cmdMessagePin)
+	Cmd.AddCommand(cmdMessageUnpin)
+	Cmd.AddCommand(cmdMessageFields)
 	Cmd.AddCommand(cmdMessageVoteUP)
 	Cmd.AddCommand(cmdMessageVoteDown)
 	Cmd.AddCommand(cmdMessageUnVoteUP)
@@ -23,7 +30,11 @@ func init() {
 	Cmd.AddCommand(cmdMessageLabel)
 	Cmd.AddCommand(cmdMessageUnlabel)
 	Cmd.AddCommand(cmdMessageRelabel)
+	Cmd.AddCommand(cmdMessageBulkLabel)
+	Cmd.AddCommand(cmdMessageBulkRelabel)
 	Cmd.AddCommand(cmdMessageList)
+	Cmd.AddCommand(cmdMessageScheduled)
+	Cmd.AddCommand(cmdMessageUnschedule)
 }
 
 // Cmd message
@@ -0,0 +1,22 @@
+package message
+
+import (
+	"github.com/ovh/tat/tatcli/internal"
+	"github.com/spf13/cobra"
+)
+
+var cmdMessagePin = &cobra.Command{
+	Use:   "pin",
+	Short: "Pin a message on top of its topic, topic admins only: tatcli message pin <topic> <idMessage>",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 2 {
+			out, err := internal.Client().MessagePin(args[0], args[1])
+			internal.Check(err)
+			if internal.Verbose {
+				internal.Print(out)
+			}
+		} else {
+			internal.Exit("Invalid argument to pin a message: tatcli message pin --help\n")
+		}
+	},
+}
@@ -0,0 +1,22 @@
+package message
+
+import (
+	"github.com/ovh/tat/tatcli/internal"
+	"github.com/spf13/cobra"
+)
+
+var cmdMessageReact = &cobra.Command{
+	Use:   "react",
+	Short: "React on a message: tatcli message react <topic> <idMessage> <reaction>",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 3 {
+			out, err := internal.Client().MessageReact(args[0], args[1], args[2])
+			internal.Check(err)
+			if internal.Verbose {
+				internal.Print(out)
+			}
+		} else {
+			internal.Exit("Invalid argument to react on a message: tatcli message react --help\n")
+		}
+	},
+}
@@ -0,0 +1,31 @@
+package message
+
+import (
+	"github.com/ovh/tat/tatcli/internal"
+	"github.com/spf13/cobra"
+)
+
+var cmdMessageScheduled = &cobra.Command{
+	Use:   "scheduled",
+	Short: "List your scheduled messages, not yet posted: tatcli message scheduled",
+	Run: func(cmd *cobra.Command, args []string) {
+		out, err := internal.Client().MessageScheduledList()
+		internal.Check(err)
+		internal.Print(out)
+	},
+}
+
+var cmdMessageUnschedule = &cobra.Command{
+	Use:   "unschedule",
+	Short: "Cancel a scheduled message: tatcli message unschedule <idScheduled>",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			internal.Exit("Invalid argument to cancel a scheduled message: tatcli message unschedule --help\n")
+		}
+		out, err := internal.Client().MessageScheduledCancel(args[0])
+		internal.Check(err)
+		if internal.Verbose {
+			internal.Print(out)
+		}
+	},
+}
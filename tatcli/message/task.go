@@ -1,24 +1,89 @@
 package message
 
 import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ovh/tat"
 	"github.com/ovh/tat/tatcli/internal"
 	"github.com/spf13/cobra"
 )
 
+var (
+	cmdTaskAssign   []string
+	cmdTaskGroups   []string
+	cmdTaskDue      string
+	cmdTaskState    string
+	cmdTaskUnassign bool
+)
+
+func init() {
+	cmdMessageTask.Flags().StringSliceVar(&cmdTaskAssign, "assign", nil, "assign message to users: --assign=userA,userB")
+	cmdMessageTask.Flags().StringSliceVar(&cmdTaskGroups, "group", nil, "assign message to groups: --group=groupA,groupB")
+	cmdMessageTask.Flags().StringVar(&cmdTaskDue, "due", "", "due date of task: 2017-01-31, 2017-01-31T18:00, a duration from now as 48h or a timestamp")
+	cmdMessageTask.Flags().StringVar(&cmdTaskState, "state", "", "state of task: todo, doing, done or blocked")
+	cmdMessageTask.Flags().BoolVar(&cmdTaskUnassign, "unassign", false, "remove task from message")
+}
+
 var cmdMessageTask = &cobra.Command{
 	Use:   "task",
-	Short: "Create a task from one message: tatcli message task /Private/username/tasks/sub-topic idMessage",
-	Long: `Create a task from one message:
-	tatcli message task /Private/username/tasks/sub-topic idMessage`,
+	Short: "Create a task from one message: tatcli message task [--assign=<user>,...] [--group=<group>,...] [--due=<date>] [--state=<state>] <topic> <idMessage>",
+	Long: `Create a task from one message, in your tasks topic:
+	tatcli message task /Private/username/tasks/sub-topic idMessage
+
+Assign a message to you, to other users or to groups, with an optional due date and state:
+	tatcli message task --assign=userA --due=2017-01-31T18:00 /Internal/Ops idMessage
+	tatcli message task --group=ops --due=48h --state=doing /Internal/Ops idMessage
+
+Change state of a task: todo, doing, done or blocked:
+	tatcli message task --state=done /Internal/Ops idMessage
+
+Remove task from a message:
+	tatcli message task --unassign /Internal/Ops idMessage
+	`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if len(args) == 2 {
-			out, err := internal.Client().MessageTask(args[0], args[1])
-			internal.Check(err)
-			if internal.Verbose {
-				internal.Print(out)
-			}
-		} else {
+		if len(args) != 2 {
 			internal.Exit("Invalid argument to task a message: tatcli message task --help\n")
 		}
+		var out *tat.MessageJSONOut
+		var err error
+		switch {
+		case cmdTaskUnassign:
+			out, err = internal.Client().MessageUnassign(args[0], args[1])
+		case len(cmdTaskAssign) > 0 || len(cmdTaskGroups) > 0 || cmdTaskDue != "":
+			task := tat.Task{Assignees: cmdTaskAssign, Groups: cmdTaskGroups, State: cmdTaskState}
+			task.DateDue, err = parseDue(cmdTaskDue)
+			internal.Check(err)
+			out, err = internal.Client().MessageAssign(args[0], args[1], task)
+		case cmdTaskState != "":
+			out, err = internal.Client().MessageTaskState(args[0], args[1], cmdTaskState)
+		default:
+			out, err = internal.Client().MessageTask(args[0], args[1])
+		}
+		internal.Check(err)
+		if internal.Verbose {
+			internal.Print(out)
+		}
 	},
 }
+
+// parseDue returns due date as timestamp: a date, a date and a time, a
+// duration from now or a timestamp. 0 if due is empty
+func parseDue(due string) (float64, error) {
+	if due == "" {
+		return 0, nil
+	}
+	if ts, err := strconv.ParseFloat(due, 64); err == nil {
+		return ts, nil
+	}
+	if d, err := time.ParseDuration(due); err == nil {
+		return float64(time.Now().Add(d).Unix()), nil
+	}
+	for _, layout := range []string{"2006-01-02", "2006-01-02T15:04", time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, due, time.Local); err == nil {
+			return float64(t.Unix()), nil
+		}
+	}
+	return 0, fmt.Errorf("Invalid due date %s, ex: 2017-01-31, 2017-01-31T18:00, 48h", due)
+}
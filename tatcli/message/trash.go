@@ -0,0 +1,46 @@
+package message
+
+import (
+	"strconv"
+
+	"github.com/ovh/tat/tatcli/internal"
+	"github.com/spf13/cobra"
+)
+
+var cmdMessageTrash = &cobra.Command{
+	Use:   "trash",
+	Short: "List messages deleted from a topic, only for tat admin and administrators on topic: tatcli message trash <topic> [<skip>] [<limit>]",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 || len(args) > 3 {
+			internal.Exit("Invalid argument to list trash: tatcli message trash --help\n")
+		}
+		skip, limit := 0, 100
+		var err error
+		if len(args) >= 2 {
+			skip, err = strconv.Atoi(args[1])
+			internal.Check(err)
+		}
+		if len(args) == 3 {
+			limit, err = strconv.Atoi(args[2])
+			internal.Check(err)
+		}
+		out, err := internal.Client().TrashList(args[0], skip, limit)
+		internal.Check(err)
+		internal.Print(out)
+	},
+}
+
+var cmdMessageUndelete = &cobra.Command{
+	Use:   "undelete",
+	Short: "Restore a deleted message from trash, with replies deleted with it: tatcli message undelete <topic> <idMessage>",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 2 {
+			internal.Exit("Invalid argument to undelete a message: tatcli message undelete --help\n")
+		}
+		out, err := internal.Client().MessageUndelete(args[0], args[1])
+		internal.Check(err)
+		if internal.Verbose {
+			internal.Print(out)
+		}
+	},
+}
@@ -0,0 +1,22 @@
+package message
+
+import (
+	"github.com/ovh/tat/tatcli/internal"
+	"github.com/spf13/cobra"
+)
+
+var cmdMessageUnpin = &cobra.Command{
+	Use:   "unpin",
+	Short: "Unpin a message from its topic, topic admins only: tatcli message unpin <topic> <idMessage>",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 2 {
+			out, err := internal.Client().MessageUnpin(args[0], args[1])
+			internal.Check(err)
+			if internal.Verbose {
+				internal.Print(out)
+			}
+		} else {
+			internal.Exit("Invalid argument to unpin a message: tatcli message unpin --help\n")
+		}
+	},
+}
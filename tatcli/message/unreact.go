@@ -0,0 +1,22 @@
+package message
+
+import (
+	"github.com/ovh/tat/tatcli/internal"
+	"github.com/spf13/cobra"
+)
+
+var cmdMessageUnreact = &cobra.Command{
+	Use:   "unreact",
+	Short: "Remove your reaction from a message: tatcli message unreact <topic> <idMessage> <reaction>",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 3 {
+			out, err := internal.Client().MessageUnreact(args[0], args[1], args[2])
+			internal.Check(err)
+			if internal.Verbose {
+				internal.Print(out)
+			}
+		} else {
+			internal.Exit("Invalid argument to unreact on a message: tatcli message unreact --help\n")
+		}
+	},
+}
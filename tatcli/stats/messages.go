@@ -0,0 +1,103 @@
+package stats
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/ovh/tat"
+	"github.com/ovh/tat/tatcli/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	criteria  tat.MessageCriteria
+	aggregate tat.MessageAggregateCriteria
+	groupBy   string
+)
+
+func init() {
+	cmdStatsMessages.Flags().StringVarP(&groupBy, "groupBy", "", "", "Group by dimensions: label, tag, author, topic. Could be label,author")
+	cmdStatsMessages.Flags().StringVarP(&aggregate.LabelPrefix, "labelPrefix", "", "", "With --groupBy label, keep only labels with this prefix: --labelPrefix='status:'")
+	cmdStatsMessages.Flags().StringVarP(&aggregate.Bucket, "bucket", "", "", "Time bucket on dateCreation: minute, hour, day, week")
+	cmdStatsMessages.Flags().StringVarP(&criteria.Text, "text", "", "", "Search by text")
+	cmdStatsMessages.Flags().StringVarP(&criteria.Label, "label", "", "", "Search by label: could be labelA,labelB")
+	cmdStatsMessages.Flags().StringVarP(&criteria.StartLabel, "startLabel", "", "", "Search by a label prefix: --startLabel='mykey:,myKey2:'")
+	cmdStatsMessages.Flags().StringVarP(&criteria.NotLabel, "notLabel", "", "", "Search by label (exclude): could be labelA,labelB")
+	cmdStatsMessages.Flags().StringVarP(&criteria.AndLabel, "andLabel", "", "", "Search by label (and) : could be labelA,labelB")
+	cmdStatsMessages.Flags().StringVarP(&criteria.Tag, "tag", "", "", "Search by tag : could be tagA,tagB")
+	cmdStatsMessages.Flags().StringVarP(&criteria.NotTag, "notTag", "", "", "Search by tag (exclude) : could be tagA,tagB")
+	cmdStatsMessages.Flags().StringVarP(&criteria.AndTag, "andTag", "", "", "Search by tag (and) : could be tagA,tagB")
+	cmdStatsMessages.Flags().StringVarP(&criteria.Username, "username", "", "", "Search by username : could be usernameA,usernameB")
+	cmdStatsMessages.Flags().StringVarP(&criteria.DateMinCreation, "dateMinCreation", "", "", "Search by dateCreation (timestamp), select messages where dateCreation >= dateMinCreation")
+	cmdStatsMessages.Flags().StringVarP(&criteria.DateMaxCreation, "dateMaxCreation", "", "", "Search by dateCreation (timestamp), select messages where dateCreation <= dateMaxCreation")
+	cmdStatsMessages.Flags().StringVarP(&criteria.OnlyMsgRoot, "onlyMsgRoot", "", "", "--onlyMsgRoot=true: restricts to root message only (inReplyOfIDRoot empty)")
+}
+
+var cmdStatsMessages = &cobra.Command{
+	Use:   "messages",
+	Short: "Count messages per label, tag, author, topic and time bucket: tatcli stats messages <topic>",
+	Long: `Count messages of a topic matching criteria, per label, tag, author, topic and time bucket.
+Time buckets are in UTC, weeks begin on monday. This command is not restricted to admin.
+
+Example:
+
+	tatcli stats messages /Internal/Alerts --groupBy=label --labelPrefix=status: --bucket=hour --dateMinCreation=1489363200
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			internal.Exit("Invalid argument: tatcli stats messages --help\n")
+		}
+		if groupBy != "" {
+			aggregate.GroupBy = strings.Split(groupBy, ",")
+		}
+		internal.Check(aggregate.Check())
+
+		out, err := internal.Client().MessageAggregate(args[0], &criteria, aggregate)
+		internal.Check(err)
+
+		header := []string{}
+		if out.Bucket != "" {
+			header = append(header, "Date")
+		}
+		for _, g := range out.GroupBy {
+			header = append(header, strings.Title(g))
+		}
+		header = append(header, "Count")
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader(header)
+		for _, r := range out.Results {
+			row := []string{}
+			if out.Bucket != "" {
+				row = append(row, formatBucket(r.Date, out.Bucket))
+			}
+			for _, g := range out.GroupBy {
+				switch g {
+				case tat.AggregateByLabel:
+					row = append(row, r.Label)
+				case tat.AggregateByTag:
+					row = append(row, r.Tag)
+				case tat.AggregateByAuthor:
+					row = append(row, r.Author)
+				case tat.AggregateByTopic:
+					row = append(row, r.Topic)
+				}
+			}
+			table.Append(append(row, strconv.Itoa(r.Count)))
+		}
+		table.SetFooter(append(make([]string, len(header)-1), "Total "+strconv.Itoa(out.Total)))
+		table.Render()
+	},
+}
+
+// formatBucket returns beginning of a time bucket, in UTC
+func formatBucket(date int64, bucket string) string {
+	t := time.Unix(date, 0).UTC()
+	if bucket == tat.AggregateBucketDay || bucket == tat.AggregateBucketWeek {
+		return t.Format("2006-01-02")
+	}
+	return t.Format("2006-01-02 15:04")
+}
@@ -12,12 +12,13 @@ func init() {
 	Cmd.AddCommand(cmdStatsDBCollections)
 	Cmd.AddCommand(cmdStatsDBSlowestQueries)
 	Cmd.AddCommand(cmdStatsInstance)
+	Cmd.AddCommand(cmdStatsMessages)
 }
 
 // Cmd command
 var Cmd = &cobra.Command{
 	Use:     "stats",
-	Short:   "Stats commands (admin only): tatcli stats --help",
-	Long:    `Stats commands (admin only): tatcli stats [<command>]`,
+	Short:   "Stats commands (admin only, except messages): tatcli stats --help",
+	Long:    `Stats commands (admin only, except messages): tatcli stats [<command>]`,
 	Aliases: []string{"stat"},
 }
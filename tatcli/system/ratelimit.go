@@ -0,0 +1,20 @@
+package system
+
+import (
+	"github.com/ovh/tat/tatcli/internal"
+	"github.com/spf13/cobra"
+)
+
+var cmdSystemRateLimit = &cobra.Command{
+	Use:   "ratelimit",
+	Short: "Rate limits and users or topics throttled during last hour: tatcli system ratelimit",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) >= 1 {
+			internal.Exit("Invalid argument: tatcli system ratelimit --help\n")
+		} else {
+			out, err := internal.Client().SystemRateLimit()
+			internal.Check(err)
+			internal.Print(out)
+		}
+	},
+}
@@ -5,6 +5,7 @@ import "github.com/spf13/cobra"
 func init() {
 	Cmd.AddCommand(cmdSystemCacheClean)
 	Cmd.AddCommand(cmdSystemCacheInfo)
+	Cmd.AddCommand(cmdSystemRateLimit)
 }
 
 // Cmd command
@@ -0,0 +1,27 @@
+package topic
+
+import (
+	"github.com/ovh/tat/tatcli/internal"
+	"github.com/spf13/cobra"
+)
+
+var unarchive bool
+
+func init() {
+	cmdTopicArchive.Flags().BoolVarP(&recursive, "recursive", "r", false, "Archive sub-topics too, only for tat admin")
+	cmdTopicArchive.Flags().BoolVarP(&unarchive, "unarchive", "", false, "Unarchive topic")
+}
+
+var cmdTopicArchive = &cobra.Command{
+	Use:   "archive",
+	Short: "Archive a topic, read only, only for tat admin and administrators on topic: tatcli topic archive [--recursive] [--unarchive] <topic>",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 1 {
+			out, err := internal.Client().TopicArchive(args[0], !unarchive, recursive)
+			internal.Check(err)
+			internal.Print(out)
+		} else {
+			internal.Exit("Invalid argument: tatcli topic archive --help\n")
+		}
+	},
+}
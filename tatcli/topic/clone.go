@@ -0,0 +1,34 @@
+package topic
+
+import (
+	"github.com/ovh/tat"
+	"github.com/ovh/tat/tatcli/internal"
+	"github.com/spf13/cobra"
+)
+
+var cloneParts tat.TopicCloneJSON
+
+func init() {
+	cmdTopicClone.Flags().BoolVarP(&recursive, "recursive", "r", false, "Clone sub-topics too")
+	cmdTopicClone.Flags().BoolVarP(&cloneParts.ACL, "acl", "", false, "Copy read only, read write and admin users and groups")
+	cmdTopicClone.Flags().BoolVarP(&cloneParts.Parameters, "parameters", "", false, "Copy parameters")
+	cmdTopicClone.Flags().BoolVarP(&cloneParts.Filters, "filters", "", false, "Copy filters of users, with their hooks")
+	cmdTopicClone.Flags().BoolVarP(&cloneParts.Labels, "labels", "", false, "Copy labels catalog")
+}
+
+var cmdTopicClone = &cobra.Command{
+	Use:   "clone",
+	Short: "Create a topic from another one, without messages, only for tat admin and administrators on topic: tatcli topic clone [--recursive] [--acl] [--parameters] [--filters] [--labels] <topic> <newTopic>",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 2 {
+			cloneParts.Topic = args[0]
+			cloneParts.NewTopic = args[1]
+			cloneParts.Recursive = recursive
+			out, err := internal.Client().TopicClone(cloneParts)
+			internal.Check(err)
+			internal.Print(out)
+		} else {
+			internal.Exit("Invalid argument: tatcli topic clone --help\n")
+		}
+	},
+}
@@ -0,0 +1,40 @@
+package topic
+
+import (
+	"io"
+	"os"
+
+	"github.com/ovh/tat/tatcli/internal"
+	"github.com/spf13/cobra"
+)
+
+var exportFile string
+
+func init() {
+	cmdTopicExport.Flags().StringVarP(&exportFile, "file", "f", "", "Write export in this file instead of stdout")
+}
+
+var cmdTopicExport = &cobra.Command{
+	Use:   "export",
+	Short: "Export messages of a topic as JSON lines: tatcli topic export <topic> [--file=export.jsonl]",
+	Long: `Export messages of a topic as JSON lines: one root message per line, with its replies, labels, votes and authors.
+Attachments are not exported. Last line tells if export is complete, an error is returned if it's truncated.
+
+Example:
+
+	tatcli topic export /Internal/MyTopic --file=mytopic.jsonl
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			internal.Exit("Invalid argument: tatcli topic export --help\n")
+		}
+		var w io.Writer = os.Stdout
+		if exportFile != "" {
+			f, err := os.Create(exportFile)
+			internal.Check(err)
+			defer f.Close()
+			w = f
+		}
+		internal.Check(internal.Client().TopicExport(args[0], w))
+	},
+}
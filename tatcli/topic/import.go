@@ -0,0 +1,37 @@
+package topic
+
+import (
+	"io"
+	"os"
+
+	"github.com/ovh/tat/tatcli/internal"
+	"github.com/spf13/cobra"
+)
+
+var cmdTopicImport = &cobra.Command{
+	Use:   "import",
+	Short: "Import messages exported by tatcli topic export, only for tat admin and administrators on topic: tatcli topic import <topic> [<file>]",
+	Long: `Import messages exported by tatcli topic export, only for tat admin and administrators on topic.
+Messages are read from file, or from stdin if no file is given.
+IDs and dates of messages are kept only if topic has parameter canForceDate.
+
+Example:
+
+	tatcli topic import /Internal/MyTopic mytopic.jsonl
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 || len(args) > 2 {
+			internal.Exit("Invalid argument: tatcli topic import --help\n")
+		}
+		var r io.Reader = os.Stdin
+		if len(args) == 2 {
+			f, err := os.Open(args[1])
+			internal.Check(err)
+			defer f.Close()
+			r = f
+		}
+		out, err := internal.Client().TopicImport(args[0], r)
+		internal.Check(err)
+		internal.Print(out)
+	},
+}
@@ -8,13 +8,24 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var (
+	attachmentsQuota     int64
+	retentionMaxAge      int64
+	retentionMaxMessages int
+	indexedFields        []string
+)
+
 func init() {
 	cmdTopicParameter.Flags().BoolVarP(&recursive, "recursive", "r", false, "Update param topic recursively")
+	cmdTopicParameter.Flags().Int64VarP(&attachmentsQuota, "attachmentsQuota", "", 0, "Max size in bytes of all attachments on topic, 0 for default quota")
+	cmdTopicParameter.Flags().Int64VarP(&retentionMaxAge, "retentionMaxAge", "", 0, "Delete threads not updated since this number of seconds, 0 to keep them")
+	cmdTopicParameter.Flags().IntVarP(&retentionMaxMessages, "retentionMaxMessages", "", 0, "Keep only this number of last updated threads, 0 to keep all")
+	cmdTopicParameter.Flags().StringSliceVarP(&indexedFields, "indexedFields", "", nil, "Index these fields of messages, for search with conditions on fields: --indexedFields=build,env. Unchanged if not given")
 }
 
 var cmdTopicParameter = &cobra.Command{
 	Use:     "parameter",
-	Short:   "Update param on one topic: tatcli topic param [--recursive] <topic> <maxLength> <maxReplies> <canForceDate> <canUpdateMsg> <canDeleteMsg> <canUpdateAllMsg> <canDeleteAllMsg> <adminCanUpdateAllMsg> <adminCanDeleteAllMsg> <isAutoComputeTags> <isAutoComputeLabels>",
+	Short:   "Update param on one topic: tatcli topic param [--recursive] [--attachmentsQuota=<bytes>] [--retentionMaxAge=<seconds>] [--retentionMaxMessages=<n>] [--indexedFields=<name>,...] <topic> <maxLength> <maxReplies> <canForceDate> <canUpdateMsg> <canDeleteMsg> <canUpdateAllMsg> <canDeleteAllMsg> <adminCanUpdateAllMsg> <adminCanDeleteAllMsg> <isAutoComputeTags> <isAutoComputeLabels>",
 	Aliases: []string{"param"},
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) != 12 {
@@ -24,7 +35,16 @@ var cmdTopicParameter = &cobra.Command{
 		var err error
 
 		p := tat.TopicParameters{
-			Topic: args[0],
+			Topic:                args[0],
+			AttachmentsQuota:     attachmentsQuota,
+			RetentionMaxAge:      retentionMaxAge,
+			RetentionMaxMessages: retentionMaxMessages,
+		}
+		if cmd.Flags().Changed("indexedFields") {
+			p.IndexedFields = indexedFields
+			if p.IndexedFields == nil {
+				p.IndexedFields = []string{}
+			}
 		}
 
 		p.MaxLength, err = strconv.Atoi(args[1])
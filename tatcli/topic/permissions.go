@@ -0,0 +1,27 @@
+package topic
+
+import (
+	"github.com/ovh/tat/tatcli/internal"
+	"github.com/spf13/cobra"
+)
+
+var cmdTopicPermissions = &cobra.Command{
+	Use:   "permissions",
+	Short: "Explain rights of a user on a topic, only for tat admin and administrators on topic: tatcli topic permissions <topic> [<username>]",
+	Long: `Display read, write and admin rights of a user on a topic, with grants giving each right:
+	tatcli topic permissions /Internal/Ops
+	tatcli topic permissions /Internal/Ops username
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 || len(args) > 2 {
+			internal.Exit("Invalid argument: tatcli topic permissions --help\n")
+		}
+		username := ""
+		if len(args) == 2 {
+			username = args[1]
+		}
+		out, err := internal.Client().TopicPermissions(args[0], username)
+		internal.Check(err)
+		internal.Print(out)
+	},
+}
@@ -0,0 +1,25 @@
+package topic
+
+import (
+	"github.com/ovh/tat"
+	"github.com/ovh/tat/tatcli/internal"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	cmdTopicRename.Flags().BoolVarP(&recursive, "recursive", "r", false, "Rename sub-topics too")
+}
+
+var cmdTopicRename = &cobra.Command{
+	Use:   "rename",
+	Short: "Rename a topic, only for tat admin and administrators on topic: tatcli topic rename [--recursive] <topic> <newTopic>",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 2 {
+			out, err := internal.Client().TopicRename(tat.TopicRenameJSON{Topic: args[0], NewTopic: args[1], Recursive: recursive})
+			internal.Check(err)
+			internal.Print(out)
+		} else {
+			internal.Exit("Invalid argument: tatcli topic rename --help\n")
+		}
+	},
+}
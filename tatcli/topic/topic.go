@@ -11,6 +11,12 @@ func init() {
 	Cmd.AddCommand(cmdTopicCreate)
 	Cmd.AddCommand(cmdTopicDelete)
 	Cmd.AddCommand(cmdTopicTruncate)
+	Cmd.AddCommand(cmdTopicRename)
+	Cmd.AddCommand(cmdTopicArchive)
+	Cmd.AddCommand(cmdTopicClone)
+	Cmd.AddCommand(cmdTopicPermissions)
+	Cmd.AddCommand(cmdTopicExport)
+	Cmd.AddCommand(cmdTopicImport)
 	Cmd.AddCommand(cmdTopicAddRoUser)
 	Cmd.AddCommand(cmdTopicComputeLabels)
 	Cmd.AddCommand(cmdTopicTruncateLabels)
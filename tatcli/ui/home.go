@@ -94,6 +94,8 @@ func (ui *tatui) initHomeRight() {
   Keywords:
    - /help display this page
    - /me show information about you
+   - /mentions show messages mentioning you, on all topics. /mentions-read marks them as read
+   - /tasks show tasks assigned to you or to your groups, by due date
    - /version to show tatcli and engine version
 
   On messages list:
@@ -102,6 +104,8 @@ func (ui *tatui) initHomeRight() {
    - /voteup, /votedown, /unvoteup, /unvotedown to vote up or down, or remove vote
    - /task, /untask to add or remove selected message as a personal task
    - /like, /unlike to add or remove like on selected message
+   - /react +1, /unreact +1 to add or remove a reaction on selected message
+   - /pin, /unpin to pin or unpin selected message on top of topic (topic admins only)
    - /filter label:labelA,labelB andtag:tag,tagb
    - /mode (run|monitoring): enable Ctrl + l shortcut, see on left side for help
    - /codereview splits screen into fours panes:
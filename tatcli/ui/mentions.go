@@ -0,0 +1,71 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/gizak/termui"
+	"github.com/ovh/tat"
+	"github.com/ovh/tat/tatcli/internal"
+)
+
+// uiMentionsLimit is the max number of mentions displayed in mentions pane
+const uiMentionsLimit = 100
+
+// showMentions displays last messages mentioning current user, unread first in yellow
+func (ui *tatui) showMentions() {
+	ui.current = uiResult
+	ui.selectedPane = uiActionBox
+	ui.send.BorderLabel = " ✎ Action"
+	termui.Body.Rows = nil
+
+	ls := termui.NewList()
+	ls.BorderTop, ls.BorderLeft, ls.BorderRight, ls.BorderBottom = true, false, false, false
+	ls.ItemFgColor = termui.ColorWhite
+	ls.Height = termui.TermHeight() - uiHeightTop - uiHeightSend
+	ui.mentions = ls
+	ui.updateMentions()
+
+	ui.prepareTopMenu()
+	termui.Body.AddRows(
+		termui.NewRow(
+			termui.NewCol(12, 0, ui.mentions),
+		),
+	)
+	ui.prepareSendRow()
+	termui.Clear()
+	ui.colorizedPanes()
+	ui.render()
+}
+
+// updateMentions refreshes items of mentions pane
+func (ui *tatui) updateMentions() {
+	if ui.mentions == nil {
+		return
+	}
+	out, err := internal.Client().UserMentions(&tat.MessageCriteria{Limit: uiMentionsLimit}, false)
+	if err != nil {
+		ui.msg.Text = err.Error()
+		return
+	}
+	var strs []string
+	for _, msg := range out.Messages {
+		item := fmt.Sprintf("%s %s", msg.Topic, ui.formatMessage(msg, true))
+		if tat.ArrayContains(out.Unread, msg.ID) {
+			item = fmt.Sprintf("[●](fg-yellow) %s", item)
+		}
+		strs = append(strs, item)
+	}
+	ui.mentions.Items = strs
+	ui.mentions.BorderLabel = fmt.Sprintf("Mentions (%d unread) - /mentions-read to mark all as read", out.NbUnread)
+}
+
+// markMentionsRead marks all mentions of current user as read
+func (ui *tatui) markMentionsRead() {
+	if _, err := internal.Client().UserMentionsRead(); err != nil {
+		ui.msg.Text = err.Error()
+		return
+	}
+	ui.msg.Text = "Mentions marked as read"
+	ui.updateMentions()
+	ui.render()
+}
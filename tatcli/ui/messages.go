@@ -37,6 +37,7 @@ func (ui *tatui) showMessages() {
 		ui.clearFilterOnCurrentTopic()
 	}
 
+	ui.initPinned()
 	ui.initMessages()
 
 	go func() {
@@ -45,6 +46,7 @@ func (ui *tatui) showMessages() {
 				break
 			}
 			mutex.Lock()
+			ui.updatePinned()
 			ui.updateMessages()
 			ui.firstCallMessages = true
 			mutex.Unlock()
@@ -57,10 +59,20 @@ func (ui *tatui) showMessages() {
 	ui.prepareTopMenu()
 
 	if len(ui.currentFilterMessages[ui.currentTopic.Topic]) > 1 {
+		if ui.pinned != nil {
+			termui.Body.AddRows(termui.NewRow(termui.NewCol(12, 0, ui.pinned)))
+		}
 		// preserve order
 		for k := 0; k < len(ui.currentFilterMessages[ui.currentTopic.Topic]); k++ {
 			termui.Body.AddRows(termui.NewRow(termui.NewCol(12, 0, ui.uilists[uiMessages][k].list)))
 		}
+	} else if ui.pinned != nil {
+		termui.Body.AddRows(
+			termui.NewRow(
+				termui.NewCol(3, 0, ui.uilists[uiTopics][0].list),
+				termui.NewCol(9, 0, ui.pinned, ui.uilists[uiMessages][0].list),
+			),
+		)
 	} else {
 		termui.Body.AddRows(
 			termui.NewRow(
@@ -86,7 +98,7 @@ func (ui *tatui) initMessages() {
 		ls.BorderLabel = "Messages"
 		ls.Width = 25
 		ls.Y = 0
-		ls.Height = (termui.TermHeight() - uiHeightTop - uiHeightSend) / len(ui.currentFilterMessages[ui.currentTopic.Topic])
+		ls.Height = (termui.TermHeight() - uiHeightTop - uiHeightSend - ui.getPinnedHeight()) / len(ui.currentFilterMessages[ui.currentTopic.Topic])
 
 		if _, ok := ui.uilists[uiMessages]; !ok {
 			ui.uilists[uiMessages] = make(map[int]*uilist)
@@ -109,7 +121,7 @@ func (ui *tatui) updateMessages() {
 		if pane > len(ui.uilists[uiMessages]) {
 			continue
 		}
-		nbPerPage := (ui.getNbPerPage() / len(ui.currentFilterMessages[ui.currentTopic.Topic])) - 1
+		nbPerPage := ((ui.getNbPerPage() - ui.getPinnedHeight()) / len(ui.currentFilterMessages[ui.currentTopic.Topic])) - 1
 		c := ui.currentFilterMessages[ui.currentTopic.Topic][pane]
 		if c == nil {
 			c = &tat.MessageCriteria{}
@@ -183,6 +195,10 @@ func (ui *tatui) formatMessage(msg tat.Message, withColor bool) string {
 		text += fmt.Sprintf("%d♡ ", msg.NbLikes)
 	}
 
+	if reactions := msg.FormatReactions(); reactions != "" {
+		text += reactions + " "
+	}
+
 	for _, label := range msg.Labels {
 		ccolor := ""
 		if withColor {
@@ -255,6 +271,12 @@ func (ui *tatui) prepareFilterMessages(text, mode, topic string) (*tat.MessageCr
 		if strings.EqualFold(tuple[0], "AndTag") {
 			c.AndTag = strings.Join(tuple[1:], ":")
 		}
+		if strings.EqualFold(tuple[0], "Pinned") {
+			c.Pinned = strings.Join(tuple[1:], ":")
+		}
+		if strings.EqualFold(tuple[0], "Reaction") {
+			c.Reaction = strings.Join(tuple[1:], ":")
+		}
 		if strings.EqualFold(tuple[0], "Username") {
 			c.Username = strings.Join(tuple[1:], ":")
 		}
@@ -0,0 +1,60 @@
+package ui
+
+import (
+	"github.com/gizak/termui"
+	"github.com/ovh/tat/tatcli/internal"
+)
+
+// uiHeightPinnedMax is the max height of pinned pane, border included
+const uiHeightPinnedMax = 5
+
+// initPinned prepares pinned pane, displayed on top of messages
+// if current topic has pinned messages
+func (ui *tatui) initPinned() {
+	ui.pinned = nil
+	items := ui.getPinnedItems()
+	if len(items) == 0 {
+		return
+	}
+	ls := termui.NewList()
+	ls.BorderTop, ls.BorderLeft, ls.BorderRight, ls.BorderBottom = true, false, false, false
+	ls.Items = items
+	ls.ItemFgColor = termui.ColorYellow
+	ls.BorderLabel = "Pinned"
+	ls.Width = 25
+	ls.Y = 0
+	ls.Height = len(items) + 1
+	if ls.Height > uiHeightPinnedMax {
+		ls.Height = uiHeightPinnedMax
+	}
+	ui.pinned = ls
+}
+
+// updatePinned refreshes pinned messages of current topic
+func (ui *tatui) updatePinned() {
+	if ui.pinned == nil {
+		return
+	}
+	ui.pinned.Items = ui.getPinnedItems()
+}
+
+func (ui *tatui) getPinnedItems() []string {
+	topicJSON, err := internal.Client().TopicOne(ui.currentTopic.Topic)
+	if err != nil {
+		ui.msg.Text = err.Error()
+		return nil
+	}
+	var strs []string
+	for _, msg := range topicJSON.PinnedMessages {
+		strs = append(strs, ui.formatMessage(msg, true))
+	}
+	return strs
+}
+
+// getPinnedHeight returns height used by pinned pane
+func (ui *tatui) getPinnedHeight() int {
+	if ui.pinned == nil {
+		return 0
+	}
+	return ui.pinned.Height
+}
@@ -42,7 +42,9 @@ func (ui *tatui) autocomplete() {
 		"/monitoring",
 		"/open",
 		"/open-links",
+		"/pin",
 		"/quit",
+		"/react",
 		"/run",
 		"/set-tatwebui-url",
 		"/save",
@@ -55,6 +57,8 @@ func (ui *tatui) autocomplete() {
 		"/unlabel",
 		"/unlabel yourLabel",
 		"/unlike",
+		"/unpin",
+		"/unreact",
 		"/untask",
 		"/unread",
 		"/unvoteup",
@@ -110,6 +114,18 @@ func (ui *tatui) processCmd() {
 		ui.send.Text = ""
 		ui.showMe()
 		return
+	case "/mentions":
+		ui.send.Text = ""
+		ui.showMentions()
+		return
+	case "/mentions-read":
+		ui.send.Text = ""
+		ui.markMentionsRead()
+		return
+	case "/tasks":
+		ui.send.Text = ""
+		ui.showTasks()
+		return
 	case "/quit":
 		ui.send.Text = ""
 		termui.StopLoop()
@@ -197,13 +213,17 @@ func (ui *tatui) processCmdOnMessage() {
 		ui.sendLabel()
 	} else if strings.HasPrefix(ui.send.Text, "/unlabel ") {
 		ui.sendUnlabel()
+	} else if strings.HasPrefix(ui.send.Text, "/react ") || strings.HasPrefix(ui.send.Text, "/unreact ") {
+		ui.sendReaction()
 	} else if strings.HasPrefix(ui.send.Text, "/vote") ||
 		strings.HasPrefix(ui.send.Text, "/like") ||
 		strings.HasPrefix(ui.send.Text, "/unlike") ||
 		strings.HasPrefix(ui.send.Text, "/task") ||
 		strings.HasPrefix(ui.send.Text, "/untask") ||
+		strings.HasPrefix(ui.send.Text, "/pin") ||
+		strings.HasPrefix(ui.send.Text, "/unpin") ||
 		strings.HasPrefix(ui.send.Text, "/unvote") {
-		// /voteup, /votedown, /unvoteup, /unvotedown, /like, /unlike, /task, /untask
+		// /voteup, /votedown, /unvoteup, /unvotedown, /like, /unlike, /task, /untask, /pin, /unpin
 		ui.sendSimpleActionMsg()
 	}
 
@@ -239,6 +259,10 @@ func (ui *tatui) sendSimpleActionMsg() {
 		msg, err = internal.Client().MessageTask(ui.currentTopic.Topic, ui.currentListMessages[ui.selectedPaneMessages][ui.uilists[uiMessages][ui.selectedPaneMessages].position].ID)
 	case "untask":
 		msg, err = internal.Client().MessageUntask(ui.currentTopic.Topic, ui.currentListMessages[ui.selectedPaneMessages][ui.uilists[uiMessages][ui.selectedPaneMessages].position].ID)
+	case "pin":
+		msg, err = internal.Client().MessagePin(ui.currentTopic.Topic, ui.currentListMessages[ui.selectedPaneMessages][ui.uilists[uiMessages][ui.selectedPaneMessages].position].ID)
+	case "unpin":
+		msg, err = internal.Client().MessageUnpin(ui.currentTopic.Topic, ui.currentListMessages[ui.selectedPaneMessages][ui.uilists[uiMessages][ui.selectedPaneMessages].position].ID)
 	}
 
 	if err != nil {
@@ -288,6 +312,33 @@ func (ui *tatui) sendUnlabel() {
 	}
 }
 
+func (ui *tatui) sendReaction() {
+	// "/react 👀" or "/unreact 👀"
+	t := strings.SplitN(strings.TrimSpace(ui.send.Text), " ", 2)
+	if len(t) != 2 {
+		return
+	}
+	reaction := strings.TrimSpace(t[1])
+	idMessage := ui.currentListMessages[ui.selectedPaneMessages][ui.uilists[uiMessages][ui.selectedPaneMessages].position].ID
+
+	var err error
+	var msg *tat.MessageJSONOut
+	if t[0] == "/react" {
+		msg, err = internal.Client().MessageReact(ui.currentTopic.Topic, idMessage, reaction)
+	} else {
+		msg, err = internal.Client().MessageUnreact(ui.currentTopic.Topic, idMessage, reaction)
+	}
+	if err != nil {
+		ui.msg.Text = err.Error()
+		return
+	}
+	ui.uilists[uiMessages][ui.selectedPaneMessages].list.Items[ui.uilists[uiMessages][ui.selectedPaneMessages].position] = ui.formatMessage(msg.Message, true)
+	ui.addMarker(ui.uilists[uiMessages][ui.selectedPaneMessages], ui.selectedPaneMessages)
+	if msg.Info != "" {
+		ui.msg.Text = msg.Info
+	}
+}
+
 func (ui *tatui) clearUI() {
 	ui.uiTopicCommands[ui.currentTopic.Topic] = ""
 	if ui.current == uiTopics {
@@ -0,0 +1,64 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/gizak/termui"
+	"github.com/ovh/tat"
+	"github.com/ovh/tat/tatcli/internal"
+)
+
+// uiTasksLimit is the max number of tasks displayed in tasks pane
+const uiTasksLimit = 100
+
+// showTasks displays tasks assigned to current user or to its groups,
+// by due date, overdue tasks in red
+func (ui *tatui) showTasks() {
+	ui.current = uiResult
+	ui.selectedPane = uiActionBox
+	ui.send.BorderLabel = " ✎ Action"
+	termui.Body.Rows = nil
+
+	ls := termui.NewList()
+	ls.BorderTop, ls.BorderLeft, ls.BorderRight, ls.BorderBottom = true, false, false, false
+	ls.ItemFgColor = termui.ColorWhite
+	ls.Height = termui.TermHeight() - uiHeightTop - uiHeightSend
+	ui.tasks = ls
+	ui.updateTasks()
+
+	ui.prepareTopMenu()
+	termui.Body.AddRows(
+		termui.NewRow(
+			termui.NewCol(12, 0, ui.tasks),
+		),
+	)
+	ui.prepareSendRow()
+	termui.Clear()
+	ui.colorizedPanes()
+	ui.render()
+}
+
+// updateTasks refreshes items of tasks pane
+func (ui *tatui) updateTasks() {
+	if ui.tasks == nil {
+		return
+	}
+	out, err := internal.Client().UserTasks(&tat.MessageCriteria{Limit: uiTasksLimit})
+	if err != nil {
+		ui.msg.Text = err.Error()
+		return
+	}
+	now := tat.TSFromNow()
+	nbOverdue := 0
+	var strs []string
+	for _, msg := range out.Messages {
+		item := fmt.Sprintf("[%s](fg-cyan) %s %s", msg.FormatTask(), msg.Topic, ui.formatMessage(msg, true))
+		if msg.Task.IsOverdue(now) {
+			item = fmt.Sprintf("[●](fg-red) %s", item)
+			nbOverdue++
+		}
+		strs = append(strs, item)
+	}
+	ui.tasks.Items = strs
+	ui.tasks.BorderLabel = fmt.Sprintf("Tasks (%d overdue)", nbOverdue)
+}
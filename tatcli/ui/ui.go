@@ -21,6 +21,9 @@ type tatui struct {
 	homeLeft    *termui.Par
 	homeRight   *termui.Par
 	send        *termui.Par
+	pinned      *termui.List
+	mentions    *termui.List
+	tasks       *termui.List
 
 	selectedPane         int
 	selectedPaneMessages int
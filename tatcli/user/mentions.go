@@ -0,0 +1,56 @@
+package user
+
+import (
+	"github.com/ovh/tat"
+	"github.com/ovh/tat/tatcli/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	mentionsCriteria   tat.MessageCriteria
+	mentionsOnlyUnread bool
+	mentionsMarkRead   bool
+)
+
+func init() {
+	cmdUserMentions.Flags().BoolVarP(&mentionsOnlyUnread, "unread", "", false, "Only mentions not yet marked as read")
+	cmdUserMentions.Flags().BoolVarP(&mentionsMarkRead, "markRead", "", false, "Mark returned mentions as read")
+	cmdUserMentions.Flags().IntVarP(&mentionsCriteria.Skip, "skip", "", 0, "Skip first mentions")
+	cmdUserMentions.Flags().IntVarP(&mentionsCriteria.Limit, "limit", "", 100, "Max number of mentions, 1000 max")
+	cmdUserMentions.Flags().StringVarP(&mentionsCriteria.Text, "text", "", "", "Search by text")
+	cmdUserMentions.Flags().StringVarP(&mentionsCriteria.Label, "label", "", "", "Search by label: could be labelA,labelB")
+	cmdUserMentions.Flags().StringVarP(&mentionsCriteria.NotLabel, "notLabel", "", "", "Search by label (exclude): could be labelA,labelB")
+	cmdUserMentions.Flags().StringVarP(&mentionsCriteria.Tag, "tag", "", "", "Search by tag : could be tagA,tagB")
+	cmdUserMentions.Flags().StringVarP(&mentionsCriteria.Username, "username", "", "", "Search by author : could be usernameA,usernameB")
+	cmdUserMentions.Flags().StringVarP(&mentionsCriteria.DateMinCreation, "dateMinCreation", "", "", "Search by dateCreation (timestamp), select messages where dateCreation >= dateMinCreation")
+	cmdUserMentions.Flags().StringVarP(&mentionsCriteria.DateMaxCreation, "dateMaxCreation", "", "", "Search by dateCreation (timestamp), select messages where dateCreation <= dateMaxCreation")
+}
+
+var cmdUserMentions = &cobra.Command{
+	Use:   "mentions",
+	Short: "Messages mentioning you, on all topics you can read: tatcli user mentions [--unread] [--markRead]",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 0 {
+			internal.Exit("Invalid argument: tatcli user mentions --help\n")
+		}
+		out, err := internal.Client().UserMentions(&mentionsCriteria, mentionsOnlyUnread)
+		internal.Check(err)
+		internal.Print(out)
+		if mentionsMarkRead && len(out.Unread) > 0 {
+			_, err := internal.Client().UserMentionsRead(out.Unread...)
+			internal.Check(err)
+		}
+	},
+}
+
+var cmdUserMentionsRead = &cobra.Command{
+	Use:   "mentionsRead",
+	Short: "Mark mentions as read, all if no idMessage given: tatcli user mentionsRead [<idMessage>]...",
+	Run: func(cmd *cobra.Command, args []string) {
+		out, err := internal.Client().UserMentionsRead(args...)
+		internal.Check(err)
+		if internal.Verbose {
+			internal.Print(out)
+		}
+	},
+}
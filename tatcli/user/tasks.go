@@ -0,0 +1,34 @@
+package user
+
+import (
+	"github.com/ovh/tat"
+	"github.com/ovh/tat/tatcli/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	tasksCriteria tat.MessageCriteria
+	tasksStates   []string
+)
+
+func init() {
+	cmdUserTasks.Flags().StringSliceVar(&tasksStates, "state", nil, "Only tasks in these states: --state=todo,blocked. All tasks not done by default")
+	cmdUserTasks.Flags().IntVarP(&tasksCriteria.Skip, "skip", "", 0, "Skip first tasks")
+	cmdUserTasks.Flags().IntVarP(&tasksCriteria.Limit, "limit", "", 100, "Max number of tasks, 1000 max")
+	cmdUserTasks.Flags().StringVarP(&tasksCriteria.Text, "text", "", "", "Search by text")
+	cmdUserTasks.Flags().StringVarP(&tasksCriteria.Label, "label", "", "", "Search by label: could be labelA,labelB")
+	cmdUserTasks.Flags().StringVarP(&tasksCriteria.Tag, "tag", "", "", "Search by tag : could be tagA,tagB")
+}
+
+var cmdUserTasks = &cobra.Command{
+	Use:   "tasks",
+	Short: "Tasks assigned to you or to your groups, on all topics you can read, by due date: tatcli user tasks [--state=todo,doing]",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 0 {
+			internal.Exit("Invalid argument: tatcli user tasks --help\n")
+		}
+		out, err := internal.Client().UserTasks(&tasksCriteria, tasksStates...)
+		internal.Check(err)
+		internal.Print(out)
+	},
+}
@@ -8,6 +8,9 @@ func init() {
 	Cmd.AddCommand(cmdUserList)
 	Cmd.AddCommand(cmdUserMe)
 	Cmd.AddCommand(cmdUserContacts)
+	Cmd.AddCommand(cmdUserMentions)
+	Cmd.AddCommand(cmdUserMentionsRead)
+	Cmd.AddCommand(cmdUserTasks)
 	Cmd.AddCommand(cmdUserAddContact)
 	Cmd.AddCommand(cmdUserRemoveContact)
 	Cmd.AddCommand(cmdUserAddFavoriteTopic)
@@ -24,6 +24,9 @@ type Topic struct {
 	History              []string         `bson:"history" json:"history"`
 	MaxLength            int              `bson:"maxlength" json:"maxlength"`
 	MaxReplies           int              `bson:"maxreplies" json:"maxreplies"`
+	AttachmentsQuota     int64            `bson:"attachmentsQuota" json:"attachmentsQuota"`
+	RetentionMaxAge      int64            `bson:"retentionMaxAge" json:"retentionMaxAge"`
+	RetentionMaxMessages int              `bson:"retentionMaxMessages" json:"retentionMaxMessages"`
 	CanForceDate         bool             `bson:"canForceDate" json:"canForceDate"`
 	CanUpdateMsg         bool             `bson:"canUpdateMsg" json:"canUpdateMsg"`
 	CanDeleteMsg         bool             `bson:"canDeleteMsg" json:"canDeleteMsg"`
@@ -33,6 +36,7 @@ type Topic struct {
 	AdminCanDeleteAllMsg bool             `bson:"adminCanDeleteAllMsg" json:"adminCanDeleteAllMsg"`
 	IsAutoComputeTags    bool             `bson:"isAutoComputeTags" json:"isAutoComputeTags"`
 	IsAutoComputeLabels  bool             `bson:"isAutoComputeLabels" json:"isAutoComputeLabels"`
+	IsArchived           bool             `bson:"isArchived" json:"isArchived"`
 	DateModification     int64            `bson:"dateModification" json:"dateModificationn,omitempty"`
 	DateCreation         int64            `bson:"dateCreation" json:"dateCreation,omitempty"`
 	DateLastMessage      int64            `bson:"dateLastMessage" json:"dateLastMessage,omitempty"`
@@ -40,6 +44,9 @@ type Topic struct {
 	Tags                 []string         `bson:"tags" json:"tags,omitempty"`
 	Labels               []Label          `bson:"labels" json:"labels,omitempty"`
 	Filters              []Filter         `bson:"filters" json:"filters"`
+	Pinned               []string         `bson:"pinned" json:"pinned,omitempty"`
+	IndexedFields        []string         `bson:"indexedFields" json:"indexedFields,omitempty"`
+	Workflow             *Workflow        `bson:"workflow,omitempty" json:"workflow,omitempty"`
 }
 
 type Filter struct {
@@ -101,6 +108,8 @@ type TopicCriteria struct {
 	GetForAllTasksTopics bool
 	Group                string
 	SortBy               string
+	Cursor               string
+	HideArchived         string
 }
 
 // CacheKey returns cache key value
@@ -151,6 +160,12 @@ func (t *TopicCriteria) CacheKey() []string {
 	if t.SortBy != "" {
 		s = append(s, "sort_by="+t.SortBy)
 	}
+	if t.Cursor != "" {
+		s = append(s, "cursor="+t.Cursor)
+	}
+	if t.HideArchived != "" {
+		s = append(s, "hide_archived="+t.HideArchived)
+	}
 	return s
 }
 
@@ -175,19 +190,84 @@ type TopicParameterJSON struct {
 	Recursive bool   `json:"recursive"`
 }
 
+// TopicRenameJSON is used to rename a topic, with its sub-topics if recursive
+type TopicRenameJSON struct {
+	Topic     string `json:"topic"`
+	NewTopic  string `json:"newTopic"`
+	Recursive bool   `json:"recursive"`
+}
+
+// TopicCloneJSON is used to clone a topic to a new topic, with its sub-topics if recursive.
+// Flags and limits of topics are always copied, other parts only if asked. Messages are never copied
+type TopicCloneJSON struct {
+	Topic      string `json:"topic"`
+	NewTopic   string `json:"newTopic"`
+	Recursive  bool   `json:"recursive"`
+	ACL        bool   `json:"acl"`        // users and groups, read only, read write and admin
+	Parameters bool   `json:"parameters"` // parameters of topic, as hooks destinations
+	Filters    bool   `json:"filters"`    // filters of users, with their hooks
+	Labels     bool   `json:"labels"`     // labels catalog of topic
+}
+
+// TopicArchiveJSON is used to archive or unarchive a topic, with its sub-topics
+// if recursive
+type TopicArchiveJSON struct {
+	Topic      string `json:"topic"`
+	IsArchived bool   `json:"isArchived"`
+	Recursive  bool   `json:"recursive"`
+}
+
+// Rights on a topic
+const (
+	TopicRightRead  = "read"
+	TopicRightWrite = "write"
+	TopicRightAdmin = "admin"
+)
+
+// Sources of a grant on a topic
+const (
+	TopicGrantUser     = "user"     // username in roUsers, rwUsers or adminUsers
+	TopicGrantGroup    = "group"    // group of user in roGroups, rwGroups or adminGroups
+	TopicGrantTatAdmin = "tatAdmin" // user is a Tat admin
+	TopicGrantPrivate  = "private"  // topic is under /Private/username
+)
+
+// TopicGrant is an entry giving a right on a topic to a user
+type TopicGrant struct {
+	Right  string `json:"right"`
+	Source string `json:"source"`
+	Field  string `json:"field,omitempty"` // roUsers, rwGroups, ...
+	Name   string `json:"name,omitempty"`  // username or groupname
+	// SameOnAncestor is the top ancestor topic with the same entry in the same field.
+	// It's only a hint: entry may have been copied from it at creation, or added separately
+	SameOnAncestor string `json:"sameOnAncestor,omitempty"`
+}
+
+// TopicPermissionsJSON is returned by GET /topic/permissions/*topic
+type TopicPermissionsJSON struct {
+	Topic    string       `json:"topic"`
+	Username string       `json:"username"`
+	Read     bool         `json:"read"`
+	Write    bool         `json:"write"`
+	Admin    bool         `json:"admin"`
+	Grants   []TopicGrant `json:"grants"`
+}
+
 // TopicsJSON represents struct used by Engine while returns list of topics
 type TopicsJSON struct {
 	Count                int            `json:"count"`
 	Topics               []Topic        `json:"topics"`
 	CountTopicsMsgUnread int            `json:"countTopicsMsgUnread"`
 	TopicsMsgUnread      map[string]int `json:"topicsMsgUnread"`
+	NextCursor           string         `json:"nextCursor,omitempty"`
 }
 
 // TopicJSON represents struct used by Engine while returns one topic
 type TopicJSON struct {
-	Topic        *Topic `json:"topic"`
-	IsTopicRw    bool   `json:"isTopicRw"`
-	IsTopicAdmin bool   `json:"isTopicAdmin"`
+	Topic          *Topic    `json:"topic"`
+	IsTopicRw      bool      `json:"isTopicRw"`
+	IsTopicAdmin   bool      `json:"isTopicAdmin"`
+	PinnedMessages []Message `json:"pinnedMessages,omitempty"`
 }
 
 // TopicDistributionJSON represents struct used by Engine while returns topic distribution
@@ -330,6 +410,15 @@ func (c *Client) TopicList(criteria *TopicCriteria) (*TopicsJSON, error) {
 	if criteria.GetForTatAdmin == "true" {
 		v.Set("getForTatAdmin", criteria.GetForTatAdmin)
 	}
+	if criteria.SortBy != "" {
+		v.Set("sortBy", criteria.SortBy)
+	}
+	if criteria.Cursor != "" {
+		v.Set("cursor", criteria.Cursor)
+	}
+	if criteria.HideArchived != "" {
+		v.Set("hideArchived", criteria.HideArchived)
+	}
 
 	path := fmt.Sprintf("/topics?%s", v.Encode())
 
@@ -359,6 +448,72 @@ func (c *Client) TopicDelete(t TopicNameJSON) ([]byte, error) {
 	return out, nil
 }
 
+// TopicRename renames a topic, with its sub-topics if recursive. Messages,
+// favorites and notifications settings of users follow topic
+func (c *Client) TopicRename(t TopicRenameJSON) ([]byte, error) {
+	if c == nil {
+		return nil, ErrClientNotInitiliazed
+	}
+	out, err := c.simplePutAndGetBytes("/topic/rename", 201, t)
+	if err != nil {
+		ErrorLogFunc("Error while renaming topic %s to %s: %s", t.Topic, t.NewTopic, err)
+		return nil, err
+	}
+	return out, nil
+}
+
+// TopicClone creates a new topic with settings of a topic, with its sub-topics if
+// recursive, without messages
+func (c *Client) TopicClone(t TopicCloneJSON) ([]byte, error) {
+	if c == nil {
+		return nil, ErrClientNotInitiliazed
+	}
+	out, err := c.simplePostAndGetBytes("/topic/clone", 201, t)
+	if err != nil {
+		ErrorLogFunc("Error while cloning topic %s to %s: %s", t.Topic, t.NewTopic, err)
+		return nil, err
+	}
+	return out, nil
+}
+
+// TopicArchive archives a topic: messages can be read but not created, updated
+// or deleted. isArchived false unarchives topic. recursive is only for tat admin
+func (c *Client) TopicArchive(topic string, isArchived, recursive bool) ([]byte, error) {
+	if c == nil {
+		return nil, ErrClientNotInitiliazed
+	}
+	out, err := c.simplePutAndGetBytes("/topic/archive", 201, TopicArchiveJSON{Topic: topic, IsArchived: isArchived, Recursive: recursive})
+	if err != nil {
+		ErrorLogFunc("Error while archiving topic %s: %s", topic, err)
+		return nil, err
+	}
+	return out, nil
+}
+
+// TopicPermissions returns effective rights of a user on a topic, with grants
+// giving each right. Current user if username is empty
+func (c *Client) TopicPermissions(topic, username string) (*TopicPermissionsJSON, error) {
+	if c == nil {
+		return nil, ErrClientNotInitiliazed
+	}
+
+	path := "/topic/permissions" + topic
+	if username != "" {
+		path += "?username=" + url.QueryEscape(username)
+	}
+	body, err := c.simpleGetAndGetBytes(path)
+	if err != nil {
+		ErrorLogFunc("Error getting permissions on topic %s: %s", topic, err)
+		return nil, err
+	}
+
+	out := &TopicPermissionsJSON{}
+	if err := json.Unmarshal(body, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // TopicTruncate deletes all messages in a topic
 func (c *Client) TopicTruncate(t TopicNameJSON) ([]byte, error) {
 	return c.simplePutAndGetBytes("/topic/truncate", 201, t)
@@ -550,19 +705,23 @@ func (c *Client) TopicDeleteParameters(topic string, params []string, recursive
 
 // TopicParameters updates param on one topic
 type TopicParameters struct {
-	Topic                string `json:"topic"`
-	MaxLength            int    `json:"maxlength"`
-	MaxReplies           int    `json:"maxreplies"`
-	CanForceDate         bool   `json:"canForceDate"`
-	CanUpdateMsg         bool   `json:"canUpdateMsg"`
-	CanDeleteMsg         bool   `json:"canDeleteMsg"`
-	CanUpdateAllMsg      bool   `json:"canUpdateAllMsg"`
-	CanDeleteAllMsg      bool   `json:"canDeleteAllMsg"`
-	AdminCanUpdateAllMsg bool   `json:"adminCanUpdateAllMsg"`
-	AdminCanDeleteAllMsg bool   `json:"adminCanDeleteAllMsg"`
-	IsAutoComputeTags    bool   `json:"isAutoComputeTags"`
-	IsAutoComputeLabels  bool   `json:"isAutoComputeLabels"`
-	Recursive            bool   `json:"recursive"`
+	Topic                string   `json:"topic"`
+	MaxLength            int      `json:"maxlength"`
+	MaxReplies           int      `json:"maxreplies"`
+	AttachmentsQuota     int64    `json:"attachmentsQuota"`
+	RetentionMaxAge      int64    `json:"retentionMaxAge"`
+	RetentionMaxMessages int      `json:"retentionMaxMessages"`
+	CanForceDate         bool     `json:"canForceDate"`
+	CanUpdateMsg         bool     `json:"canUpdateMsg"`
+	CanDeleteMsg         bool     `json:"canDeleteMsg"`
+	CanUpdateAllMsg      bool     `json:"canUpdateAllMsg"`
+	CanDeleteAllMsg      bool     `json:"canDeleteAllMsg"`
+	AdminCanUpdateAllMsg bool     `json:"adminCanUpdateAllMsg"`
+	AdminCanDeleteAllMsg bool     `json:"adminCanDeleteAllMsg"`
+	IsAutoComputeTags    bool     `json:"isAutoComputeTags"`
+	IsAutoComputeLabels  bool     `json:"isAutoComputeLabels"`
+	Recursive            bool     `json:"recursive"`
+	IndexedFields        []string `json:"indexedFields"` // replaces indexed fields if not nil
 }
 
 // TopicParameter updates param on one topic
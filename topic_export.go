@@ -0,0 +1,126 @@
+package tat
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// TopicImportJSON is returned by POST /topic/import/*topic
+type TopicImportJSON struct {
+	Info     string               `json:"info"`
+	Messages int                  `json:"messages"`
+	Failures []MessageBulkFailure `json:"failures,omitempty"`
+}
+
+// TopicExportEndJSON is the last line of GET /topic/export/*topic, after messages.
+// An export without it, or with an error, is truncated
+type TopicExportEndJSON struct {
+	ExportEnd bool   `json:"exportEnd"`
+	Messages  int    `json:"messages"` // number of root messages exported
+	Error     string `json:"error,omitempty"`
+}
+
+// ParseTopicExportEnd returns last line of an export, nil if line is a message
+func ParseTopicExportEnd(line []byte) *TopicExportEndJSON {
+	end := &TopicExportEndJSON{}
+	if err := json.Unmarshal(line, end); err != nil || !end.ExportEnd {
+		return nil
+	}
+	return end
+}
+
+// checkTopicExportEnd returns an error if last line of an export is missing or has an error
+func checkTopicExportEnd(lastLine []byte) error {
+	end := ParseTopicExportEnd(bytes.TrimSpace(lastLine))
+	if end == nil {
+		return fmt.Errorf("Export is truncated, last line exportEnd is missing")
+	}
+	if end.Error != "" {
+		return fmt.Errorf("Export is truncated after %d messages: %s", end.Messages, end.Error)
+	}
+	return nil
+}
+
+// TopicExport writes messages of a topic to w, as JSON lines: one root message
+// per line, with all its replies, labels, votes and authors. Attachments are not exported
+func (c *Client) TopicExport(topic string, w io.Writer) error {
+	if c == nil {
+		return ErrClientNotInitiliazed
+	}
+
+	path := fmt.Sprintf("%s/topic/export%s", c.url, topic)
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	c.initHeaders(req)
+
+	// no request timeout here, export of a big topic can be long
+	exportClient := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: c.sslInsecureSkipVerify},
+		},
+	}
+	resp, err := exportClient.Do(req)
+	if err != nil {
+		ErrorLogFunc("TopicExport: error while requesting tat: %s", err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("Response code:%d (want:%d) with Body:%s", resp.StatusCode, http.StatusOK, string(body))
+	}
+	// copy line by line, to check last one
+	reader := bufio.NewReader(resp.Body)
+	var last []byte
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			if _, errw := w.Write(line); errw != nil {
+				return errw
+			}
+			last = line
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+	}
+	return checkTopicExportEnd(last)
+}
+
+// TopicImport inserts messages read from r, as written by TopicExport, in topic.
+// IDs and dates of messages are kept only if topic has parameter canForceDate.
+// A truncated export is refused, without importing any message
+func (c *Client) TopicImport(topic string, r io.Reader) (*TopicImportJSON, error) {
+	if c == nil {
+		return nil, ErrClientNotInitiliazed
+	}
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	b = bytes.TrimSpace(b)
+	if err := checkTopicExportEnd(b[bytes.LastIndexByte(b, '\n')+1:]); err != nil {
+		return nil, err
+	}
+
+	body, err := c.reqWantContentType(http.MethodPost, http.StatusOK, "/topic/import"+topic, "application/x-ndjson", b)
+	if err != nil {
+		ErrorLogFunc("Error importing messages in topic %s: %s", topic, err)
+		return nil, err
+	}
+
+	out := &TopicImportJSON{}
+	if err := json.Unmarshal(body, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,21 @@
+package tat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTopicExportEnd(t *testing.T) {
+	assert.Nil(t, ParseTopicExportEnd([]byte(`{"_id":"abc","text":"a message"}`)), "a message is not the last line")
+	assert.Nil(t, ParseTopicExportEnd([]byte(`not json`)))
+
+	end := ParseTopicExportEnd([]byte(`{"exportEnd":true,"messages":42}`))
+	assert.NotNil(t, end)
+	assert.Equal(t, 42, end.Messages)
+
+	assert.Nil(t, checkTopicExportEnd([]byte("{\"exportEnd\":true,\"messages\":0}\n")))
+	assert.NotNil(t, checkTopicExportEnd([]byte(`{"_id":"abc","text":"a message"}`)), "last line is missing")
+	assert.NotNil(t, checkTopicExportEnd(nil), "last line is missing")
+	assert.NotNil(t, checkTopicExportEnd([]byte(`{"exportEnd":true,"messages":3,"error":"Error while exporting topic /a"}`)))
+}
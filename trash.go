@@ -0,0 +1,62 @@
+package tat
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// TrashedMessage is a deleted message, kept in trash until it's purged.
+// Replies deleted with a message are trashed with IDDeletion of this message
+type TrashedMessage struct {
+	ID           string  `bson:"_id"          json:"_id"`
+	IDDeletion   string  `bson:"idDeletion"   json:"idDeletion"`
+	Topic        string  `bson:"topic"        json:"topic"`
+	Message      Message `bson:"message"      json:"message"`
+	NbMessages   int     `bson:"nbMessages"   json:"nbMessages"`
+	DeletedBy    string  `bson:"deletedBy"    json:"deletedBy"`
+	DateDeletion float64 `bson:"dateDeletion" json:"dateDeletion"`
+}
+
+// TrashedMessagesJSON is used by GET /trash/*topic
+type TrashedMessagesJSON struct {
+	Messages []TrashedMessage `json:"messages"`
+}
+
+// TrashList returns messages deleted from a topic, last deleted first.
+// Replies deleted with a message are not listed, they are counted in NbMessages
+func (c *Client) TrashList(topic string, skip, limit int) (*TrashedMessagesJSON, error) {
+	if c == nil {
+		return nil, ErrClientNotInitiliazed
+	}
+
+	v := url.Values{}
+	v.Set("skip", strconv.Itoa(skip))
+	v.Set("limit", strconv.Itoa(limit))
+	body, err := c.simpleGetAndGetBytes(fmt.Sprintf("/trash%s?%s", topic, v.Encode()))
+	if err != nil {
+		ErrorLogFunc("Error getting trash of topic %s: %s", topic, err)
+		return nil, err
+	}
+
+	out := &TrashedMessagesJSON{}
+	if err := json.Unmarshal(body, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MessageUndelete restores a deleted message from trash, with replies deleted with it
+func (c *Client) MessageUndelete(topic, idMessage string) ([]byte, error) {
+	if c == nil {
+		return nil, ErrClientNotInitiliazed
+	}
+
+	out, err := c.simplePutAndGetBytes(fmt.Sprintf("/trash/undelete/%s%s", idMessage, topic), 200, nil)
+	if err != nil {
+		ErrorLogFunc("Error undeleting message %s: %s", idMessage, err)
+		return nil, err
+	}
+	return out, nil
+}
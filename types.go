@@ -10,6 +10,8 @@ const (
 	TatHeaderPassword = "Tat_password"
 	// TatHeaderXTatRefererLower contains tat microservice name & version "X-TAT-FROM"
 	TatHeaderXTatRefererLower = "X-Tat-Referer"
+	// IdempotencyKeyHeader is Idempotency-Key header, same as MessageJSON.IdempotencyKey
+	IdempotencyKeyHeader = "Idempotency-Key"
 )
 
 // ArrayContains return true if element is in array
@@ -0,0 +1,143 @@
+package tat
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Workflow restricts labels used as states on messages of a topic: a message
+// has at most one state, and goes from a state to another only through a transition
+type Workflow struct {
+	States      []string             `bson:"states"      json:"states"`
+	Transitions []WorkflowTransition `bson:"transitions" json:"transitions"`
+}
+
+// WorkflowTransition allows a message to go from a state to another. From is empty
+// for a message without state, To is empty for a removal of state. If Users and Groups
+// are empty, all users with rw access on topic can make the transition
+type WorkflowTransition struct {
+	From   string   `bson:"from"   json:"from"`
+	To     string   `bson:"to"     json:"to"`
+	Users  []string `bson:"users"  json:"users,omitempty"`
+	Groups []string `bson:"groups" json:"groups,omitempty"`
+}
+
+// TopicWorkflowJSON is used by PUT /topic/workflow, a nil workflow removes workflow from topic
+type TopicWorkflowJSON struct {
+	Topic    string    `json:"topic"`
+	Workflow *Workflow `json:"workflow"`
+}
+
+// Check returns an error if a state is used twice, or if a transition uses an unknown state
+func (w *Workflow) Check() error {
+	if len(w.States) == 0 {
+		return fmt.Errorf("Invalid workflow, at least one state is required")
+	}
+	for i, s := range w.States {
+		if s == "" {
+			return fmt.Errorf("Invalid workflow, a state can't be empty")
+		}
+		if ArrayContains(w.States[:i], s) {
+			return fmt.Errorf("Invalid workflow, state %s is defined twice", s)
+		}
+	}
+	for i, t := range w.Transitions {
+		if t.From != "" && !ArrayContains(w.States, t.From) {
+			return fmt.Errorf("Invalid workflow, %s is not a state", t.From)
+		}
+		if t.To != "" && !ArrayContains(w.States, t.To) {
+			return fmt.Errorf("Invalid workflow, %s is not a state", t.To)
+		}
+		if t.From == t.To {
+			return fmt.Errorf("Invalid workflow, transition from %s to itself", t.From)
+		}
+		for _, o := range w.Transitions[:i] {
+			if o.From == t.From && o.To == t.To {
+				return fmt.Errorf("Invalid workflow, transition from %s to %s is defined twice", t.From, t.To)
+			}
+		}
+	}
+	return nil
+}
+
+// State returns the state of a message with these labels, empty if none.
+// Returns an error if labels contain several states
+func (w *Workflow) State(labels []Label) (string, error) {
+	state := ""
+	for _, l := range labels {
+		if !ArrayContains(w.States, l.Text) || l.Text == state {
+			continue
+		}
+		if state != "" {
+			return "", fmt.Errorf("A message can have only one state of workflow, %s and %s given", state, l.Text)
+		}
+		state = l.Text
+	}
+	return state, nil
+}
+
+// NextStates returns states reachable from state, empty string for a removal of state
+func (w *Workflow) NextStates(from string) []string {
+	next := []string{}
+	for _, t := range w.Transitions {
+		if t.From == from {
+			next = append(next, t.To)
+		}
+	}
+	return next
+}
+
+// CheckTransition returns an error if there is no transition from a state to
+// another, or if transition is not allowed to username or to its groups
+func (w *Workflow) CheckTransition(from, to, username string, groups []string) error {
+	if from == to {
+		return nil
+	}
+	for _, t := range w.Transitions {
+		if t.From != from || t.To != to {
+			continue
+		}
+		if len(t.Users) == 0 && len(t.Groups) == 0 {
+			return nil
+		}
+		if ArrayContains(t.Users, username) || ItemInBothArrays(t.Groups, groups) {
+			return nil
+		}
+		return fmt.Errorf("User %s is not allowed to go from %s to %s", username, formatState(from), formatState(to))
+	}
+	next := []string{}
+	for _, s := range w.NextStates(from) {
+		next = append(next, formatState(s))
+	}
+	return fmt.Errorf("Invalid transition from %s to %s, possible next states: %s", formatState(from), formatState(to), strings.Join(next, ", "))
+}
+
+// NeedGroups returns true if a transition is restricted to groups
+func (w *Workflow) NeedGroups() bool {
+	for _, t := range w.Transitions {
+		if len(t.Groups) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func formatState(s string) string {
+	if s == "" {
+		return "no state"
+	}
+	return s
+}
+
+// TopicSetWorkflow sets workflow of labels on a topic, a nil workflow removes it
+func (c *Client) TopicSetWorkflow(topic string, workflow *Workflow) ([]byte, error) {
+	if c == nil {
+		return nil, ErrClientNotInitiliazed
+	}
+	out, err := c.simplePutAndGetBytes("/topic/workflow", 201, TopicWorkflowJSON{Topic: topic, Workflow: workflow})
+	if err != nil {
+		ErrorLogFunc("Error while setting workflow on topic %s: %s", topic, err)
+		return nil, err
+	}
+	return out, nil
+}
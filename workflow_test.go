@@ -0,0 +1,61 @@
+package tat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testWorkflow() *Workflow {
+	return &Workflow{
+		States: []string{"todo", "review", "done"},
+		Transitions: []WorkflowTransition{
+			{From: "", To: "todo"},
+			{From: "todo", To: "review"},
+			{From: "review", To: "todo"},
+			{From: "review", To: "done", Groups: []string{"leads"}},
+			{From: "done", To: "", Users: []string{"admin"}},
+		},
+	}
+}
+
+func TestWorkflowCheck(t *testing.T) {
+	assert.Nil(t, testWorkflow().Check())
+	assert.NotNil(t, (&Workflow{}).Check(), "no state")
+	assert.NotNil(t, (&Workflow{States: []string{"todo", "todo"}}).Check(), "state defined twice")
+
+	w := testWorkflow()
+	w.Transitions = append(w.Transitions, WorkflowTransition{From: "todo", To: "wip"})
+	assert.NotNil(t, w.Check(), "unknown state")
+
+	w = testWorkflow()
+	w.Transitions = append(w.Transitions, WorkflowTransition{From: "todo", To: "review"})
+	assert.NotNil(t, w.Check(), "transition defined twice")
+}
+
+func TestWorkflowState(t *testing.T) {
+	w := testWorkflow()
+	s, err := w.State([]Label{{Text: "bug"}, {Text: "review"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "review", s)
+
+	s, err = w.State([]Label{{Text: "bug"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "", s)
+
+	_, err = w.State([]Label{{Text: "todo"}, {Text: "done"}})
+	assert.NotNil(t, err, "several states")
+}
+
+func TestWorkflowCheckTransition(t *testing.T) {
+	w := testWorkflow()
+	assert.Nil(t, w.CheckTransition("", "todo", "foo", nil))
+	assert.Nil(t, w.CheckTransition("review", "review", "foo", nil), "same state")
+	assert.NotNil(t, w.CheckTransition("todo", "done", "foo", nil), "no transition")
+	assert.NotNil(t, w.CheckTransition("review", "done", "foo", []string{"dev"}), "not in group")
+	assert.Nil(t, w.CheckTransition("review", "done", "foo", []string{"dev", "leads"}))
+	assert.NotNil(t, w.CheckTransition("done", "", "foo", nil), "not allowed user")
+	assert.Nil(t, w.CheckTransition("done", "", "admin", nil))
+	assert.True(t, w.NeedGroups())
+	assert.Equal(t, []string{"todo", "done"}, w.NextStates("review"))
+}